@@ -91,7 +91,7 @@ echo "$domain" > /etc/zivpn/domain
 echo "$api_key" > /etc/zivpn/apikey
 run_silent "Configuring" "wget -q https://raw.githubusercontent.com/RyyStore/ZiVPN/main/config.json -O /etc/zivpn/config.json"
 
-run_silent "Generating SSL" "openssl req -new -newkey rsa:4096 -days 365 -nodes -x509 -subj '/C=ID/ST=Jawa Barat/L=Bandung/O=AutoFTbot/OU=IT Department/CN=$domain' -keyout /etc/zivpn/zivpn.key -out /etc/zivpn/zivpn.crt"
+run_silent "Generating SSL" "openssl req -new -newkey rsa:4096 -days 365 -nodes -x509 -subj '/C=ID/ST=Jawa Barat/L=Bandung/O=AutoFTbot/OU=IT Department/CN=$domain' -addext 'subjectAltName=DNS:$domain' -keyout /etc/zivpn/zivpn.key -out /etc/zivpn/zivpn.crt"
 
 # Find a free API port
 print_task "Finding available API Port"
@@ -149,6 +149,7 @@ mkdir -p /etc/zivpn/api
 run_silent "Setting up API" "wget -q https://raw.githubusercontent.com/RyyStore/ZiVPN/main/zivpn-api.go -O /etc/zivpn/api/zivpn-api.go && wget -q https://raw.githubusercontent.com/RyyStore/ZiVPN/main/go.mod -O /etc/zivpn/api/go.mod"
 
 cd /etc/zivpn/api
+run_silent "Downloading API Deps" "go get golang.org/x/crypto"
 if go build -o zivpn-api zivpn-api.go &>/dev/null; then
   print_done "Compiling API"
 else
@@ -238,7 +239,7 @@ if [[ -n "$bot_token" ]] && [[ -n "$admin_id" ]]; then
   run_silent "Downloading Bot" "wget -q https://raw.githubusercontent.com/RyyStore/ZiVPN/main/$bot_file -O /etc/zivpn/api/$bot_file"
   
   cd /etc/zivpn/api
-  run_silent "Downloading Bot Deps" "go get github.com/go-telegram-bot-api/telegram-bot-api/v5"
+  run_silent "Downloading Bot Deps" "go get github.com/go-telegram-bot-api/telegram-bot-api/v5 github.com/skip2/go-qrcode"
   
   build_log="/tmp/zivpn_bot_build.log"
   if go build -o zivpn-bot "$bot_file" &> "$build_log"; then
@@ -1,27 +1,135 @@
 package main
 
 import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	ConfigFile         = "/etc/zivpn/config.json"
+	UserDB             = "/etc/zivpn/users.json"
+	DomainFile         = "/etc/zivpn/domain"
+	ApiKeyFile         = "/etc/zivpn/apikey"
+	Port               = "/etc/zivpn/api_port"
+	ExpireFile         = "/etc/zivpn/last_expire.json"
+	ConfigBackupFile   = "/etc/zivpn/config.json.bak"
+	HistoryDir         = "/etc/zivpn/history"
+	BotConfigFile      = "/etc/zivpn/bot-config.json"
+	WalletFile         = "/etc/zivpn/wallets.json"
+	MetricsFile        = "/etc/zivpn/metrics.json"
+	VerifiedFile       = "/etc/zivpn/bot-verified.json"
+	BotUsersFile       = "/etc/zivpn/bot-users.json"
+	PaidBotUsersFile   = "/etc/zivpn/paid-bot-users.json"
+	BotStateFile       = "/etc/zivpn/bot-state.json"
+	PaidBotStateFile   = "/etc/zivpn/paid-bot-state.json"
+	BotQuotaFile       = "/etc/zivpn/bot-quota.json"
+	LangDir            = "/etc/zivpn/lang"
+	CertFile           = "/etc/zivpn/zivpn.crt"
+	KeyFile            = "/etc/zivpn/zivpn.key"
+	BackupSettingsFile = "/etc/zivpn/backup.json"
+	BackupIdentityFile = "/etc/zivpn/backup-identity"
+	BackupDir          = "/var/backups/zivpn"
+)
+
+// BackupVersion is the manifest format written by /api/backup.
+const BackupVersion = 1
+
+// Encrypted backups start with EncryptedMagic, followed by a JSON header line
+// (wrapped file keys) and the AES-256-GCM sealed ZIP. Recipients are X25519
+// public keys; secret keys are the matching private keys.
+const (
+	EncryptedMagic  = "ZIVPN-ENC-1\n"
+	PublicKeyPrefix = "ZIVPNPUB1"
+	SecretKeyPrefix = "ZIVPNSEC1"
+	ScryptLogN      = 15
 )
 
+// BackupEntry is one state file covered by backup and restore. Mode is
+// applied on restore regardless of what the archive carried.
+type BackupEntry struct {
+	Name      string // name inside the archive
+	Path      string
+	Mode      os.FileMode
+	Component string
+}
+
+// BackupEntries declares every state file ZiVPN keeps. Components can be
+// selected with ?include= / ?exclude= on /api/backup and /api/restore.
+// The certificate and key paths are defaults; backupEntries resolves them
+// from config.json. A Name with a wildcard covers every matching file in
+// the directory of Path, see expandEntries and matchEntry.
+var BackupEntries = []BackupEntry{
+	{"config.json", ConfigFile, 0644, "core"},
+	{"zivpn.crt", CertFile, 0644, "core"},
+	{"zivpn.key", KeyFile, 0600, "core"},
+	{"users.json", UserDB, 0644, "users"},
+	{"domain", DomainFile, 0644, "api"},
+	{"apikey", ApiKeyFile, 0600, "api"},
+	{"api_port", Port, 0644, "api"},
+	{"bot-config.json", BotConfigFile, 0600, "bot"},
+	{"bot-verified.json", VerifiedFile, 0600, "bot"},
+	{"bot-users.json", BotUsersFile, 0600, "bot"},
+	{"paid-bot-users.json", PaidBotUsersFile, 0600, "bot"},
+	{"bot-state.json", BotStateFile, 0600, "bot"},
+	{"paid-bot-state.json", PaidBotStateFile, 0600, "bot"},
+	{"bot-quota.json", BotQuotaFile, 0600, "bot"},
+	{"lang/*.json", filepath.Join(LangDir, "*.json"), 0644, "lang"},
+	{"wallets.json", WalletFile, 0600, "wallet"},
+	{"metrics.json", MetricsFile, 0644, "wallet"},
+}
+
+// Restore limits. Real backups are a few KB, so these only stop abuse such
+// as zip bombs.
 const (
-	ConfigFile = "/etc/zivpn/config.json"
-	UserDB     = "/etc/zivpn/users.json"
-	DomainFile = "/etc/zivpn/domain"
-	ApiKeyFile = "/etc/zivpn/apikey"
-	Port       = "/etc/zivpn/api_port"
+	MaxBackupSize       = 20 << 20 // upload, compressed or encrypted
+	MaxBackupFileSize   = 10 << 20 // one uncompressed entry
+	MaxBackupTotalSize  = 50 << 20 // all uncompressed entries
+	MaxBackupFiles      = 64
+	MaxCompressionRatio = 200
+	PreRestoreKeep      = 5
 )
 
+// CertWarnDays is how close to expiry the TLS certificate may get before
+// /api/health reports it as failing.
+const CertWarnDays = 14
+
+// ConfigApplyTimeout is how long PUT /api/config waits for the core to come
+// back up and listen before restoring the previous config.
+const ConfigApplyTimeout = 15 * time.Second
+
 var AuthToken = "AutoFtBot-agskjgdvsbdreiWG1234512SDKrqw"
 
 type Config struct {
@@ -35,15 +143,28 @@ type Config struct {
 	} `json:"auth"`
 }
 
+// ConfigUpdate is the body of PUT /api/config. Omitted fields are left
+// unchanged; the password list is managed through the user endpoints.
+type ConfigUpdate struct {
+	Listen *string `json:"listen"`
+	Cert   *string `json:"cert"`
+	Key    *string `json:"key"`
+	Obfs   *string `json:"obfs"`
+}
+
 type UserRequest struct {
 	Password string `json:"password"`
 	Days     int    `json:"days"`
+	Owner    int64  `json:"owner,omitempty"` // Telegram ID of the creator, only used on create
+
+	NewPassword string `json:"new_password,omitempty"` // only used on password change
 }
 
 type UserStore struct {
 	Password string `json:"password"`
 	Expired  string `json:"expired"`
 	Status   string `json:"status"`
+	Owner    int64  `json:"owner,omitempty"` // 0 means admin-owned (API, app or accounts created before ownership)
 }
 
 type Response struct {
@@ -54,20 +175,280 @@ type Response struct {
 
 var mutex = &sync.Mutex{}
 
+// Event is a single entry on the /api/events stream.
+type Event struct {
+	ID   int64       `json:"id"`
+	Type string      `json:"type"`
+	Time string      `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// EventBufferSize bounds how many past events are kept for Last-Event-ID resume.
+const EventBufferSize = 256
+
+type eventHub struct {
+	mu     sync.Mutex
+	epoch  string // per-boot prefix of the SSE id, since nextID restarts at 1
+	nextID int64
+	ring   []Event
+	subs   map[chan Event]bool
+}
+
+var events = &eventHub{
+	epoch: strconv.FormatInt(time.Now().Unix(), 36),
+	subs:  make(map[chan Event]bool),
+}
+
+// latencyBuckets are the upper bounds (seconds) of the request duration histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type handlerStats struct {
+	requests map[int]int64
+	buckets  []int64
+	sum      float64
+	count    int64
+}
+
+type apiMetrics struct {
+	mu              sync.Mutex
+	handlers        map[string]*handlerStats
+	restartFailures int64
+}
+
+var metrics = &apiMetrics{handlers: make(map[string]*handlerStats)}
+
+// SystemInfo is the payload of /api/info. The domain/public_ip/private_ip/
+// port/service fields keep their original names for existing clients.
+type SystemInfo struct {
+	Domain     string          `json:"domain"`
+	PublicIP   string          `json:"public_ip"`
+	PrivateIP  string          `json:"private_ip"`
+	Port       string          `json:"port"`
+	Service    string          `json:"service"`
+	Hostname   string          `json:"hostname"`
+	Uptime     int64           `json:"uptime_seconds"`
+	CPU        CPUInfo         `json:"cpu"`
+	Load       [3]float64      `json:"load"`
+	Memory     MemoryInfo      `json:"memory"`
+	Disk       DiskInfo        `json:"disk"`
+	Interfaces []InterfaceInfo `json:"interfaces"`
+}
+
+type CPUInfo struct {
+	Cores        int     `json:"cores"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+type MemoryInfo struct {
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"`
+	Used      uint64 `json:"used"`
+}
+
+type DiskInfo struct {
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+	Used  uint64 `json:"used"`
+}
+
+type InterfaceInfo struct {
+	Name    string `json:"name"`
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+var (
+	publicIPLookup = true
+	publicIPTTL    = time.Hour
+
+	publicIPMu         sync.Mutex
+	publicIPCache      string
+	publicIPFetched    time.Time
+	publicIPAttempted  time.Time
+	publicIPRefreshing bool
+)
+
+// PublicIPRetry is how long publicIP waits after a failed lookup before
+// trying again.
+const PublicIPRetry = 5 * time.Minute
+
+// LogUnits maps the unit names accepted by /api/logs to systemd units.
+var LogUnits = map[string]string{
+	"zivpn":     "zivpn.service",
+	"zivpn-api": "zivpn-api.service",
+	"zivpn-bot": "zivpn-bot.service",
+}
+
+const (
+	DefaultLogLines = 100
+	MaxLogLines     = 1000
+)
+
+var passwordPattern = regexp.MustCompile(`(?i)(pass(word)?["']?\s*[:=]\s*["']?)[^\s"',}]+`)
+
+// Snapshot describes one numbered copy of config.json and users.json in
+// HistoryDir, written after every mutation.
+type Snapshot struct {
+	ID     int    `json:"id"`
+	Time   string `json:"time"`
+	Reason string `json:"reason"`
+	Users  int    `json:"users"`
+}
+
+type UserChange struct {
+	Password string    `json:"password"`
+	Before   UserStore `json:"before"`
+	After    UserStore `json:"after"`
+}
+
+type SnapshotDiff struct {
+	From          int          `json:"from"`
+	To            int          `json:"to"`
+	Added         []UserStore  `json:"added"`
+	Removed       []UserStore  `json:"removed"`
+	Changed       []UserChange `json:"changed"`
+	ConfigChanged []string     `json:"config_changed"`
+}
+
+var historyKeep = 50
+
+// BackupManifest is stored as manifest.json inside every backup archive.
+type BackupManifest struct {
+	Version   int               `json:"version"`
+	Hostname  string            `json:"hostname"`
+	CreatedAt string            `json:"created_at"`
+	Files      map[string]string `json:"files"` // name -> SHA-256 hex
+	Components []string          `json:"components,omitempty"`
+}
+
+// RestoreReport describes what /api/restore did, or would do on a dry run.
+type RestoreReport struct {
+	DryRun       bool     `json:"dry_run"`
+	Legacy       bool     `json:"legacy"` // archive has no manifest.json
+	Encrypted    bool     `json:"encrypted"`
+	Files        []string `json:"files"`   // written (or, on a dry run, to be written)
+	Skipped      []string `json:"skipped"` // in the archive but not selected or unknown
+	UsersAdded   []string `json:"users_added"`
+	UsersRemoved []string `json:"users_removed"`
+	// Services that only pick the restored files up after a restart
+	RestartRequired []string `json:"restart_required"`
+	// Full backup of the state that was replaced, under BackupDir/pre-restore
+	SafetyBackup string `json:"safety_backup,omitempty"`
+	RolledBack   bool   `json:"rolled_back"`
+}
+
+// BackupSettings is stored in BackupSettingsFile. When Passphrase or
+// Recipients is set every backup, downloaded or scheduled, is encrypted.
+type BackupSettings struct {
+	Passphrase string   `json:"passphrase"`
+	Recipients []string `json:"recipients"`
+	Schedule   string   `json:"schedule"` // cron expression, empty disables
+	KeepDaily  int      `json:"keep_daily"`
+	KeepWeekly int      `json:"keep_weekly"`
+	Telegram   bool     `json:"telegram"` // bots forward scheduled backups to the admin
+}
+
+// BackupRun records the outcome of the last scheduled (or /api/backup/run)
+// backup, persisted as status.json in BackupDir.
+type BackupRun struct {
+	Time      string `json:"time"`
+	Trigger   string `json:"trigger"`
+	Success   bool   `json:"success"`
+	File      string `json:"file,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Encrypted bool   `json:"encrypted"`
+	Weekly    bool   `json:"weekly"`
+	Error     string `json:"error,omitempty"`
+}
+
+// cronSchedule is a parsed five-field cron expression. Each field is a
+// bitmask of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// encStanza wraps the file key of an encrypted backup for one passphrase
+// ("scrypt") or one recipient ("X25519").
+type encStanza struct {
+	Type string `json:"type"`
+	Salt string `json:"salt,omitempty"`
+	LogN int    `json:"log_n,omitempty"`
+	EPK  string `json:"epk,omitempty"`
+	Body string `json:"body"`
+}
+
+type encHeader struct {
+	Stanzas []encStanza `json:"stanzas"`
+	Nonce   string      `json:"nonce"`
+}
+
+var errNeedSecret = fmt.Errorf("Backup terenkripsi, passphrase atau secret key diperlukan")
+
+// ExpiryRun records the outcome of the last /api/cron/expire call. It is
+// persisted to ExpireFile so /api/health survives API restarts.
+type ExpiryRun struct {
+	Time    string `json:"time"`
+	Success bool   `json:"success"`
+	Revoked int    `json:"revoked"`
+	Error   string `json:"error,omitempty"`
+}
+
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
 func main() {
 	port := flag.Int("port", 8080, "Port to run the API server on")
+	flag.BoolVar(&publicIPLookup, "ip-lookup", true, "Look up the public IP for /api/info (disable on offline servers)")
+	flag.DurationVar(&publicIPTTL, "ip-ttl", time.Hour, "How long a looked-up public IP is cached")
+	flag.IntVar(&historyKeep, "history-keep", 50, "Number of config/user snapshots to keep in "+HistoryDir)
 	flag.Parse()
 
 	if keyBytes, err := ioutil.ReadFile(ApiKeyFile); err == nil {
 		AuthToken = strings.TrimSpace(string(keyBytes))
 	}
 
-	http.HandleFunc("/api/user/create", authMiddleware(createUser))
-	http.HandleFunc("/api/user/delete", authMiddleware(deleteUser))
-	http.HandleFunc("/api/user/renew", authMiddleware(renewUser))
-	http.HandleFunc("/api/users", authMiddleware(listUsers))
-	http.HandleFunc("/api/info", authMiddleware(getSystemInfo))
-	http.HandleFunc("/api/cron/expire", authMiddleware(checkExpiration))
+	// Warm the public IP cache so the first /api/info already has it.
+	publicIP()
+
+	http.HandleFunc("/api/user/create", instrument("user_create", authMiddleware(createUser)))
+	http.HandleFunc("/api/user/delete", instrument("user_delete", authMiddleware(deleteUser)))
+	http.HandleFunc("/api/user/renew", instrument("user_renew", authMiddleware(renewUser)))
+	http.HandleFunc("/api/user/lock", instrument("user_lock", authMiddleware(lockUser)))
+	http.HandleFunc("/api/user/password", instrument("user_password", authMiddleware(changePassword)))
+	http.HandleFunc("/api/users", instrument("users", authMiddleware(listUsers)))
+	http.HandleFunc("/api/info", instrument("info", authMiddleware(getSystemInfo)))
+	http.HandleFunc("/api/cron/expire", instrument("cron_expire", authMiddleware(checkExpiration)))
+	http.HandleFunc("/api/events", authMiddleware(streamEvents))
+	http.HandleFunc("/api/health", instrument("health", authMiddleware(healthCheck)))
+	http.HandleFunc("/api/service/status", instrument("service_status", authMiddleware(serviceStatus)))
+	http.HandleFunc("/api/service/restart", instrument("service_restart", authMiddleware(serviceControl("restart"))))
+	http.HandleFunc("/api/service/stop", instrument("service_stop", authMiddleware(serviceControl("stop"))))
+	http.HandleFunc("/api/service/start", instrument("service_start", authMiddleware(serviceControl("start"))))
+	http.HandleFunc("/api/snapshots", instrument("snapshots", authMiddleware(listSnapshots)))
+	http.HandleFunc("/api/snapshots/diff", instrument("snapshots_diff", authMiddleware(diffSnapshots)))
+	http.HandleFunc("/api/snapshots/rollback", instrument("snapshots_rollback", authMiddleware(rollbackSnapshot)))
+	http.HandleFunc("/api/backup", instrument("backup", authMiddleware(downloadBackup)))
+	http.HandleFunc("/api/restore", instrument("restore", authMiddleware(restoreBackup)))
+	http.HandleFunc("/api/backup/settings", instrument("backup_settings", authMiddleware(manageBackupSettings)))
+	http.HandleFunc("/api/backup/keygen", instrument("backup_keygen", authMiddleware(generateBackupKey)))
+	http.HandleFunc("/api/backup/status", instrument("backup_status", authMiddleware(backupStatus)))
+	http.HandleFunc("/api/backup/run", instrument("backup_run", authMiddleware(runBackupNow)))
+	http.HandleFunc("/api/config", instrument("config", authMiddleware(manageConfig)))
+	http.HandleFunc("/api/logs", instrument("logs", authMiddleware(tailLogs)))
+	http.HandleFunc("/metrics", metricsAuth(serveMetrics))
+
+	if snaps, err := readSnapshots(); err == nil && len(snaps) == 0 {
+		if err := snapshotState("initial"); err != nil {
+			log.Printf("Gagal membuat snapshot awal: %v", err)
+		}
+	}
+
+	go runBackupScheduler()
 
 	log.Printf("Server started at :%d", *port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
@@ -84,6 +465,41 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// metricsAuth is authMiddleware that also accepts "Authorization: Bearer
+// <key>", which Prometheus scrape configs can send.
+func metricsAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-API-Key")
+		if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+			token = strings.TrimPrefix(bearer, "Bearer ")
+		}
+		if token != AuthToken {
+			jsonResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func instrument(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		observeRequest(name, rec.status, time.Since(start).Seconds())
+	}
+}
+
 func jsonResponse(w http.ResponseWriter, status int, success bool, message string, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -145,6 +561,7 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		Password: req.Password,
 		Expired:  expDate,
 		Status:   "active",
+		Owner:    req.Owner,
 	}
 	users = append(users, newUser)
 
@@ -152,6 +569,7 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan database user", nil)
 		return
 	}
+	recordSnapshot("user.create " + req.Password)
 
 	if err := restartService(); err != nil {
 		jsonResponse(w, http.StatusInternalServerError, false, "Gagal merestart service", nil)
@@ -163,6 +581,11 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		domain = strings.TrimSpace(string(domainBytes))
 	}
 
+	publishEvent("user.created", map[string]string{
+		"password": req.Password,
+		"expired":  expDate,
+	})
+
 	jsonResponse(w, http.StatusOK, true, "User berhasil dibuat", map[string]string{
 		"password": req.Password,
 		"expired":  expDate,
@@ -236,6 +659,7 @@ func deleteUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	recordSnapshot("user.delete " + req.Password)
 
 	if foundInConfig {
 		if err := restartService(); err != nil {
@@ -244,6 +668,8 @@ func deleteUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	publishEvent("user.deleted", map[string]string{"password": req.Password})
+
 	jsonResponse(w, http.StatusOK, true, "User berhasil dihapus", nil)
 }
 
@@ -309,206 +735,3138 @@ func renewUser(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan database user", nil)
 		return
 	}
+	recordSnapshot("user.renew " + req.Password)
 
 	if err := restartService(); err != nil {
 		jsonResponse(w, http.StatusInternalServerError, false, "Gagal merestart service", nil)
 		return
 	}
 
+	publishEvent("user.renewed", map[string]string{
+		"password": req.Password,
+		"expired":  newExpDate,
+	})
+
 	jsonResponse(w, http.StatusOK, true, "User berhasil diperpanjang", map[string]string{
 		"password": req.Password,
 		"expired":  newExpDate,
 	})
 }
 
-func listUsers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// lockUser disables a password without deleting it. Renewing unlocks it.
+func lockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
 		return
 	}
 
-	users, err := loadUsers()
-	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, "Invalid request body", nil)
 		return
 	}
-
-	type UserInfo struct {
-		Password string `json:"password"`
-		Expired  string `json:"expired"`
-		Status   string `json:"status"`
+	if req.Password == "" {
+		jsonResponse(w, http.StatusBadRequest, false, "Password harus diisi", nil)
+		return
 	}
 
-	userList := []UserInfo{}
-	today := time.Now().Format("2006-01-02")
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	for _, u := range users {
-		status := "Active"
-		if u.Status == "locked" {
-			status = "Locked"
-		} else if u.Expired < today {
-			status = "Expired"
-		}
-		
-		userList = append(userList, UserInfo{
-			Password: u.Password,
-			Expired:  u.Expired,
-			Status:   status,
-		})
+	users, err := loadUsers()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
+		return
 	}
 
-	jsonResponse(w, http.StatusOK, true, "Daftar user", userList)
-}
-
-func getSystemInfo(w http.ResponseWriter, r *http.Request) {
-	cmd := exec.Command("curl", "-s", "ifconfig.me")
-	ipPub, _ := cmd.Output()
-
-	cmd = exec.Command("hostname", "-I")
-	ipPriv, _ := cmd.Output()
-
-	domain := "Tidak diatur"
-	if domainBytes, err := ioutil.ReadFile(DomainFile); err == nil {
-		domain = strings.TrimSpace(string(domainBytes))
+	idx := -1
+	for i, u := range users {
+		if u.Password == req.Password {
+			idx = i
+			break
+		}
 	}
-
-	info := map[string]string{
-		"domain":     domain,
-		"public_ip":  strings.TrimSpace(string(ipPub)),
-		"private_ip": strings.Fields(string(ipPriv))[0],
-		"port":       "5667",
-		"service":    "zivpn",
+	if idx == -1 {
+		jsonResponse(w, http.StatusNotFound, false, "User tidak ditemukan di database", nil)
+		return
 	}
-
-	jsonResponse(w, http.StatusOK, true, "System Info", info)
-}
-
-func checkExpiration(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+	if users[idx].Status == "locked" {
+		jsonResponse(w, http.StatusConflict, false, "User sudah terkunci", nil)
 		return
 	}
 
-	users, err := loadUsers()
-	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
+	users[idx].Status = "locked"
+	if err := saveUsers(users); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan database user", nil)
 		return
 	}
 
-	today := time.Now().Format("2006-01-02")
-	
-	// Load config to check who is currently active
 	config, err := loadConfig()
 	if err != nil {
 		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
 		return
 	}
-
-	activeUsers := make(map[string]bool)
+	newConfigAuth := []string{}
+	foundInConfig := false
 	for _, p := range config.Auth.Config {
-		activeUsers[p] = true
+		if p == req.Password {
+			foundInConfig = true
+		} else {
+			newConfigAuth = append(newConfigAuth, p)
+		}
 	}
+	if foundInConfig {
+		config.Auth.Config = newConfigAuth
+		if err := saveConfig(config); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan config", nil)
+			return
+		}
+	}
+	recordSnapshot("user.lock " + req.Password)
 
-	revokedCount := 0
-	for _, u := range users {
-		if u.Expired < today && activeUsers[u.Password] {
-			log.Printf("User %s expired (Exp: %s). Revoking access.\n", u.Password, u.Expired)
-			revokeAccess(u.Password)
-			revokedCount++
+	if foundInConfig {
+		if err := restartService(); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal merestart service", nil)
+			return
 		}
 	}
 
-	jsonResponse(w, http.StatusOK, true, fmt.Sprintf("Expiration check complete. Revoked: %d", revokedCount), nil)
+	publishEvent("user.locked", map[string]string{"password": req.Password})
+
+	jsonResponse(w, http.StatusOK, true, "User berhasil dikunci", map[string]string{
+		"password": req.Password,
+		"expired":  users[idx].Expired,
+	})
 }
 
-func revokeAccess(password string) {
-	mutex.Lock()
-	defer mutex.Unlock()
+// changePassword renames an account, keeping its expiry, status and owner.
+func changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
 
-	config, err := loadConfig()
-	if err == nil {
-		newConfigAuth := []string{}
-		changed := false
-		for _, p := range config.Auth.Config {
-			if p == password {
-				changed = true
-			} else {
-				newConfigAuth = append(newConfigAuth, p)
-			}
-		}
-		if changed {
-			config.Auth.Config = newConfigAuth
-			saveConfig(config)
-			restartService()
-		}
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, "Invalid request body", nil)
+		return
+	}
+	if req.Password == "" || req.NewPassword == "" {
+		jsonResponse(w, http.StatusBadRequest, false, "Password dan new_password harus diisi", nil)
+		return
 	}
-}
 
-func enableUser(password string) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	config, err := loadConfig()
+	users, err := loadUsers()
 	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
 		return
 	}
 
-	exists := false
-	for _, p := range config.Auth.Config {
-		if p == password {
-			exists = true
-			break
+	idx := -1
+	for i, u := range users {
+		if u.Password == req.NewPassword {
+			jsonResponse(w, http.StatusConflict, false, "Password baru sudah dipakai", nil)
+			return
+		}
+		if u.Password == req.Password {
+			idx = i
 		}
 	}
-
-	if !exists {
-		config.Auth.Config = append(config.Auth.Config, password)
-		saveConfig(config)
-		restartService()
+	if idx == -1 {
+		jsonResponse(w, http.StatusNotFound, false, "User tidak ditemukan di database", nil)
+		return
 	}
-}
-
 
-func loadConfig() (Config, error) {
-	var config Config
-	file, err := ioutil.ReadFile(ConfigFile)
+	config, err := loadConfig()
 	if err != nil {
-		return config, err
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
+		return
 	}
-	err = json.Unmarshal(file, &config)
-	return config, err
-}
+	// Locked and expired accounts are not in the auth list and stay out of it
+	foundInConfig := false
+	for i, p := range config.Auth.Config {
+		if p == req.NewPassword {
+			jsonResponse(w, http.StatusConflict, false, "Password baru sudah dipakai", nil)
+			return
+		}
+		if p == req.Password {
+			config.Auth.Config[i] = req.NewPassword
+			foundInConfig = true
+		}
+	}
+
+	users[idx].Password = req.NewPassword
+	if err := saveUsers(users); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan database user", nil)
+		return
+	}
+	if foundInConfig {
+		if err := saveConfig(config); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan config", nil)
+			return
+		}
+	}
+	recordSnapshot("user.password " + req.Password + " -> " + req.NewPassword)
+
+	if foundInConfig {
+		if err := restartService(); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal merestart service", nil)
+			return
+		}
+	}
+
+	publishEvent("user.password_changed", map[string]string{
+		"password":     req.NewPassword,
+		"old_password": req.Password,
+	})
+
+	jsonResponse(w, http.StatusOK, true, "Password berhasil diganti", map[string]string{
+		"password": req.NewPassword,
+		"expired":  users[idx].Expired,
+	})
+}
+
+func listUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	users, err := loadUsers()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
+		return
+	}
+
+	type UserInfo struct {
+		Password string `json:"password"`
+		Expired  string `json:"expired"`
+		Status   string `json:"status"`
+		Owner    int64  `json:"owner,omitempty"`
+	}
+
+	// Optional ?owner=<telegram id> limits the list to one owner's accounts
+	var owner int64
+	filterOwner := r.URL.Query().Get("owner") != ""
+	if filterOwner {
+		owner, err = strconv.ParseInt(r.URL.Query().Get("owner"), 10, 64)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, false, "Owner harus berupa angka", nil)
+			return
+		}
+	}
+
+	userList := []UserInfo{}
+	today := time.Now().Format("2006-01-02")
+
+	for _, u := range users {
+		if filterOwner && u.Owner != owner {
+			continue
+		}
+		status := "Active"
+		if u.Status == "locked" {
+			status = "Locked"
+		} else if u.Expired < today {
+			status = "Expired"
+		}
+		
+		userList = append(userList, UserInfo{
+			Password: u.Password,
+			Expired:  u.Expired,
+			Status:   status,
+			Owner:    u.Owner,
+		})
+	}
+
+	jsonResponse(w, http.StatusOK, true, "Daftar user", userList)
+}
+
+func getSystemInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	domain := "Tidak diatur"
+	if domainBytes, err := ioutil.ReadFile(DomainFile); err == nil {
+		domain = strings.TrimSpace(string(domainBytes))
+	}
+
+	port := ""
+	if config, err := loadConfig(); err == nil {
+		if p, err := listenPort(config.Listen); err == nil {
+			port = strconv.Itoa(p)
+		}
+	}
+
+	hostname, _ := os.Hostname()
+
+	info := SystemInfo{
+		Domain:     domain,
+		PublicIP:   publicIP(),
+		PrivateIP:  privateIP(),
+		Port:       port,
+		Service:    "zivpn",
+		Hostname:   hostname,
+		Uptime:     readUptime(),
+		CPU:        readCPU(),
+		Load:       readLoad(),
+		Memory:     readMemory(),
+		Disk:       readDisk("/"),
+		Interfaces: readInterfaces(),
+	}
+
+	jsonResponse(w, http.StatusOK, true, "System Info", info)
+}
+
+// publicIP returns the cached public address without blocking. Once the TTL
+// has passed it starts a background refresh; after a failed lookup it waits
+// PublicIPRetry before trying again and keeps serving the previous value.
+func publicIP() string {
+	if !publicIPLookup {
+		return ""
+	}
+
+	publicIPMu.Lock()
+	defer publicIPMu.Unlock()
+
+	stale := publicIPCache == "" || time.Since(publicIPFetched) >= publicIPTTL
+	if stale && !publicIPRefreshing && time.Since(publicIPAttempted) >= PublicIPRetry {
+		publicIPRefreshing = true
+		publicIPAttempted = time.Now()
+		go refreshPublicIP()
+	}
+	return publicIPCache
+}
+
+func refreshPublicIP() {
+	ip := lookupPublicIP()
+
+	publicIPMu.Lock()
+	defer publicIPMu.Unlock()
+
+	publicIPRefreshing = false
+	if ip != "" {
+		publicIPCache = ip
+		publicIPFetched = time.Now()
+	}
+}
+
+func lookupPublicIP() string {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("https://ifconfig.me/ip")
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	if ip := net.ParseIP(strings.TrimSpace(string(body))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+func privateIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
+
+func readUptime() int64 {
+	data, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	secs, _ := strconv.ParseFloat(fields[0], 64)
+	return int64(secs)
+}
+
+// readCPU samples /proc/stat twice to compute current utilisation.
+func readCPU() CPUInfo {
+	info := CPUInfo{}
+	idle1, total1, cores := readCPUStat()
+	time.Sleep(200 * time.Millisecond)
+	idle2, total2, _ := readCPUStat()
+
+	info.Cores = cores
+	if total2 > total1 {
+		busy := float64((total2 - total1) - (idle2 - idle1))
+		info.UsagePercent = busy / float64(total2-total1) * 100
+	}
+	return info
+}
+
+func readCPUStat() (idle, total uint64, cores int) {
+	data, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		if fields[0] == "cpu" {
+			for i, f := range fields[1:] {
+				v, _ := strconv.ParseUint(f, 10, 64)
+				total += v
+				// idle and iowait
+				if i == 3 || i == 4 {
+					idle += v
+				}
+			}
+		} else if strings.HasPrefix(fields[0], "cpu") {
+			cores++
+		}
+	}
+	return idle, total, cores
+}
+
+func readLoad() [3]float64 {
+	var load [3]float64
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return load
+	}
+	fields := strings.Fields(string(data))
+	for i := 0; i < 3 && i < len(fields); i++ {
+		load[i], _ = strconv.ParseFloat(fields[i], 64)
+	}
+	return load
+}
+
+func readMemory() MemoryInfo {
+	mem := MemoryInfo{}
+	data, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return mem
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, _ := strconv.ParseUint(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			mem.Total = kb * 1024
+		case "MemAvailable:":
+			mem.Available = kb * 1024
+		}
+	}
+	if mem.Total > mem.Available {
+		mem.Used = mem.Total - mem.Available
+	}
+	return mem
+}
+
+func readDisk(path string) DiskInfo {
+	disk := DiskInfo{Path: path}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return disk
+	}
+	disk.Total = st.Blocks * uint64(st.Bsize)
+	disk.Free = st.Bavail * uint64(st.Bsize)
+	disk.Used = disk.Total - st.Bfree*uint64(st.Bsize)
+	return disk
+}
+
+func readInterfaces() []InterfaceInfo {
+	ifaces := []InterfaceInfo{}
+	data, err := ioutil.ReadFile("/proc/net/dev")
+	if err != nil {
+		return ifaces
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) < 2 {
+		return ifaces
+	}
+	for _, line := range lines[2:] {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		fields := strings.Fields(parts[1])
+		if name == "lo" || len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		ifaces = append(ifaces, InterfaceInfo{Name: name, RxBytes: rx, TxBytes: tx})
+	}
+	return ifaces
+}
+
+func checkExpiration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	users, err := loadUsers()
+	if err != nil {
+		recordExpiryRun(0, err)
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca database user", nil)
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	
+	// Load config to check who is currently active
+	config, err := loadConfig()
+	if err != nil {
+		recordExpiryRun(0, err)
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
+		return
+	}
+
+	activeUsers := make(map[string]bool)
+	for _, p := range config.Auth.Config {
+		activeUsers[p] = true
+	}
+
+	revokedCount := 0
+	revoked := []string{}
+	var revokeErr error
+	for _, u := range users {
+		if u.Expired < today && activeUsers[u.Password] {
+			log.Printf("User %s expired (Exp: %s). Revoking access.\n", u.Password, u.Expired)
+			if err := revokeAccess(u.Password); err != nil {
+				log.Printf("Gagal mencabut akses %s: %v", u.Password, err)
+				revokeErr = err
+				continue
+			}
+			revokedCount++
+			revoked = append(revoked, u.Password)
+		}
+	}
+
+	recordExpiryRun(revokedCount, revokeErr)
+	publishEvent("expire.run", map[string]interface{}{
+		"revoked_count": revokedCount,
+		"revoked":       revoked,
+		"success":       revokeErr == nil,
+	})
+
+	if revokeErr != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, fmt.Sprintf("Expiration check gagal sebagian. Revoked: %d", revokedCount), nil)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, true, fmt.Sprintf("Expiration check complete. Revoked: %d", revokedCount), nil)
+}
+
+func recordExpiryRun(revoked int, runErr error) {
+	run := ExpiryRun{
+		Time:    time.Now().Format(time.RFC3339),
+		Success: runErr == nil,
+		Revoked: revoked,
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(ExpireFile, data, 0644); err != nil {
+		log.Printf("Gagal menyimpan status expiry: %v", err)
+	}
+}
+
+func revokeAccess(password string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	newConfigAuth := []string{}
+	changed := false
+	for _, p := range config.Auth.Config {
+		if p == password {
+			changed = true
+		} else {
+			newConfigAuth = append(newConfigAuth, p)
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	config.Auth.Config = newConfigAuth
+	if err := saveConfig(config); err != nil {
+		return err
+	}
+	recordSnapshot("expire " + password)
+	return restartService()
+}
+
+func enableUser(password string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	config, err := loadConfig()
+	if err != nil {
+		return
+	}
+
+	exists := false
+	for _, p := range config.Auth.Config {
+		if p == password {
+			exists = true
+			break
+		}
+	}
+
+	if !exists {
+		config.Auth.Config = append(config.Auth.Config, password)
+		saveConfig(config)
+		recordSnapshot("user.enable " + password)
+		restartService()
+	}
+}
+
+
+func loadConfig() (Config, error) {
+	var config Config
+	file, err := ioutil.ReadFile(ConfigFile)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(file, &config)
+	return config, err
+}
+
+func saveConfig(config Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ConfigFile, data, 0644)
+}
+
+func loadUsers() ([]UserStore, error) {
+	var users []UserStore
+	file, err := ioutil.ReadFile(UserDB)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return users, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(file, &users)
+	return users, err
+}
+
+func saveUsers(users []UserStore) error {
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(UserDB, data, 0644)
+}
+
+func restartService() error {
+	cmd := exec.Command("systemctl", "restart", "zivpn.service")
+	err := cmd.Run()
+
+	data := map[string]interface{}{"success": err == nil}
+	if err != nil {
+		data["error"] = err.Error()
+		metrics.mu.Lock()
+		metrics.restartFailures++
+		metrics.mu.Unlock()
+	}
+	publishEvent("service.restarted", data)
+	return err
+}
+
+// publishEvent records an event in the ring buffer and fans it out to every
+// connected /api/events client. Slow clients drop events rather than block.
+func publishEvent(eventType string, data interface{}) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	events.nextID++
+	ev := Event{
+		ID:   events.nextID,
+		Type: eventType,
+		Time: time.Now().Format(time.RFC3339),
+		Data: data,
+	}
+
+	events.ring = append(events.ring, ev)
+	if len(events.ring) > EventBufferSize {
+		events.ring = events.ring[len(events.ring)-EventBufferSize:]
+	}
+
+	for ch := range events.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// subscribeEvents registers a new listener and returns the buffered events
+// newer than lastID so the caller can replay them before going live.
+func subscribeEvents(lastID int64) (chan Event, []Event) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	backlog := []Event{}
+	for _, ev := range events.ring {
+		if ev.ID > lastID {
+			backlog = append(backlog, ev)
+		}
+	}
+
+	ch := make(chan Event, 32)
+	events.subs[ch] = true
+	return ch, backlog
+}
+
+func unsubscribeEvents(ch chan Event) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	delete(events.subs, ch)
+}
+
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonResponse(w, http.StatusInternalServerError, false, "Streaming tidak didukung", nil)
+		return
+	}
+
+	ch, backlog := subscribeEvents(parseLastEventID(r.Header.Get("Last-Event-ID")))
+	defer unsubscribeEvents(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, ev := range backlog {
+		writeEvent(w, ev)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			writeEvent(w, ev)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// parseLastEventID turns a Last-Event-ID header into the sequence number to
+// replay after. A new client only gets live events. A client from an earlier
+// boot gets the whole buffer, which holds nothing older than this boot.
+func parseLastEventID(header string) int64 {
+	if header == "" {
+		return math.MaxInt64
+	}
+	if epoch, seq, ok := strings.Cut(header, "-"); ok && epoch == events.epoch {
+		lastID, _ := strconv.ParseInt(seq, 10, 64)
+		return lastID
+	}
+	return 0
+}
+
+func writeEvent(w http.ResponseWriter, ev Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s-%d\nevent: %s\ndata: %s\n\n", events.epoch, ev.ID, ev.Type, data)
+}
+
+func observeRequest(handler string, status int, seconds float64) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	st, ok := metrics.handlers[handler]
+	if !ok {
+		st = &handlerStats{
+			requests: make(map[int]int64),
+			buckets:  make([]int64, len(latencyBuckets)),
+		}
+		metrics.handlers[handler] = st
+	}
+
+	st.requests[status]++
+	st.sum += seconds
+	st.count++
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			st.buckets[i]++
+		}
+	}
+}
+
+// serveMetrics writes the Prometheus text exposition format.
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	metrics.mu.Lock()
+	names := make([]string, 0, len(metrics.handlers))
+	for name := range metrics.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("# HELP zivpn_api_requests_total Total API requests by handler and status code.\n")
+	b.WriteString("# TYPE zivpn_api_requests_total counter\n")
+	for _, name := range names {
+		st := metrics.handlers[name]
+		codes := make([]int, 0, len(st.requests))
+		for code := range st.requests {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "zivpn_api_requests_total{handler=%q,code=\"%d\"} %d\n", name, code, st.requests[code])
+		}
+	}
+
+	b.WriteString("# HELP zivpn_api_request_duration_seconds API request latency by handler.\n")
+	b.WriteString("# TYPE zivpn_api_request_duration_seconds histogram\n")
+	for _, name := range names {
+		st := metrics.handlers[name]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(&b, "zivpn_api_request_duration_seconds_bucket{handler=%q,le=\"%g\"} %d\n", name, le, st.buckets[i])
+		}
+		fmt.Fprintf(&b, "zivpn_api_request_duration_seconds_bucket{handler=%q,le=\"+Inf\"} %d\n", name, st.count)
+		fmt.Fprintf(&b, "zivpn_api_request_duration_seconds_sum{handler=%q} %g\n", name, st.sum)
+		fmt.Fprintf(&b, "zivpn_api_request_duration_seconds_count{handler=%q} %d\n", name, st.count)
+	}
+
+	b.WriteString("# HELP zivpn_restart_failures_total Failed restarts of zivpn.service.\n")
+	b.WriteString("# TYPE zivpn_restart_failures_total counter\n")
+	fmt.Fprintf(&b, "zivpn_restart_failures_total %d\n", metrics.restartFailures)
+	metrics.mu.Unlock()
+
+	if users, err := loadUsers(); err == nil {
+		counts := map[string]int{"active": 0, "expired": 0, "locked": 0}
+		today := time.Now().Format("2006-01-02")
+		for _, u := range users {
+			if u.Status == "locked" {
+				counts["locked"]++
+			} else if u.Expired < today {
+				counts["expired"]++
+			} else {
+				counts["active"]++
+			}
+		}
+		b.WriteString("# HELP zivpn_users Managed users by status.\n")
+		b.WriteString("# TYPE zivpn_users gauge\n")
+		for _, status := range []string{"active", "expired", "locked"} {
+			fmt.Fprintf(&b, "zivpn_users{status=%q} %d\n", status, counts[status])
+		}
+		b.WriteString("# HELP zivpn_users_total Total managed users.\n")
+		b.WriteString("# TYPE zivpn_users_total gauge\n")
+		fmt.Fprintf(&b, "zivpn_users_total %d\n", len(users))
+	}
+
+	if config, err := loadConfig(); err == nil {
+		if notAfter, err := certExpiry(config.Cert); err == nil {
+			b.WriteString("# HELP zivpn_cert_expiry_timestamp_seconds Expiry of the TLS certificate in config.json.\n")
+			b.WriteString("# TYPE zivpn_cert_expiry_timestamp_seconds gauge\n")
+			fmt.Fprintf(&b, "zivpn_cert_expiry_timestamp_seconds %d\n", notAfter.Unix())
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+func certExpiry(path string) (time.Time, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no PEM data in %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+func healthCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	checks := []HealthCheck{}
+	add := func(name string, err error, okMessage string) {
+		if err != nil {
+			checks = append(checks, HealthCheck{Name: name, OK: false, Message: err.Error()})
+		} else {
+			checks = append(checks, HealthCheck{Name: name, OK: true, Message: okMessage})
+		}
+	}
+
+	add("core_active", checkCoreActive(), "zivpn.service active")
+
+	config, configErr := loadConfig()
+	add("config", configErr, "config.json valid")
+
+	if configErr != nil {
+		add("udp_listen", fmt.Errorf("config tidak terbaca"), "")
+		add("certificate", fmt.Errorf("config tidak terbaca"), "")
+	} else {
+		port, err := listenPort(config.Listen)
+		if err == nil {
+			err = checkUDPListening(port)
+		}
+		add("udp_listen", err, fmt.Sprintf("listening on udp/%d", port))
+
+		notAfter, err := checkCertificate(config.Cert, config.Key)
+		add("certificate", err, fmt.Sprintf("valid until %s", notAfter.Format("2006-01-02")))
+	}
+
+	add("state_writable", checkWritable(ConfigFile, UserDB), "state files writable")
+
+	run, err := checkLastExpiry()
+	add("last_expiry", err, fmt.Sprintf("last run %s, revoked %d", run.Time, run.Revoked))
+
+	healthy := true
+	for _, c := range checks {
+		if !c.OK {
+			healthy = false
+		}
+	}
+
+	if !healthy {
+		jsonResponse(w, http.StatusServiceUnavailable, false, "Unhealthy", checks)
+		return
+	}
+	jsonResponse(w, http.StatusOK, true, "Healthy", checks)
+}
+
+func checkCoreActive() error {
+	out, err := exec.Command("systemctl", "is-active", "zivpn.service").Output()
+	state := strings.TrimSpace(string(out))
+	if err != nil || state != "active" {
+		if state == "" {
+			state = "unknown"
+		}
+		return fmt.Errorf("zivpn.service %s", state)
+	}
+	return nil
+}
+
+// listenPort extracts the port from a listen address such as ":5667".
+func listenPort(listen string) (int, error) {
+	idx := strings.LastIndex(listen, ":")
+	port, err := strconv.Atoi(listen[idx+1:])
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("listen %q tidak valid", listen)
+	}
+	return port, nil
+}
+
+// checkUDPListening looks for a bound UDP socket on port in /proc/net/udp{,6}.
+func checkUDPListening(port int) error {
+	want := fmt.Sprintf(":%04X", port)
+	for _, file := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) > 1 && strings.HasSuffix(fields[1], want) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("tidak ada socket UDP di port %d", port)
+}
+
+func checkCertificate(certFile, keyFile string) (time.Time, error) {
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return time.Time{}, err
+	}
+	notAfter, err := certExpiry(certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if time.Until(notAfter) < CertWarnDays*24*time.Hour {
+		return notAfter, fmt.Errorf("sertifikat expired %s", notAfter.Format("2006-01-02"))
+	}
+	return notAfter, nil
+}
+
+// checkWritable opens each existing file for writing without truncating it;
+// missing files are checked by their directory instead.
+func checkWritable(paths ...string) error {
+	for _, path := range paths {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if os.IsNotExist(err) {
+			tmp, err := ioutil.TempFile(filepath.Dir(path), ".healthcheck-")
+			if err != nil {
+				return err
+			}
+			tmp.Close()
+			os.Remove(tmp.Name())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+	return nil
+}
+
+func checkLastExpiry() (ExpiryRun, error) {
+	var run ExpiryRun
+	data, err := ioutil.ReadFile(ExpireFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return run, fmt.Errorf("expiry belum pernah dijalankan")
+		}
+		return run, err
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, err
+	}
+	if !run.Success {
+		return run, fmt.Errorf("expiry %s gagal: %s", run.Time, run.Error)
+	}
+	if t, err := time.Parse(time.RFC3339, run.Time); err == nil && time.Since(t) > 25*time.Hour {
+		return run, fmt.Errorf("expiry terakhir %s (lebih dari 25 jam)", run.Time)
+	}
+	return run, nil
+}
+
+func serviceStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	out, err := exec.Command("systemctl", "show", "zivpn.service",
+		"--property=ActiveState,SubState,MainPID,ActiveEnterTimestamp,NRestarts").Output()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca status service", nil)
+		return
+	}
+
+	status := map[string]string{"unit": "zivpn.service"}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			status[kv[0]] = kv[1]
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, true, "Status service", status)
+}
+
+// serviceControl returns a handler that runs one systemctl action on the core.
+func serviceControl(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		var err error
+		if action == "restart" {
+			err = restartService()
+		} else {
+			err = exec.Command("systemctl", action, "zivpn.service").Run()
+			data := map[string]interface{}{"action": action, "success": err == nil}
+			if err != nil {
+				data["error"] = err.Error()
+			}
+			publishEvent("service."+action, data)
+		}
+
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, fmt.Sprintf("Gagal %s service", action), nil)
+			return
+		}
+		jsonResponse(w, http.StatusOK, true, fmt.Sprintf("Service berhasil di-%s", action), nil)
+	}
+}
+
+func tailLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	unitName := q.Get("unit")
+	if unitName == "" {
+		unitName = "zivpn"
+	}
+	unit, ok := LogUnits[unitName]
+	if !ok {
+		jsonResponse(w, http.StatusBadRequest, false, "Unit tidak dikenal", nil)
+		return
+	}
+
+	lines := DefaultLogLines
+	if v := q.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			jsonResponse(w, http.StatusBadRequest, false, "lines harus angka positif", nil)
+			return
+		}
+		if n > MaxLogLines {
+			n = MaxLogLines
+		}
+		lines = n
+	}
+
+	args := []string{"-u", unit, "--no-pager", "-o", "short-iso"}
+	if since := q.Get("since"); since != "" {
+		args = append(args, "--since="+since)
+	}
+	grep := strings.ToLower(q.Get("grep"))
+	if grep == "" {
+		args = append(args, "-n", strconv.Itoa(lines))
+	}
+
+	cmd := exec.Command("journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca journal", nil)
+		return
+	}
+
+	// A grep reads the whole journal, so only the last matches are kept
+	ring := make([]string, lines)
+	count := 0
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || (grep != "" && !strings.Contains(strings.ToLower(line), grep)) {
+			continue
+		}
+		ring[count%lines] = line
+		count++
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); err != nil || scanErr != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca journal", nil)
+		return
+	}
+
+	secrets := logSecrets()
+	result := []string{}
+	start := 0
+	if count > lines {
+		start = count - lines
+	}
+	for i := start; i < count; i++ {
+		result = append(result, redactLine(ring[i%lines], secrets))
+	}
+
+	jsonResponse(w, http.StatusOK, true, fmt.Sprintf("Log %s", unit), result)
+}
+
+// logSecrets collects every VPN password and the API key so they can be
+// masked in journal output.
+func logSecrets() []string {
+	secrets := []string{AuthToken}
+	if config, err := loadConfig(); err == nil {
+		secrets = append(secrets, config.Auth.Config...)
+	}
+	if users, err := loadUsers(); err == nil {
+		for _, u := range users {
+			secrets = append(secrets, u.Password)
+		}
+	}
+	// Longest first so a password containing another is masked whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+func redactLine(line string, secrets []string) string {
+	line = passwordPattern.ReplaceAllString(line, "${1}***")
+	for _, secret := range secrets {
+		if secret != "" {
+			line = strings.ReplaceAll(line, secret, "***")
+		}
+	}
+	return line
+}
+
+func manageConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		config, err := loadConfig()
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
+			return
+		}
+		jsonResponse(w, http.StatusOK, true, "Config", map[string]interface{}{
+			"listen":    config.Listen,
+			"cert":      config.Cert,
+			"key":       config.Key,
+			"obfs":      config.Obfs,
+			"auth_mode": config.Auth.Mode,
+			"users":     len(config.Auth.Config),
+		})
+	case http.MethodPut:
+		updateConfig(w, r)
+	default:
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+	}
+}
+
+func updateConfig(w http.ResponseWriter, r *http.Request) {
+	var req ConfigUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, "Invalid request body", nil)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	oldConfig, err := loadConfig()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
+		return
+	}
+
+	newConfig := oldConfig
+	if req.Listen != nil {
+		newConfig.Listen = strings.TrimSpace(*req.Listen)
+	}
+	if req.Cert != nil {
+		newConfig.Cert = strings.TrimSpace(*req.Cert)
+	}
+	if req.Key != nil {
+		newConfig.Key = strings.TrimSpace(*req.Key)
+	}
+	if req.Obfs != nil {
+		newConfig.Obfs = strings.TrimSpace(*req.Obfs)
+	}
+
+	if err := validateConfig(newConfig); err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+	// install.sh forwards the client port range to the installed port with
+	// iptables, so moving the core to another port would cut every client off
+	oldPort, _ := listenPort(oldConfig.Listen)
+	if newPort, _ := listenPort(newConfig.Listen); oldPort != 0 && newPort != oldPort {
+		jsonResponse(w, http.StatusBadRequest, false, fmt.Sprintf("Port listen tidak bisa diubah (tetap %d): port 6000-19999 di-DNAT ke port ini oleh iptables", oldPort), nil)
+		return
+	}
+
+	oldData, err := ioutil.ReadFile(ConfigFile)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca config", nil)
+		return
+	}
+	if err := ioutil.WriteFile(ConfigBackupFile, oldData, 0644); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membuat snapshot config", nil)
+		return
+	}
+
+	if err := saveConfig(newConfig); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan config", nil)
+		return
+	}
+
+	applyErr := restartService()
+	if applyErr == nil {
+		applyErr = waitForCore(newConfig.Listen, ConfigApplyTimeout)
+	}
+
+	if applyErr != nil {
+		log.Printf("Config baru gagal diterapkan (%v), mengembalikan config lama", applyErr)
+		rollbackErr := ioutil.WriteFile(ConfigFile, oldData, 0644)
+		if rollbackErr == nil {
+			rollbackErr = restartService()
+		}
+		if rollbackErr == nil {
+			rollbackErr = waitForCore(oldConfig.Listen, ConfigApplyTimeout)
+		}
+
+		if rollbackErr != nil {
+			log.Printf("Rollback config gagal: %v", rollbackErr)
+			publishEvent("config.rollback_failed", map[string]string{
+				"error":          applyErr.Error(),
+				"rollback_error": rollbackErr.Error(),
+			})
+			jsonResponse(w, http.StatusInternalServerError, false, "Config gagal diterapkan ("+applyErr.Error()+") dan core tidak pulih dengan config sebelumnya: "+rollbackErr.Error(), nil)
+			return
+		}
+		publishEvent("config.rolled_back", map[string]string{"error": applyErr.Error()})
+		jsonResponse(w, http.StatusInternalServerError, false, "Config gagal diterapkan, config sebelumnya dikembalikan: "+applyErr.Error(), nil)
+		return
+	}
+
+	recordSnapshot("config.update")
+	publishEvent("config.updated", map[string]string{
+		"listen": newConfig.Listen,
+		"obfs":   newConfig.Obfs,
+	})
+	jsonResponse(w, http.StatusOK, true, "Config berhasil diterapkan", map[string]string{
+		"listen": newConfig.Listen,
+		"cert":   newConfig.Cert,
+		"key":    newConfig.Key,
+		"obfs":   newConfig.Obfs,
+	})
+}
+
+func validateConfig(config Config) error {
+	if _, err := listenPort(config.Listen); err != nil {
+		return err
+	}
+	if host := config.Listen[:strings.LastIndex(config.Listen, ":")+1]; len(host) > 1 {
+		if net.ParseIP(strings.Trim(host[:len(host)-1], "[]")) == nil {
+			return fmt.Errorf("listen %q tidak valid", config.Listen)
+		}
+	}
+	if config.Obfs == "" || len(config.Obfs) > 64 || strings.ContainsAny(config.Obfs, " \t\r\n") {
+		return fmt.Errorf("obfs harus 1-64 karakter tanpa spasi")
+	}
+	if _, err := tls.LoadX509KeyPair(config.Cert, config.Key); err != nil {
+		return fmt.Errorf("cert/key tidak valid: %v", err)
+	}
+	return nil
+}
+
+// waitForCore polls until zivpn.service is active and bound to the listen
+// port, or the timeout expires.
+func waitForCore(listen string, timeout time.Duration) error {
+	port, err := listenPort(listen)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = checkCoreActive()
+		if err == nil {
+			err = checkUDPListening(port)
+		}
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// recordSnapshot is snapshotState for mutation paths, where a failed
+// snapshot must not fail the request that already changed state.
+func recordSnapshot(reason string) {
+	if err := snapshotState(reason); err != nil {
+		log.Printf("Gagal membuat snapshot (%s): %v", reason, err)
+	}
+}
+
+// snapshotState copies the current config.json and users.json into the next
+// numbered directory under HistoryDir and prunes the oldest beyond historyKeep.
+func snapshotState(reason string) error {
+	snaps, err := readSnapshots()
+	if err != nil {
+		return err
+	}
+
+	id := 1
+	if len(snaps) > 0 {
+		id = snaps[len(snaps)-1].ID + 1
+	}
+
+	dir := snapshotDir(id)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	users := 0
+	for _, file := range []string{ConfigFile, UserDB} {
+		data, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if file == UserDB {
+			var list []UserStore
+			json.Unmarshal(data, &list)
+			users = len(list)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0600); err != nil {
+			return err
+		}
+	}
+
+	meta, _ := json.MarshalIndent(Snapshot{
+		ID:     id,
+		Time:   time.Now().Format(time.RFC3339),
+		Reason: reason,
+		Users:  users,
+	}, "", "  ")
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), meta, 0600); err != nil {
+		return err
+	}
+
+	snaps = append(snaps, Snapshot{ID: id})
+	for len(snaps) > historyKeep && historyKeep > 0 {
+		os.RemoveAll(snapshotDir(snaps[0].ID))
+		snaps = snaps[1:]
+	}
+	return nil
+}
+
+func snapshotDir(id int) string {
+	return filepath.Join(HistoryDir, fmt.Sprintf("%06d", id))
+}
+
+// readSnapshots returns all snapshots in HistoryDir, oldest first.
+func readSnapshots() ([]Snapshot, error) {
+	snaps := []Snapshot{}
+	entries, err := ioutil.ReadDir(HistoryDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snaps, nil
+		}
+		return nil, err
+	}
+
+	for _, e := range entries {
+		id, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		snap := Snapshot{ID: id}
+		if data, err := ioutil.ReadFile(filepath.Join(snapshotDir(id), "meta.json")); err == nil {
+			json.Unmarshal(data, &snap)
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].ID < snaps[j].ID })
+	return snaps, nil
+}
+
+func loadSnapshot(id int) (Config, []UserStore, error) {
+	var config Config
+	var users []UserStore
+	dir := snapshotDir(id)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return config, nil, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, nil, err
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return config, nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &users); err != nil {
+			return config, nil, err
+		}
+	}
+	return config, users, nil
+}
+
+func listSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	snaps, err := readSnapshots()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca history", nil)
+		return
+	}
+	jsonResponse(w, http.StatusOK, true, "Daftar snapshot", snaps)
+}
+
+func diffSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	from, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+	to, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil {
+		jsonResponse(w, http.StatusBadRequest, false, "from dan to harus ID snapshot", nil)
+		return
+	}
+
+	fromConfig, fromUsers, err := loadSnapshot(from)
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, false, fmt.Sprintf("Snapshot %d tidak ditemukan", from), nil)
+		return
+	}
+	toConfig, toUsers, err := loadSnapshot(to)
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, false, fmt.Sprintf("Snapshot %d tidak ditemukan", to), nil)
+		return
+	}
+
+	diff := diffState(fromConfig, fromUsers, toConfig, toUsers)
+	diff.From, diff.To = from, to
+	jsonResponse(w, http.StatusOK, true, "Diff snapshot", diff)
+}
+
+// diffState compares two config/user states. Users are matched by password.
+func diffState(fromConfig Config, fromUsers []UserStore, toConfig Config, toUsers []UserStore) SnapshotDiff {
+	diff := SnapshotDiff{
+		Added:         []UserStore{},
+		Removed:       []UserStore{},
+		Changed:       []UserChange{},
+		ConfigChanged: []string{},
+	}
+
+	before := make(map[string]UserStore)
+	for _, u := range fromUsers {
+		before[u.Password] = u
+	}
+	after := make(map[string]UserStore)
+	for _, u := range toUsers {
+		after[u.Password] = u
+		old, ok := before[u.Password]
+		if !ok {
+			diff.Added = append(diff.Added, u)
+		} else if old != u {
+			diff.Changed = append(diff.Changed, UserChange{Password: u.Password, Before: old, After: u})
+		}
+	}
+	for _, u := range fromUsers {
+		if _, ok := after[u.Password]; !ok {
+			diff.Removed = append(diff.Removed, u)
+		}
+	}
+
+	if fromConfig.Listen != toConfig.Listen {
+		diff.ConfigChanged = append(diff.ConfigChanged, "listen")
+	}
+	if fromConfig.Cert != toConfig.Cert {
+		diff.ConfigChanged = append(diff.ConfigChanged, "cert")
+	}
+	if fromConfig.Key != toConfig.Key {
+		diff.ConfigChanged = append(diff.ConfigChanged, "key")
+	}
+	if fromConfig.Obfs != toConfig.Obfs {
+		diff.ConfigChanged = append(diff.ConfigChanged, "obfs")
+	}
+	if strings.Join(fromConfig.Auth.Config, "\n") != strings.Join(toConfig.Auth.Config, "\n") {
+		diff.ConfigChanged = append(diff.ConfigChanged, "auth")
+	}
+	return diff
+}
+
+func rollbackSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		jsonResponse(w, http.StatusBadRequest, false, "Invalid request body", nil)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	config, users, err := loadSnapshot(req.ID)
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, false, fmt.Sprintf("Snapshot %d tidak ditemukan", req.ID), nil)
+		return
+	}
+
+	if err := saveConfig(config); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan config", nil)
+		return
+	}
+	if users == nil {
+		users = []UserStore{}
+	}
+	if err := saveUsers(users); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan database user", nil)
+		return
+	}
+	recordSnapshot(fmt.Sprintf("rollback %d", req.ID))
+
+	if err := restartService(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal merestart service", nil)
+		return
+	}
+
+	publishEvent("snapshot.rolled_back", map[string]int{"id": req.ID})
+	jsonResponse(w, http.StatusOK, true, fmt.Sprintf("Berhasil rollback ke snapshot %d", req.ID), nil)
+}
+
+func downloadBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	entries, err := selectBackupEntries(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+
+	settings, err := loadBackupSettings()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca pengaturan backup", nil)
+		return
+	}
+	passphrase := settings.Passphrase
+	if p := r.Header.Get("X-Backup-Passphrase"); p != "" {
+		passphrase = p
+	}
+	recipients := append(settings.Recipients, r.URL.Query()["recipient"]...)
+	for _, recipient := range recipients {
+		if _, err := parsePublicKey(recipient); err != nil {
+			jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+			return
+		}
+	}
+
+	mutex.Lock()
+	data, err := buildBackup(entries)
+	mutex.Unlock()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membuat backup", nil)
+		return
+	}
+
+	fileName := fmt.Sprintf("zivpn-backup-%s.zip", time.Now().Format("20060102-150405"))
+	contentType := "application/zip"
+	if passphrase != "" || len(recipients) > 0 {
+		data, err = encryptBackup(data, passphrase, recipients)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal mengenkripsi backup", nil)
+			return
+		}
+		fileName += ".enc"
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// buildBackup zips every existing file among entries plus a manifest.json
+// carrying the SHA-256 of each file.
+func buildBackup(entries []BackupEntry) ([]byte, error) {
+	hostname, _ := os.Hostname()
+	manifest := BackupManifest{
+		Version:   BackupVersion,
+		Hostname:  hostname,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Files:     make(map[string]string),
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	seen := make(map[string]bool)
+	for _, entry := range expandEntries(entries) {
+		data, err := ioutil.ReadFile(entry.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !seen[entry.Component] {
+			seen[entry.Component] = true
+			manifest.Components = append(manifest.Components, entry.Component)
+		}
+
+		name := entry.Name
+		fw, err := zipWriter.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fw, err := zipWriter.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(manifestData); err != nil {
+		return nil, err
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreBackup accepts a backup ZIP as the raw body or as the "file" field
+// of a multipart form. With ?dry_run=1 nothing is written. Encrypted backups
+// are opened with X-Backup-Secret (passphrase or secret key), falling back to
+// BackupIdentityFile.
+func restoreBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBackupSize)
+
+	var body []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			jsonResponse(w, http.StatusBadRequest, false, "Field file tidak ditemukan", nil)
+			return
+		}
+		defer file.Close()
+		body, err = ioutil.ReadAll(file)
+	} else {
+		body, err = ioutil.ReadAll(r.Body)
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, fmt.Sprintf("Gagal membaca file (maksimal %d MB)", MaxBackupSize>>20), nil)
+		return
+	}
+
+	encrypted := isEncryptedBackup(body)
+	if encrypted {
+		secret := r.Header.Get("X-Backup-Secret")
+		if secret == "" {
+			if identity, err := ioutil.ReadFile(BackupIdentityFile); err == nil {
+				secret = strings.TrimSpace(string(identity))
+			}
+		}
+		body, err = decryptBackup(body, secret)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, false, err.Error(), map[string]bool{"encrypted": true})
+			return
+		}
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "1" || r.URL.Query().Get("dry_run") == "true"
+
+	entries, err := selectBackupEntries(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	report, err := restoreArchive(body, entries, dryRun)
+	report.Encrypted = encrypted
+	if err != nil {
+		if report.RolledBack {
+			publishEvent("backup.restore_failed", map[string]interface{}{"error": err.Error()})
+			jsonResponse(w, http.StatusInternalServerError, false, err.Error(), report)
+			return
+		}
+		jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+
+	if dryRun {
+		jsonResponse(w, http.StatusOK, true, "Dry run restore", report)
+		return
+	}
+
+	publishEvent("backup.restored", map[string]interface{}{"files": report.Files})
+	jsonResponse(w, http.StatusOK, true, "Restore berhasil", report)
+
+	// A new api_port only takes effect once the API listens again
+	for _, svc := range report.RestartRequired {
+		if svc == "zivpn-api" {
+			go func() {
+				time.Sleep(2 * time.Second)
+				exec.Command("systemctl", "restart", "zivpn-api").Run()
+			}()
+		}
+	}
+}
+
+// restoreArchive validates the whole archive before touching any file on
+// disk, then writes the selected entries unless dryRun is set. A full backup
+// of the current state is kept first; if a write fails or the core does not
+// come back up, every written file is put back.
+func restoreArchive(data []byte, entries []BackupEntry, dryRun bool) (RestoreReport, error) {
+	report := RestoreReport{
+		DryRun:          dryRun,
+		Files:           []string{},
+		Skipped:         []string{},
+		UsersAdded:      []string{},
+		UsersRemoved:    []string{},
+		RestartRequired: []string{},
+	}
+
+	allowed := make(map[string]BackupEntry)
+	for _, entry := range entries {
+		allowed[entry.Name] = entry
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return report, fmt.Errorf("File bukan format ZIP yang valid")
+	}
+
+	if len(zipReader.File) > MaxBackupFiles {
+		return report, fmt.Errorf("Backup berisi terlalu banyak file (%d)", len(zipReader.File))
+	}
+
+	contents := make(map[string][]byte)
+	var manifest *BackupManifest
+	var total int
+	for _, f := range zipReader.File {
+		if entry, ok := matchEntry(allowed, f.Name); ok {
+			allowed[f.Name] = entry
+		} else if f.Name != "manifest.json" {
+			if !f.FileInfo().IsDir() {
+				report.Skipped = append(report.Skipped, f.Name)
+			}
+			continue
+		}
+		if _, dup := contents[f.Name]; dup || (f.Name == "manifest.json" && manifest != nil) {
+			return report, fmt.Errorf("%s muncul lebih dari sekali di backup", f.Name)
+		}
+		if f.UncompressedSize64 > MaxBackupFileSize {
+			return report, fmt.Errorf("%s terlalu besar", f.Name)
+		}
+		if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > MaxCompressionRatio {
+			return report, fmt.Errorf("Rasio kompresi %s tidak wajar, backup ditolak", f.Name)
+		}
+
+		content, err := readZipFile(f)
+		if err != nil {
+			return report, err
+		}
+		total += len(content)
+		if total > MaxBackupTotalSize {
+			return report, fmt.Errorf("Isi backup terlalu besar")
+		}
+
+		if f.Name == "manifest.json" {
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(content, manifest); err != nil {
+				return report, fmt.Errorf("manifest.json tidak valid: %v", err)
+			}
+			continue
+		}
+		contents[f.Name] = content
+	}
+
+	if manifest == nil {
+		report.Legacy = true
+	} else if err := verifyManifest(manifest, contents, allowed); err != nil {
+		return report, err
+	}
+
+	if len(contents) == 0 {
+		return report, fmt.Errorf("Backup tidak berisi file yang bisa direstore")
+	}
+	sort.Strings(report.Skipped)
+
+	for name, content := range contents {
+		if err := validateBackupFile(name, content); err != nil {
+			return report, err
+		}
+		report.Files = append(report.Files, name)
+	}
+	sort.Strings(report.Files)
+
+	if cert, ok := contents["zivpn.crt"]; ok {
+		if key, ok := contents["zivpn.key"]; ok {
+			if _, err := tls.X509KeyPair(cert, key); err != nil {
+				return report, fmt.Errorf("zivpn.crt dan zivpn.key tidak cocok: %v", err)
+			}
+		}
+	}
+
+	if content, ok := contents["config.json"]; ok {
+		contents["config.json"] = pinCertPaths(content)
+	}
+
+	restart := make(map[string]bool)
+	for _, name := range report.Files {
+		switch name {
+		case "api_port":
+			restart["zivpn-api"] = true
+			restart["zivpn-bot"] = true
+		case "apikey", "bot-config.json", "bot-state.json", "paid-bot-state.json", "bot-quota.json":
+			restart["zivpn-bot"] = true
+		}
+		// Catalogs are read once when the bot starts
+		if allowed[name].Component == "lang" {
+			restart["zivpn-bot"] = true
+		}
+	}
+	for _, svc := range []string{"zivpn-api", "zivpn-bot"} {
+		if restart[svc] {
+			report.RestartRequired = append(report.RestartRequired, svc)
+		}
+	}
+
+	if content, ok := contents["users.json"]; ok {
+		var incoming []UserStore
+		json.Unmarshal(content, &incoming)
+		current, _ := loadUsers()
+
+		currentSet := make(map[string]bool)
+		for _, u := range current {
+			currentSet[u.Password] = true
+		}
+		incomingSet := make(map[string]bool)
+		for _, u := range incoming {
+			incomingSet[u.Password] = true
+			if !currentSet[u.Password] {
+				report.UsersAdded = append(report.UsersAdded, u.Password)
+			}
+		}
+		for _, u := range current {
+			if !incomingSet[u.Password] {
+				report.UsersRemoved = append(report.UsersRemoved, u.Password)
+			}
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	safety, err := writePreRestoreBackup()
+	if err != nil {
+		return report, fmt.Errorf("Gagal membuat backup pengaman sebelum restore: %v", err)
+	}
+	report.SafetyBackup = safety
+	recordSnapshot("pre-restore")
+
+	// Keep the current bytes of every file about to be replaced
+	previous := make(map[string][]byte)
+	existed := make(map[string]bool)
+	for _, name := range report.Files {
+		data, err := ioutil.ReadFile(allowed[name].Path)
+		if err != nil && !os.IsNotExist(err) {
+			return report, fmt.Errorf("Gagal membaca %s saat ini: %v", name, err)
+		}
+		previous[name] = data
+		existed[name] = err == nil
+	}
+
+	written := []string{}
+	for _, name := range report.Files {
+		if err := writeBackupEntry(allowed[name], contents[name]); err != nil {
+			revertRestore(written, allowed, previous, existed)
+			report.Files = []string{}
+			report.RolledBack = true
+			return report, fmt.Errorf("Gagal menulis %s: %v. Semua file dikembalikan", name, err)
+		}
+		written = append(written, name)
+	}
+
+	coreChanged := false
+	for _, name := range written {
+		if allowed[name].Component == "core" || allowed[name].Component == "users" {
+			coreChanged = true
+		}
+	}
+	if coreChanged {
+		err := restartService()
+		if err == nil {
+			var config Config
+			if config, err = loadConfig(); err == nil {
+				err = waitForCore(config.Listen, ConfigApplyTimeout)
+			}
+		}
+		if err != nil {
+			revertRestore(written, allowed, previous, existed)
+			restartService()
+			report.Files = []string{}
+			report.RolledBack = true
+			return report, fmt.Errorf("Core gagal berjalan setelah restore (%v). Semua file dikembalikan", err)
+		}
+	}
+
+	if _, ok := contents["apikey"]; ok {
+		AuthToken = strings.TrimSpace(string(contents["apikey"]))
+	}
+	recordSnapshot("restore")
+	return report, nil
+}
+
+// readZipFile reads one archive entry without trusting the size in its
+// header.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("Gagal membaca %s: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(rc, MaxBackupFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Gagal membaca %s: %v", f.Name, err)
+	}
+	if len(content) > MaxBackupFileSize {
+		return nil, fmt.Errorf("%s terlalu besar", f.Name)
+	}
+	return content, nil
+}
+
+func writeBackupEntry(entry BackupEntry, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(entry.Path, content, entry.Mode); err != nil {
+		return err
+	}
+	// WriteFile only applies the mode when it creates the file
+	return os.Chmod(entry.Path, entry.Mode)
+}
+
+// revertRestore puts back the previous contents of the written files and
+// removes the ones that did not exist before.
+func revertRestore(written []string, allowed map[string]BackupEntry, previous map[string][]byte, existed map[string]bool) {
+	for _, name := range written {
+		entry := allowed[name]
+		var err error
+		if existed[name] {
+			err = writeBackupEntry(entry, previous[name])
+		} else {
+			err = os.Remove(entry.Path)
+		}
+		if err != nil {
+			log.Printf("Gagal mengembalikan %s: %v", name, err)
+		}
+	}
+}
+
+// writePreRestoreBackup stores a full backup of the current state under
+// BackupDir/pre-restore, keeping the last PreRestoreKeep.
+func writePreRestoreBackup() (string, error) {
+	dir := filepath.Join(BackupDir, "pre-restore")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data, err := buildBackup(backupEntries())
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("zivpn-backup-%s.zip", time.Now().Format("20060102-150405")))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	pruneBackupFiles(dir, PreRestoreKeep)
+	return path, nil
+}
+
+func verifyManifest(manifest *BackupManifest, contents map[string][]byte, allowed map[string]BackupEntry) error {
+	if manifest.Version < 1 || manifest.Version > BackupVersion {
+		return fmt.Errorf("Versi backup %d tidak didukung", manifest.Version)
+	}
+	for name, content := range contents {
+		want, ok := manifest.Files[name]
+		if !ok {
+			return fmt.Errorf("%s tidak tercatat di manifest", name)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != want {
+			return fmt.Errorf("Checksum %s tidak cocok", name)
+		}
+	}
+	for name := range manifest.Files {
+		if _, ok := contents[name]; ok {
+			continue
+		}
+		if _, ok := matchEntry(allowed, name); ok {
+			return fmt.Errorf("%s ada di manifest tetapi tidak ada di arsip", name)
+		}
+	}
+	return nil
+}
+
+// validateBackupFile checks that a file from an archive is well-formed
+// before it is allowed to replace the live copy.
+func validateBackupFile(name string, content []byte) error {
+	if strings.HasPrefix(name, "lang/") {
+		var catalog struct {
+			Name     string            `json:"name"`
+			Messages map[string]string `json:"messages"`
+		}
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			return fmt.Errorf("%s tidak valid: %v", name, err)
+		}
+		return nil
+	}
+
+	switch name {
+	case "config.json":
+		var config Config
+		if err := json.Unmarshal(content, &config); err != nil {
+			return fmt.Errorf("config.json tidak valid: %v", err)
+		}
+		if _, err := listenPort(config.Listen); err != nil {
+			return fmt.Errorf("config.json: %v", err)
+		}
+		if config.Cert == "" || config.Key == "" || config.Obfs == "" {
+			return fmt.Errorf("config.json: cert, key dan obfs wajib diisi")
+		}
+		if !filepath.IsAbs(config.Cert) || !filepath.IsAbs(config.Key) {
+			return fmt.Errorf("config.json: cert dan key harus path absolut")
+		}
+		if len(config.Obfs) > 64 || strings.ContainsAny(config.Obfs, " \t\r\n") {
+			return fmt.Errorf("config.json: obfs tidak valid")
+		}
+		if config.Auth.Mode != "passwords" {
+			return fmt.Errorf("config.json: auth.mode harus \"passwords\"")
+		}
+		for _, p := range config.Auth.Config {
+			if strings.TrimSpace(p) == "" {
+				return fmt.Errorf("config.json: auth.config berisi password kosong")
+			}
+		}
+	case "users.json":
+		var users []UserStore
+		if err := json.Unmarshal(content, &users); err != nil {
+			return fmt.Errorf("users.json tidak valid: %v", err)
+		}
+		seen := make(map[string]bool)
+		for i, u := range users {
+			if u.Password == "" {
+				return fmt.Errorf("users.json: user #%d tanpa password", i+1)
+			}
+			if seen[u.Password] {
+				return fmt.Errorf("users.json: user %s duplikat", u.Password)
+			}
+			seen[u.Password] = true
+			if _, err := time.Parse("2006-01-02", u.Expired); err != nil {
+				return fmt.Errorf("users.json: tanggal expired %s tidak valid", u.Password)
+			}
+			switch strings.ToLower(u.Status) {
+			case "", "active", "locked", "expired":
+			default:
+				return fmt.Errorf("users.json: status %s tidak valid", u.Password)
+			}
+		}
+	case "bot-config.json":
+		var botConfig struct {
+			BotToken string `json:"bot_token"`
+			AdminID  int64  `json:"admin_id"`
+		}
+		if err := json.Unmarshal(content, &botConfig); err != nil {
+			return fmt.Errorf("bot-config.json tidak valid: %v", err)
+		}
+		if botConfig.BotToken == "" || botConfig.AdminID == 0 {
+			return fmt.Errorf("bot-config.json: bot_token dan admin_id wajib diisi")
+		}
+	case "wallets.json":
+		var wallets []struct {
+			TelegramID int64 `json:"telegram_id"`
+			Balance    int   `json:"balance"`
+		}
+		if err := json.Unmarshal(content, &wallets); err != nil {
+			return fmt.Errorf("wallets.json tidak valid: %v", err)
+		}
+		seen := make(map[int64]bool)
+		for i, wl := range wallets {
+			if wl.TelegramID == 0 || wl.Balance < 0 || seen[wl.TelegramID] {
+				return fmt.Errorf("wallets.json: entri #%d tidak valid", i+1)
+			}
+			seen[wl.TelegramID] = true
+		}
+	case "metrics.json":
+		var entries []map[string]interface{}
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return fmt.Errorf("metrics.json tidak valid: %v", err)
+		}
+	case "bot-verified.json":
+		var verified struct {
+			Users map[int64]time.Time `json:"users"`
+		}
+		if err := json.Unmarshal(content, &verified); err != nil {
+			return fmt.Errorf("bot-verified.json tidak valid: %v", err)
+		}
+	case "bot-users.json", "paid-bot-users.json":
+		var registry struct {
+			Users map[int64]struct {
+				ID       int64     `json:"id"`
+				LastSeen time.Time `json:"last_seen"`
+			} `json:"users"`
+		}
+		if err := json.Unmarshal(content, &registry); err != nil {
+			return fmt.Errorf("%s tidak valid: %v", name, err)
+		}
+	case "bot-quota.json":
+		var quota struct {
+			Creates map[int64][]time.Time `json:"creates"`
+		}
+		if err := json.Unmarshal(content, &quota); err != nil {
+			return fmt.Errorf("bot-quota.json tidak valid: %v", err)
+		}
+	case "bot-state.json", "paid-bot-state.json":
+		var state struct {
+			Users map[int64]struct {
+				State     string            `json:"state"`
+				Data      map[string]string `json:"data"`
+				UpdatedAt time.Time         `json:"updated_at"`
+			} `json:"users"`
+			Messages map[int64]int `json:"messages"`
+			Payments map[int64]struct {
+				OrderID   string    `json:"order_id"`
+				Price     int       `json:"price"`
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"payments"`
+		}
+		if err := json.Unmarshal(content, &state); err != nil {
+			return fmt.Errorf("%s tidak valid: %v", name, err)
+		}
+	case "zivpn.crt":
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return fmt.Errorf("zivpn.crt bukan PEM")
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("zivpn.crt tidak valid: %v", err)
+		}
+	case "zivpn.key":
+		if block, _ := pem.Decode(content); block == nil {
+			return fmt.Errorf("zivpn.key bukan PEM")
+		}
+	case "api_port":
+		port, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("api_port tidak valid")
+		}
+	case "domain", "apikey":
+		if strings.TrimSpace(string(content)) == "" {
+			return fmt.Errorf("%s kosong", name)
+		}
+	}
+	return nil
+}
+
+// pinCertPaths points a restored config.json at the certificate and key
+// this server keeps. Restore writes as root, so the archive must not choose
+// where zivpn.crt and zivpn.key go.
+func pinCertPaths(content []byte) []byte {
+	var config Config
+	if err := json.Unmarshal(content, &config); err != nil {
+		return content
+	}
+	for _, entry := range backupEntries() {
+		switch entry.Name {
+		case "zivpn.crt":
+			config.Cert = entry.Path
+		case "zivpn.key":
+			config.Key = entry.Path
+		}
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return content
+	}
+	return data
+}
+
+// backupEntries is BackupEntries with the certificate and key the core
+// actually uses, which PUT /api/config can move.
+func backupEntries() []BackupEntry {
+	config, err := loadConfig()
+	if err != nil {
+		return BackupEntries
+	}
+	return withCertPaths(BackupEntries, config)
+}
+
+// withCertPaths points the zivpn.crt and zivpn.key entries at config's files.
+func withCertPaths(entries []BackupEntry, config Config) []BackupEntry {
+	resolved := make([]BackupEntry, len(entries))
+	for i, entry := range entries {
+		switch {
+		case entry.Name == "zivpn.crt" && config.Cert != "":
+			entry.Path = config.Cert
+		case entry.Name == "zivpn.key" && config.Key != "":
+			entry.Path = config.Key
+		}
+		resolved[i] = entry
+	}
+	return resolved
+}
+
+// expandEntries replaces wildcard entries with one entry per existing file.
+func expandEntries(entries []BackupEntry) []BackupEntry {
+	var expanded []BackupEntry
+	for _, entry := range entries {
+		if !strings.Contains(entry.Name, "*") {
+			expanded = append(expanded, entry)
+			continue
+		}
+		files, _ := filepath.Glob(entry.Path)
+		for _, file := range files {
+			name := filepath.Join(filepath.Dir(entry.Name), filepath.Base(file))
+			expanded = append(expanded, BackupEntry{name, file, entry.Mode, entry.Component})
+		}
+	}
+	return expanded
+}
+
+var backupFileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// matchEntry finds the entry for an archive name, resolving names covered
+// by a wildcard entry to a file in that entry's directory. The wildcard
+// cannot match a "/", so the file stays inside the directory.
+func matchEntry(allowed map[string]BackupEntry, name string) (BackupEntry, bool) {
+	if entry, ok := allowed[name]; ok && !strings.Contains(entry.Name, "*") {
+		return entry, true
+	}
+	for _, entry := range allowed {
+		if !strings.Contains(entry.Name, "*") {
+			continue
+		}
+		if ok, _ := filepath.Match(entry.Name, name); ok && backupFileName.MatchString(filepath.Base(name)) {
+			path := filepath.Join(filepath.Dir(entry.Path), filepath.Base(name))
+			return BackupEntry{name, path, entry.Mode, entry.Component}, true
+		}
+	}
+	return BackupEntry{}, false
+}
+
+// selectBackupEntries applies the comma-separated ?include= and ?exclude=
+// component lists to BackupEntries.
+func selectBackupEntries(r *http.Request) ([]BackupEntry, error) {
+	known := make(map[string]bool)
+	for _, entry := range BackupEntries {
+		known[entry.Component] = true
+	}
+
+	parse := func(param string) (map[string]bool, error) {
+		set := make(map[string]bool)
+		for _, c := range strings.Split(r.URL.Query().Get(param), ",") {
+			c = strings.TrimSpace(c)
+			if c == "" {
+				continue
+			}
+			if !known[c] {
+				return nil, fmt.Errorf("Komponen %q tidak dikenal", c)
+			}
+			set[c] = true
+		}
+		return set, nil
+	}
+
+	include, err := parse("include")
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := parse("exclude")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []BackupEntry{}
+	for _, entry := range backupEntries() {
+		if len(include) > 0 && !include[entry.Component] {
+			continue
+		}
+		if exclude[entry.Component] {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("Tidak ada komponen yang dipilih")
+	}
+	return entries, nil
+}
+
+// manageBackupSettings shows (GET) or replaces (PUT) the backup encryption
+// settings. The passphrase itself is never returned.
+func manageBackupSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		settings, err := loadBackupSettings()
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca pengaturan backup", nil)
+			return
+		}
+		jsonResponse(w, http.StatusOK, true, "Pengaturan backup", backupSettingsView(settings))
+	case http.MethodPut:
+		var req struct {
+			Passphrase *string   `json:"passphrase"`
+			Recipients *[]string `json:"recipients"`
+			Schedule   *string   `json:"schedule"`
+			KeepDaily  *int      `json:"keep_daily"`
+			KeepWeekly *int      `json:"keep_weekly"`
+			Telegram   *bool     `json:"telegram"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, false, "Invalid request body", nil)
+			return
+		}
 
-func saveConfig(config Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		settings, err := loadBackupSettings()
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca pengaturan backup", nil)
+			return
+		}
+		if req.Passphrase != nil {
+			if *req.Passphrase != "" && len(*req.Passphrase) < 8 {
+				jsonResponse(w, http.StatusBadRequest, false, "Passphrase minimal 8 karakter", nil)
+				return
+			}
+			settings.Passphrase = *req.Passphrase
+		}
+		if req.Recipients != nil {
+			settings.Recipients = []string{}
+			for _, recipient := range *req.Recipients {
+				recipient = strings.TrimSpace(recipient)
+				if _, err := parsePublicKey(recipient); err != nil {
+					jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+					return
+				}
+				settings.Recipients = append(settings.Recipients, recipient)
+			}
+		}
+		if req.Schedule != nil {
+			schedule := strings.TrimSpace(*req.Schedule)
+			if schedule != "" {
+				if _, err := parseCron(schedule); err != nil {
+					jsonResponse(w, http.StatusBadRequest, false, err.Error(), nil)
+					return
+				}
+			}
+			settings.Schedule = schedule
+		}
+		if req.KeepDaily != nil {
+			if *req.KeepDaily < 1 || *req.KeepDaily > 365 {
+				jsonResponse(w, http.StatusBadRequest, false, "keep_daily harus 1-365", nil)
+				return
+			}
+			settings.KeepDaily = *req.KeepDaily
+		}
+		if req.KeepWeekly != nil {
+			if *req.KeepWeekly < 0 || *req.KeepWeekly > 365 {
+				jsonResponse(w, http.StatusBadRequest, false, "keep_weekly harus 0-365", nil)
+				return
+			}
+			settings.KeepWeekly = *req.KeepWeekly
+		}
+		if req.Telegram != nil {
+			settings.Telegram = *req.Telegram
+		}
+
+		if err := saveBackupSettings(settings); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan pengaturan backup", nil)
+			return
+		}
+		jsonResponse(w, http.StatusOK, true, "Pengaturan backup disimpan", backupSettingsView(settings))
+	default:
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+	}
+}
+
+// generateBackupKey returns a fresh recipient/secret key pair. Nothing is
+// stored unless ?save=1, which adds the public key to the recipients and
+// keeps the secret key in BackupIdentityFile for unattended restores.
+func generateBackupKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	pub, sec, err := generateBackupKeypair()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membuat key", nil)
+		return
+	}
+
+	if r.URL.Query().Get("save") == "1" {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		settings, err := loadBackupSettings()
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca pengaturan backup", nil)
+			return
+		}
+		settings.Recipients = append(settings.Recipients, pub)
+		if err := ioutil.WriteFile(BackupIdentityFile, []byte(sec+"\n"), 0600); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan secret key", nil)
+			return
+		}
+		if err := saveBackupSettings(settings); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, false, "Gagal menyimpan pengaturan backup", nil)
+			return
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, true, "Key backup dibuat", map[string]string{
+		"public_key": pub,
+		"secret_key": sec,
+	})
+}
+
+// backupSettingsView is BackupSettings as returned by the API, without the
+// passphrase itself.
+func backupSettingsView(settings BackupSettings) map[string]interface{} {
+	_, identityErr := os.Stat(BackupIdentityFile)
+	return map[string]interface{}{
+		"passphrase_set":  settings.Passphrase != "",
+		"recipients":      settings.Recipients,
+		"server_identity": identityErr == nil,
+		"schedule":        settings.Schedule,
+		"keep_daily":      settings.KeepDaily,
+		"keep_weekly":     settings.KeepWeekly,
+		"telegram":        settings.Telegram,
+	}
+}
+
+func loadBackupSettings() (BackupSettings, error) {
+	settings := BackupSettings{Recipients: []string{}, KeepDaily: 7, KeepWeekly: 4}
+	file, err := ioutil.ReadFile(BackupSettingsFile)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	err = json.Unmarshal(file, &settings)
+	if settings.Recipients == nil {
+		settings.Recipients = []string{}
+	}
+	return settings, err
+}
+
+func saveBackupSettings(settings BackupSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(ConfigFile, data, 0644)
+	return ioutil.WriteFile(BackupSettingsFile, data, 0600)
 }
 
-func loadUsers() ([]UserStore, error) {
-	var users []UserStore
-	file, err := ioutil.ReadFile(UserDB)
+func isEncryptedBackup(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EncryptedMagic))
+}
+
+// encryptBackup seals plain under a random file key and wraps that key for
+// the passphrase (scrypt) and for every recipient (X25519 + HKDF). The
+// header line is authenticated as additional data.
+func encryptBackup(plain []byte, passphrase string, recipients []string) ([]byte, error) {
+	fileKey := make([]byte, 32)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+
+	header := encHeader{}
+	if passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		wrapKey, err := scrypt.Key([]byte(passphrase), salt, 1<<ScryptLogN, 8, 1, 32)
+		if err != nil {
+			return nil, err
+		}
+		body, err := sealKey(wrapKey, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		header.Stanzas = append(header.Stanzas, encStanza{Type: "scrypt", Salt: base64.RawStdEncoding.EncodeToString(salt), LogN: ScryptLogN, Body: base64.RawStdEncoding.EncodeToString(body)})
+	}
+
+	for _, recipient := range recipients {
+		pub, err := parsePublicKey(recipient)
+		if err != nil {
+			return nil, err
+		}
+		eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := eph.ECDH(pub)
+		if err != nil {
+			return nil, err
+		}
+		epk := eph.PublicKey().Bytes()
+		wrapKey := hkdfSHA256(shared, append(append([]byte{}, epk...), pub.Bytes()...), "zivpn-backup-x25519")
+		body, err := sealKey(wrapKey, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		header.Stanzas = append(header.Stanzas, encStanza{Type: "X25519", EPK: base64.RawStdEncoding.EncodeToString(epk), Body: base64.RawStdEncoding.EncodeToString(body)})
+	}
+
+	if len(header.Stanzas) == 0 {
+		return nil, fmt.Errorf("tidak ada passphrase atau recipient")
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header.Nonce = base64.RawStdEncoding.EncodeToString(nonce)
+
+	headerLine, err := json.Marshal(header)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return users, nil
+		return nil, err
+	}
+
+	gcm, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []byte(EncryptedMagic)
+	out = append(out, headerLine...)
+	out = append(out, '\n')
+	return gcm.Seal(out, nonce, plain, headerLine), nil
+}
+
+// decryptBackup opens an encrypted backup with a passphrase or a secret key.
+func decryptBackup(data []byte, secret string) ([]byte, error) {
+	rest := data[len(EncryptedMagic):]
+	idx := strings.IndexByte(string(rest), '\n')
+	if idx < 0 {
+		return nil, fmt.Errorf("header backup terenkripsi rusak")
+	}
+	headerLine, ciphertext := rest[:idx], rest[idx+1:]
+
+	var header encHeader
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		return nil, fmt.Errorf("header backup terenkripsi rusak")
+	}
+	if secret == "" {
+		return nil, errNeedSecret
+	}
+
+	var identity *ecdh.PrivateKey
+	if strings.HasPrefix(secret, SecretKeyPrefix) {
+		key, err := parseSecretKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		identity = key
+	}
+
+	var fileKey []byte
+	for _, st := range header.Stanzas {
+		body, err := base64.RawStdEncoding.DecodeString(st.Body)
+		if err != nil {
+			continue
+		}
+		switch {
+		case st.Type == "scrypt" && identity == nil:
+			salt, err := base64.RawStdEncoding.DecodeString(st.Salt)
+			if err != nil || st.LogN < 10 || st.LogN > 20 {
+				continue
+			}
+			wrapKey, err := scrypt.Key([]byte(secret), salt, 1<<st.LogN, 8, 1, 32)
+			if err != nil {
+				continue
+			}
+			fileKey, _ = openKey(wrapKey, body)
+		case st.Type == "X25519" && identity != nil:
+			epkBytes, err := base64.RawStdEncoding.DecodeString(st.EPK)
+			if err != nil {
+				continue
+			}
+			epk, err := ecdh.X25519().NewPublicKey(epkBytes)
+			if err != nil {
+				continue
+			}
+			shared, err := identity.ECDH(epk)
+			if err != nil {
+				continue
+			}
+			wrapKey := hkdfSHA256(shared, append(epkBytes, identity.PublicKey().Bytes()...), "zivpn-backup-x25519")
+			fileKey, _ = openKey(wrapKey, body)
+		}
+		if fileKey != nil {
+			break
 		}
+	}
+	if fileKey == nil {
+		return nil, fmt.Errorf("Passphrase atau secret key salah")
+	}
+
+	nonce, err := base64.RawStdEncoding.DecodeString(header.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("header backup terenkripsi rusak")
+	}
+	gcm, err := newGCM(fileKey)
+	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(file, &users)
-	return users, err
+	plain, err := gcm.Open(nil, nonce, ciphertext, headerLine)
+	if err != nil {
+		return nil, fmt.Errorf("Backup terenkripsi rusak atau diubah")
+	}
+	return plain, nil
 }
 
-func saveUsers(users []UserStore) error {
-	data, err := json.MarshalIndent(users, "", "  ")
+// generateBackupKeypair returns a new recipient public key and its secret key.
+func generateBackupKeypair() (string, string, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
 	if err != nil {
-		return err
+		return "", "", err
 	}
-	return ioutil.WriteFile(UserDB, data, 0644)
+	pub := PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	sec := SecretKeyPrefix + base64.RawURLEncoding.EncodeToString(key.Bytes())
+	return pub, sec, nil
 }
 
-func restartService() error {
-	cmd := exec.Command("systemctl", "restart", "zivpn.service")
-	return cmd.Run()
+func parsePublicKey(s string) (*ecdh.PublicKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), PublicKeyPrefix))
+	if err != nil || !strings.HasPrefix(strings.TrimSpace(s), PublicKeyPrefix) {
+		return nil, fmt.Errorf("recipient %q tidak valid", s)
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
+
+func parseSecretKey(s string) (*ecdh.PrivateKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), SecretKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("secret key tidak valid")
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKey wraps a file key. Every wrap key is single-use (fresh salt or
+// ephemeral key), so a zero nonce is safe.
+func sealKey(wrapKey, fileKey []byte) ([]byte, error) {
+	gcm, err := newGCM(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nil, make([]byte, gcm.NonceSize()), fileKey, nil), nil
+}
+
+func openKey(wrapKey, body []byte) ([]byte, error) {
+	gcm, err := newGCM(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, make([]byte, gcm.NonceSize()), body, nil)
+}
+
+// hkdfSHA256 derives a 32-byte key with HKDF-SHA256.
+func hkdfSHA256(secret, salt []byte, info string) []byte {
+	key := make([]byte, 32)
+	io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key)
+	return key
+}
+
+// backupStatus reports the schedule, the last run and the backups kept in
+// BackupDir.
+func backupStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	settings, err := loadBackupSettings()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membaca pengaturan backup", nil)
+		return
+	}
+
+	status := map[string]interface{}{
+		"schedule":    settings.Schedule,
+		"keep_daily":  settings.KeepDaily,
+		"keep_weekly": settings.KeepWeekly,
+		"telegram":    settings.Telegram,
+		"encrypted":   settings.Passphrase != "" || len(settings.Recipients) > 0,
+		"next_run":    nil,
+		"last_run":    nil,
+		"daily":       listBackupFiles(filepath.Join(BackupDir, "daily")),
+		"weekly":      listBackupFiles(filepath.Join(BackupDir, "weekly")),
+	}
+	if settings.Schedule != "" {
+		if sched, err := parseCron(settings.Schedule); err == nil {
+			if next, ok := sched.next(time.Now()); ok {
+				status["next_run"] = next.Format(time.RFC3339)
+			}
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(BackupDir, "status.json")); err == nil {
+		var run BackupRun
+		if json.Unmarshal(data, &run) == nil {
+			status["last_run"] = run
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, true, "Status backup", status)
+}
+
+// runBackupNow runs a scheduled-style backup immediately.
+func runBackupNow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, http.StatusMethodNotAllowed, false, "Method not allowed", nil)
+		return
+	}
+
+	run := runScheduledBackup("manual")
+	if !run.Success {
+		jsonResponse(w, http.StatusInternalServerError, false, "Gagal membuat backup: "+run.Error, run)
+		return
+	}
+	jsonResponse(w, http.StatusOK, true, "Backup dibuat", run)
+}
+
+// runBackupScheduler wakes at the start of every minute and runs a backup
+// when the configured schedule matches. The settings are re-read each time,
+// so schedule changes apply without a restart.
+func runBackupScheduler() {
+	for {
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+		settings, err := loadBackupSettings()
+		if err != nil || settings.Schedule == "" {
+			continue
+		}
+		sched, err := parseCron(settings.Schedule)
+		if err != nil {
+			log.Printf("Jadwal backup tidak valid: %v", err)
+			continue
+		}
+		if sched.matches(time.Now()) {
+			runScheduledBackup("schedule")
+		}
+	}
+}
+
+// runScheduledBackup writes a full backup to BackupDir/daily, copies the
+// first one of each ISO week to BackupDir/weekly, prunes both to their keep
+// counts and publishes backup.created so the bots can forward the file.
+func runScheduledBackup(trigger string) BackupRun {
+	run := BackupRun{Time: time.Now().Format(time.RFC3339), Trigger: trigger}
+
+	settings, err := loadBackupSettings()
+	if err == nil {
+		run, err = writeScheduledBackup(run, settings)
+	}
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("Backup terjadwal gagal: %v", err)
+	}
+	run.Success = err == nil
+
+	if data, err := json.MarshalIndent(run, "", "  "); err == nil {
+		ioutil.WriteFile(filepath.Join(BackupDir, "status.json"), data, 0600)
+	}
+
+	publishEvent("backup.created", map[string]interface{}{
+		"time":      run.Time,
+		"trigger":   run.Trigger,
+		"success":   run.Success,
+		"file":      run.File,
+		"size":      run.Size,
+		"encrypted": run.Encrypted,
+		"weekly":    run.Weekly,
+		"error":     run.Error,
+		"telegram":  settings.Telegram,
+	})
+	return run
+}
+
+func writeScheduledBackup(run BackupRun, settings BackupSettings) (BackupRun, error) {
+	dailyDir := filepath.Join(BackupDir, "daily")
+	weeklyDir := filepath.Join(BackupDir, "weekly")
+	for _, dir := range []string{dailyDir, weeklyDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return run, err
+		}
+	}
+
+	mutex.Lock()
+	data, err := buildBackup(backupEntries())
+	mutex.Unlock()
+	if err != nil {
+		return run, err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("zivpn-backup-%s.zip", now.Format("20060102-150405"))
+	if settings.Passphrase != "" || len(settings.Recipients) > 0 {
+		data, err = encryptBackup(data, settings.Passphrase, settings.Recipients)
+		if err != nil {
+			return run, err
+		}
+		name += ".enc"
+		run.Encrypted = true
+	}
+
+	path := filepath.Join(dailyDir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return run, err
+	}
+	run.File = path
+	run.Size = len(data)
+
+	// One weekly copy per ISO week; file names sort by creation time
+	year, week := now.ISOWeek()
+	weekly := listBackupFiles(weeklyDir)
+	needWeekly := true
+	if len(weekly) > 0 {
+		if info, err := os.Stat(filepath.Join(weeklyDir, weekly[len(weekly)-1])); err == nil {
+			y, w := info.ModTime().ISOWeek()
+			needWeekly = y != year || w != week
+		}
+	}
+	if needWeekly && settings.KeepWeekly > 0 {
+		if err := ioutil.WriteFile(filepath.Join(weeklyDir, name), data, 0600); err != nil {
+			return run, err
+		}
+		run.Weekly = true
+	}
+
+	pruneBackupFiles(dailyDir, settings.KeepDaily)
+	pruneBackupFiles(weeklyDir, settings.KeepWeekly)
+	return run, nil
+}
+
+// listBackupFiles returns the backup archives in dir, oldest first.
+func listBackupFiles(dir string) []string {
+	files := []string{}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "zivpn-backup-") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func pruneBackupFiles(dir string, keep int) {
+	files := listBackupFiles(dir)
+	for len(files) > keep {
+		if err := os.Remove(filepath.Join(dir, files[0])); err != nil {
+			log.Printf("Gagal menghapus backup lama %s: %v", files[0], err)
+		}
+		files = files[1:]
+	}
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week" with *,
+// lists, ranges and steps, plus the @hourly, @daily and @weekly shorthands.
+func parseCron(expr string) (*cronSchedule, error) {
+	switch expr {
+	case "@hourly":
+		expr = "0 * * * *"
+	case "@daily":
+		expr = "0 0 * * *"
+	case "@weekly":
+		expr = "0 0 * * 0"
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Jadwal harus 5 kolom cron, contoh \"0 3 * * *\"")
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("Kolom cron %q tidak valid", field)
+		}
+		masks[i] = mask
+	}
+
+	// Sunday may be written as 0 or 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step")
+			}
+			step, stepped = n, true
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			if i := strings.Index(part, "-"); i >= 0 {
+				a, errA := strconv.Atoi(part[:i])
+				b, errB := strconv.Atoi(part[i+1:])
+				if errA != nil || errB != nil {
+					return 0, fmt.Errorf("bad range")
+				}
+				lo, hi = a, b
+			} else {
+				n, err := strconv.Atoi(part)
+				if err != nil {
+					return 0, fmt.Errorf("bad value")
+				}
+				lo, hi = n, n
+				// "N/step" runs from N to the end of the field
+				if stepped {
+					hi = max
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("out of range")
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// matches follows cron semantics: when both day-of-month and day-of-week are
+// restricted, either one matching is enough.
+func (c *cronSchedule) matches(t time.Time) bool {
+	return c.minute&(1<<uint(t.Minute())) != 0 && c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.month&(1<<uint(t.Month())) != 0 && c.dayMatches(t)
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if !c.domAny && !c.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// CronSearchYears bounds next; it covers schedules that only fire on
+// 29 February.
+const CronSearchYears = 5
+
+// next returns the first minute after t that matches. ok is false when the
+// schedule never fires within CronSearchYears, e.g. "0 0 31 2 *".
+func (c *cronSchedule) next(t time.Time) (next time.Time, ok bool) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(CronSearchYears, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
 }
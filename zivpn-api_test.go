@@ -0,0 +1,743 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetEvents gives each test an empty hub with a known epoch.
+func resetEvents(t *testing.T) {
+	old := events
+	events = &eventHub{epoch: "boot", subs: make(map[chan Event]bool)}
+	t.Cleanup(func() { events = old })
+}
+
+func TestParseLastEventID(t *testing.T) {
+	resetEvents(t)
+
+	tests := []struct {
+		header string
+		want   int64
+	}{
+		{"", math.MaxInt64}, // new client: live events only
+		{"boot-42", 42},     // same boot: resume after 42
+		{"boot-0", 0},       // same boot, nothing seen yet
+		{"older-42", 0},     // earlier boot: replay the whole buffer
+		{"42", 0},           // pre-epoch id format
+		{"boot-garbage", 0}, // unparsable sequence
+		{"boot-7-extra", 0}, // only one dash is expected
+	}
+	for _, tt := range tests {
+		if got := parseLastEventID(tt.header); got != tt.want {
+			t.Errorf("parseLastEventID(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestEventRingKeepsNewest(t *testing.T) {
+	resetEvents(t)
+
+	total := EventBufferSize + 10
+	for i := 0; i < total; i++ {
+		publishEvent("test", i)
+	}
+	if len(events.ring) != EventBufferSize {
+		t.Fatalf("ring holds %d events, want %d", len(events.ring), EventBufferSize)
+	}
+	if first := events.ring[0].ID; first != int64(total-EventBufferSize+1) {
+		t.Errorf("oldest kept event is %d, want %d", first, total-EventBufferSize+1)
+	}
+	if last := events.ring[len(events.ring)-1].ID; last != int64(total) {
+		t.Errorf("newest event is %d, want %d", last, total)
+	}
+}
+
+func TestSubscribeEventsReplay(t *testing.T) {
+	resetEvents(t)
+
+	for i := 0; i < 5; i++ {
+		publishEvent("test", i)
+	}
+
+	tests := []struct {
+		header string
+		want   []int64
+	}{
+		{"", nil},
+		{"boot-3", []int64{4, 5}},
+		{"boot-5", nil},
+		{"older-3", []int64{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		ch, backlog := subscribeEvents(parseLastEventID(tt.header))
+		unsubscribeEvents(ch)
+
+		var got []int64
+		for _, ev := range backlog {
+			got = append(got, ev.ID)
+		}
+		if !equalIDs(got, tt.want) {
+			t.Errorf("Last-Event-ID %q replays %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPublishEventFansOut(t *testing.T) {
+	resetEvents(t)
+
+	ch, _ := subscribeEvents(parseLastEventID(""))
+	defer unsubscribeEvents(ch)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			publishEvent("test", strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for len(seen) < 10 {
+		ev := <-ch
+		if seen[ev.ID] {
+			t.Fatalf("event %d delivered twice", ev.ID)
+		}
+		seen[ev.ID] = true
+	}
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiffState(t *testing.T) {
+	base := Config{Listen: ":5667", Cert: "/etc/zivpn/zivpn.crt", Key: "/etc/zivpn/zivpn.key", Obfs: "zivpn"}
+	base.Auth.Mode = "passwords"
+	base.Auth.Config = []string{"alice", "bob"}
+
+	alice := UserStore{Password: "alice", Expired: "2030-01-01", Status: "active"}
+	bob := UserStore{Password: "bob", Expired: "2030-01-01", Status: "active"}
+	carol := UserStore{Password: "carol", Expired: "2030-02-01", Status: "active"}
+	bobLocked := bob
+	bobLocked.Status = "locked"
+
+	obfs := base
+	obfs.Obfs = "other"
+	auth := base
+	auth.Auth.Config = []string{"alice", "carol"}
+
+	tests := []struct {
+		name          string
+		toConfig      Config
+		toUsers       []UserStore
+		added         []string
+		removed       []string
+		changed       []string
+		configChanged []string
+	}{
+		{"identical", base, []UserStore{alice, bob}, nil, nil, nil, nil},
+		{"user added", base, []UserStore{alice, bob, carol}, []string{"carol"}, nil, nil, nil},
+		{"user removed", base, []UserStore{alice}, nil, []string{"bob"}, nil, nil},
+		{"user changed", base, []UserStore{alice, bobLocked}, nil, nil, []string{"bob"}, nil},
+		{"order ignored", base, []UserStore{bob, alice}, nil, nil, nil, nil},
+		{"obfs changed", obfs, []UserStore{alice, bob}, nil, nil, nil, []string{"obfs"}},
+		{"replace user", auth, []UserStore{alice, carol}, []string{"carol"}, []string{"bob"}, nil, []string{"auth"}},
+	}
+	for _, tt := range tests {
+		diff := diffState(base, []UserStore{alice, bob}, tt.toConfig, tt.toUsers)
+
+		var added, removed, changed []string
+		for _, u := range diff.Added {
+			added = append(added, u.Password)
+		}
+		for _, u := range diff.Removed {
+			removed = append(removed, u.Password)
+		}
+		for _, c := range diff.Changed {
+			changed = append(changed, c.Password)
+			if c.Before == c.After {
+				t.Errorf("%s: change of %s has identical before and after", tt.name, c.Password)
+			}
+		}
+		if !equalStrings(added, tt.added) || !equalStrings(removed, tt.removed) ||
+			!equalStrings(changed, tt.changed) || !equalStrings(diff.ConfigChanged, tt.configChanged) {
+			t.Errorf("%s: got added=%v removed=%v changed=%v config=%v, want %v %v %v %v", tt.name,
+				added, removed, changed, diff.ConfigChanged, tt.added, tt.removed, tt.changed, tt.configChanged)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const testConfigJSON = `{"listen":":5667","cert":"/etc/zivpn/zivpn.crt","key":"/etc/zivpn/zivpn.key","obfs":"zivpn","auth":{"mode":"passwords","config":["alice"]}}`
+
+// testEntries writes files into a temporary directory and returns backup
+// entries pointing at them.
+func testEntries(t *testing.T, files map[string]string) []BackupEntry {
+	dir := t.TempDir()
+	var entries []BackupEntry
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, BackupEntry{name, path, 0600, "test"})
+	}
+	return entries
+}
+
+// writeZip builds an archive from name/content pairs, in order.
+func writeZip(t *testing.T, files ...[2]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readZip returns every file in an archive by name.
+func readZip(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		content, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestBackupManifestRoundTrip(t *testing.T) {
+	entries := testEntries(t, map[string]string{
+		"config.json": testConfigJSON,
+		"domain":      "vpn.example.com\n",
+	})
+	data, err := buildBackup(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readZip(t, data)
+	var manifest BackupManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != BackupVersion || len(manifest.Files) != 2 {
+		t.Fatalf("manifest = %+v, want version %d with 2 files", manifest, BackupVersion)
+	}
+
+	report, err := restoreArchive(data, entries, true)
+	if err != nil {
+		t.Fatalf("restoring an untouched backup: %v", err)
+	}
+	if report.Legacy || !equalStrings(report.Files, []string{"config.json", "domain"}) {
+		t.Errorf("report = %+v, want files [config.json domain] with a manifest", report)
+	}
+}
+
+func TestRestoreVerifiesManifest(t *testing.T) {
+	entries := testEntries(t, map[string]string{
+		"config.json": testConfigJSON,
+		"domain":      "vpn.example.com\n",
+	})
+	data, err := buildBackup(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readZip(t, data)
+	manifest := files["manifest.json"]
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{"tampered file", writeZip(t,
+			[2]string{"config.json", files["config.json"]},
+			[2]string{"domain", "evil.example.com\n"},
+			[2]string{"manifest.json", manifest}), "Checksum domain"},
+		{"file missing from archive", writeZip(t,
+			[2]string{"config.json", files["config.json"]},
+			[2]string{"manifest.json", manifest}), "domain ada di manifest"},
+		{"duplicate file", writeZip(t,
+			[2]string{"domain", files["domain"]},
+			[2]string{"domain", files["domain"]},
+			[2]string{"manifest.json", manifest}), "lebih dari sekali"},
+		{"unsupported version", writeZip(t,
+			[2]string{"domain", files["domain"]},
+			[2]string{"manifest.json", strings.Replace(manifest, `"version": 1`, `"version": 99`, 1)}), "tidak didukung"},
+		{"not a zip", []byte("hello"), "bukan format ZIP"},
+	}
+	for _, tt := range tests {
+		_, err := restoreArchive(tt.archive, entries, true)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Archives from before manifests existed still restore
+	legacy := writeZip(t, [2]string{"domain", files["domain"]}, [2]string{"unknown.txt", "x"})
+	report, err := restoreArchive(legacy, entries, true)
+	if err != nil {
+		t.Fatalf("legacy archive: %v", err)
+	}
+	if !report.Legacy || !equalStrings(report.Skipped, []string{"unknown.txt"}) {
+		t.Errorf("legacy report = %+v, want legacy with unknown.txt skipped", report)
+	}
+}
+
+func TestValidateBackupFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"config.json", testConfigJSON, true},
+		{"config.json", `{"listen":"5667"}`, false},
+		{"config.json", strings.Replace(testConfigJSON, `"/etc/zivpn/zivpn.key"`, `"zivpn.key"`, 1), false},
+		{"config.json", strings.Replace(testConfigJSON, `"passwords"`, `"external"`, 1), false},
+		{"users.json", `[{"password":"alice","expired":"2030-01-01","status":"active"}]`, true},
+		{"users.json", `[{"password":"alice","expired":"2030-01-01"},{"password":"alice","expired":"2030-01-01"}]`, false},
+		{"users.json", `[{"password":"alice","expired":"soon"}]`, false},
+		{"users.json", `[{"password":"alice","expired":"2030-01-01","status":"deleted"}]`, false},
+		{"wallets.json", `[{"telegram_id":1,"balance":100}]`, true},
+		{"wallets.json", `[{"telegram_id":1,"balance":-5}]`, false},
+		{"api_port", "8080\n", true},
+		{"api_port", "70000", false},
+		{"domain", " \n", false},
+		{"zivpn.key", "not pem", false},
+		{"bot-quota.json", `{"creates":{"1":["2030-01-01T00:00:00Z"]}}`, true},
+		{"paid-bot-state.json", `{"users":{},"payments":{"1":{"order_id":"x","price":1000}}}`, true},
+		{"paid-bot-state.json", `[]`, false},
+		{"lang/ms.json", `{"name":"Melayu","messages":{"Batal":"Batal"}}`, true},
+		{"lang/ms.json", `{"messages":["Batal"]}`, false},
+	}
+	for _, tt := range tests {
+		err := validateBackupFile(tt.name, []byte(tt.content))
+		if (err == nil) != tt.ok {
+			t.Errorf("validateBackupFile(%s, %s) = %v, want ok=%v", tt.name, tt.content, err, tt.ok)
+		}
+	}
+}
+
+func TestMatchEntryStaysInDirectory(t *testing.T) {
+	allowed := map[string]BackupEntry{
+		"users.json":  {"users.json", UserDB, 0644, "users"},
+		"lang/*.json": {"lang/*.json", filepath.Join(LangDir, "*.json"), 0644, "lang"},
+	}
+
+	tests := []struct {
+		name string
+		path string // "" when the name must be refused
+	}{
+		{"users.json", UserDB},
+		{"lang/ms.json", filepath.Join(LangDir, "ms.json")},
+		{"lang/template-bot.json", filepath.Join(LangDir, "template-bot.json")},
+		{"lang/../apikey.json", ""},
+		{"lang/sub/ms.json", ""},
+		{"lang/.hidden.json", ""},
+		{"lang/*.json", ""},
+		{"lang/ms.txt", ""},
+		{"/etc/cron.d/x", ""},
+	}
+	for _, tt := range tests {
+		entry, ok := matchEntry(allowed, tt.name)
+		if ok != (tt.path != "") || entry.Path != tt.path {
+			t.Errorf("matchEntry(%q) = %q, %v; want %q", tt.name, entry.Path, ok, tt.path)
+		}
+	}
+}
+
+func TestPinCertPathsIgnoresArchive(t *testing.T) {
+	evil := strings.Replace(testConfigJSON, `"/etc/zivpn/zivpn.key"`, `"/etc/cron.d/x"`, 1)
+	var config Config
+	if err := json.Unmarshal(pinCertPaths([]byte(evil)), &config); err != nil {
+		t.Fatal(err)
+	}
+	// The live config.json decides, falling back to the defaults
+	wantCert, wantKey := CertFile, KeyFile
+	for _, entry := range backupEntries() {
+		switch entry.Name {
+		case "zivpn.crt":
+			wantCert = entry.Path
+		case "zivpn.key":
+			wantKey = entry.Path
+		}
+	}
+	if config.Cert != wantCert || config.Key != wantKey {
+		t.Errorf("cert/key = %s, %s; want %s, %s", config.Cert, config.Key, wantCert, wantKey)
+	}
+	if config.Key == "/etc/cron.d/x" {
+		t.Error("key path was taken from the archive")
+	}
+	if config.Obfs != "zivpn" || len(config.Auth.Config) != 1 {
+		t.Errorf("other fields changed: %+v", config)
+	}
+}
+
+func TestEncryptBackupRoundTrip(t *testing.T) {
+	pub, sec, err := generateBackupKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, otherSec, err := generateBackupKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := writeZip(t, [2]string{"domain", "vpn.example.com\n"})
+
+	tests := []struct {
+		name       string
+		passphrase string
+		recipients []string
+		secret     string
+		ok         bool
+	}{
+		{"passphrase", "hunter2", nil, "hunter2", true},
+		{"wrong passphrase", "hunter2", nil, "hunter3", false},
+		{"recipient", "", []string{pub}, sec, true},
+		{"second recipient", "", []string{pub, otherPub}, otherSec, true},
+		{"not a recipient", "", []string{pub}, otherSec, false},
+		{"passphrase or recipient, by key", "hunter2", []string{pub}, sec, true},
+		{"passphrase or recipient, by passphrase", "hunter2", []string{pub}, "hunter2", true},
+		{"secret key is not a passphrase", "hunter2", nil, sec, false},
+	}
+	for _, tt := range tests {
+		sealed, err := encryptBackup(plain, tt.passphrase, tt.recipients)
+		if err != nil {
+			t.Fatalf("%s: encrypt: %v", tt.name, err)
+		}
+		if !isEncryptedBackup(sealed) || !bytes.HasPrefix(sealed, []byte(EncryptedMagic)) {
+			t.Fatalf("%s: output does not start with %q", tt.name, EncryptedMagic)
+		}
+		if bytes.Contains(sealed, []byte("vpn.example.com")) {
+			t.Fatalf("%s: plaintext visible in output", tt.name)
+		}
+
+		opened, err := decryptBackup(sealed, tt.secret)
+		if tt.ok && (err != nil || !bytes.Equal(opened, plain)) {
+			t.Errorf("%s: decrypt = %v, want the original archive", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: decrypt succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestDecryptBackupRejectsTampering(t *testing.T) {
+	plain := writeZip(t, [2]string{"domain", "vpn.example.com\n"})
+	sealed, err := encryptBackup(plain, "hunter2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerEnd := len(EncryptedMagic) + bytes.IndexByte(sealed[len(EncryptedMagic):], '\n')
+
+	flip := func(i int) []byte {
+		out := append([]byte{}, sealed...)
+		out[i] ^= 1
+		return out
+	}
+	// A rewritten header stays valid JSON but no longer matches the seal
+	var header encHeader
+	json.Unmarshal(sealed[len(EncryptedMagic):headerEnd], &header)
+	header.Nonce = strings.Repeat("A", len(header.Nonce))
+	newHeader, _ := json.Marshal(header)
+	reheadered := append([]byte(EncryptedMagic), newHeader...)
+	reheadered = append(reheadered, sealed[headerEnd:]...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"ciphertext bit flipped", flip(len(sealed) - 1)},
+		{"header replaced", reheadered},
+		{"truncated", sealed[:len(sealed)-4]},
+		{"no header newline", []byte(EncryptedMagic + "{}")},
+	}
+	for _, tt := range tests {
+		if _, err := decryptBackup(tt.data, "hunter2"); err == nil {
+			t.Errorf("%s: decrypt succeeded, want an error", tt.name)
+		}
+	}
+
+	if _, err := decryptBackup(sealed, ""); err != errNeedSecret {
+		t.Errorf("decrypt without a secret = %v, want errNeedSecret", err)
+	}
+	if _, err := encryptBackup(plain, "", nil); err == nil {
+		t.Error("encrypting without passphrase or recipient succeeded")
+	}
+}
+
+func TestParseBackupKeys(t *testing.T) {
+	pub, sec, err := generateBackupKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(pub, PublicKeyPrefix) || !strings.HasPrefix(sec, SecretKeyPrefix) {
+		t.Fatalf("keys %q / %q lack their prefixes", pub, sec)
+	}
+	if _, err := parsePublicKey(" " + pub + "\n"); err != nil {
+		t.Errorf("public key with whitespace: %v", err)
+	}
+	if _, err := parsePublicKey(strings.TrimPrefix(pub, PublicKeyPrefix)); err == nil {
+		t.Error("public key without prefix accepted")
+	}
+	if _, err := parsePublicKey(sec); err == nil {
+		t.Error("secret key accepted as a recipient")
+	}
+	key, err := parseSecretKey(sec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := parsePublicKey(pub)
+	if !key.PublicKey().Equal(parsed) {
+		t.Error("secret key does not match its public key")
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+		ok       bool
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}, true},
+		{"3", 0, 59, []int{3}, true},
+		{"1,3,5", 0, 59, []int{1, 3, 5}, true},
+		{"10-13", 0, 59, []int{10, 11, 12, 13}, true},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}, true},
+		{"5/15", 0, 59, []int{5, 20, 35, 50}, true},
+		{"20/1", 0, 23, []int{20, 21, 22, 23}, true},
+		{"10-20/5", 0, 59, []int{10, 15, 20}, true},
+		{"0-5/2,30", 0, 59, []int{0, 2, 4, 30}, true},
+		{"1/10", 1, 31, []int{1, 11, 21, 31}, true},
+		{"60", 0, 59, nil, false},
+		{"0", 1, 31, nil, false},
+		{"5-1", 0, 59, nil, false},
+		{"*/0", 0, 59, nil, false},
+		{"*/x", 0, 59, nil, false},
+		{"a", 0, 59, nil, false},
+		{"", 0, 59, nil, false},
+	}
+	for _, tt := range tests {
+		mask, err := parseCronField(tt.field, tt.min, tt.max)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseCronField(%q) error = %v, want ok=%v", tt.field, err, tt.ok)
+			continue
+		}
+		var want uint64
+		for _, v := range tt.want {
+			want |= 1 << uint(v)
+		}
+		if tt.ok && mask != want {
+			t.Errorf("parseCronField(%q) = %b, want %b", tt.field, mask, want)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"0 3 * * *", true},
+		{"@daily", true},
+		{"@hourly", true},
+		{"@weekly", true},
+		{"*/5 * * * 1-5", true},
+		{"0 0 * * 7", true},
+		{"0 3 * *", false},
+		{"0 3 * * * *", false},
+		{"0 24 * * *", false},
+		{"0 0 32 * *", false},
+		{"0 0 * 13 *", false},
+		{"0 0 * * 8", false},
+		{"@yearly", false},
+	}
+	for _, tt := range tests {
+		if _, err := parseCron(tt.expr); (err == nil) != tt.ok {
+			t.Errorf("parseCron(%q) error = %v, want ok=%v", tt.expr, err, tt.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-10-18 is a Sunday
+	from := at("2026-10-18 10:07")
+
+	tests := []struct {
+		expr string
+		want string // "" when there is no upcoming run
+	}{
+		{"0 3 * * *", "2026-10-19 03:00"},
+		{"* * * * *", "2026-10-18 10:08"},
+		{"7 10 * * *", "2026-10-19 10:07"}, // strictly after from
+		{"*/15 * * * *", "2026-10-18 10:15"},
+		{"50/5 * * * *", "2026-10-18 10:50"},
+		{"0 */6 * * *", "2026-10-18 12:00"},
+		{"@hourly", "2026-10-18 11:00"},
+		{"@weekly", "2026-10-25 00:00"},
+		{"0 0 * * 7", "2026-10-25 00:00"},
+		{"30 8 * * 1-5", "2026-10-19 08:30"},
+		{"0 0 1 * *", "2026-11-01 00:00"},
+		{"0 0 1 1 *", "2027-01-01 00:00"},
+		{"0 0 13 * 5", "2026-10-23 00:00"}, // day-of-month or day-of-week
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+		{"0 0 31 2 *", ""},
+		{"0 0 31 4,6,9,11 *", ""},
+	}
+	for _, tt := range tests {
+		sched, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		next, ok := sched.next(from)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("next(%q) = %s, want no upcoming run", tt.expr, next.Format("2006-01-02 15:04"))
+			}
+			continue
+		}
+		if !ok || !next.Equal(at(tt.want)) {
+			t.Errorf("next(%q) = %s, %v; want %s", tt.expr, next.Format("2006-01-02 15:04"), ok, tt.want)
+		}
+		if ok && !sched.matches(next) {
+			t.Errorf("next(%q) = %s, which matches() rejects", tt.expr, next)
+		}
+	}
+}
+
+// storedZip builds an archive without compression, so sizes are exact.
+func storedZip(t *testing.T, files ...[2]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f[0], Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRestoreLimits(t *testing.T) {
+	names := []string{"a.json", "b.json", "c.json", "d.json", "e.json", "f.json"}
+	files := make(map[string]string)
+	for _, name := range names {
+		files[name] = "{}"
+	}
+	entries := testEntries(t, files)
+
+	var many [][2]string
+	for i := 0; i <= MaxBackupFiles; i++ {
+		many = append(many, [2]string{fmt.Sprintf("junk-%d", i), "x"})
+	}
+
+	// Incompressible content just under the per-file limit
+	noise := make([]byte, MaxBackupFileSize-1)
+	rand.New(rand.NewSource(1)).Read(noise)
+	var large [][2]string
+	for _, name := range names {
+		large = append(large, [2]string{name, string(noise)})
+	}
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{"too many files", writeZip(t, many...), "terlalu banyak file"},
+		{"file too large", storedZip(t, [2]string{"a.json", strings.Repeat(" ", MaxBackupFileSize+1)}), "a.json terlalu besar"},
+		{"zip bomb", writeZip(t, [2]string{"a.json", strings.Repeat("0", 1<<20)}), "Rasio kompresi a.json"},
+		{"total too large", storedZip(t, large...), "Isi backup terlalu besar"},
+		{"nothing restorable", writeZip(t, [2]string{"other.txt", "x"}), "tidak berisi file"},
+	}
+	for _, tt := range tests {
+		_, err := restoreArchive(tt.archive, entries, true)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPruneBackupFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"zivpn-backup-20260101-000000.zip",
+		"zivpn-backup-20260103-000000.zip",
+		"zivpn-backup-20260102-000000.zip.enc",
+		"zivpn-backup-20260104-000000.zip",
+		"notes.txt",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneBackupFiles(dir, 2)
+
+	got := listBackupFiles(dir)
+	want := []string{"zivpn-backup-20260103-000000.zip", "zivpn-backup-20260104-000000.zip"}
+	if !equalStrings(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("pruning removed a file it does not own: %v", err)
+	}
+}
@@ -1,23 +1,36 @@
 package main
 
 import (
-	"archive/zip"
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 // ==========================================
@@ -30,8 +43,28 @@ const (
 	ApiKeyFile    = "/etc/zivpn/apikey"
 	DomainFile    = "/etc/zivpn/domain"
 	PortFile	  = "/etc/zivpn/port"
+	StateFile     = "/etc/zivpn/bot-state.json"
+	QuotaFile     = "/etc/zivpn/bot-quota.json"
+	VerifiedFile  = "/etc/zivpn/bot-verified.json"
+	UsersFile     = "/etc/zivpn/bot-users.json"
+	LangDir       = "/etc/zivpn/lang"
+	LangTemplate  = "/etc/zivpn/lang/template-bot.json"
 )
 
+// ZiVPN certificate reused for the webhook listener
+const (
+	CertFile             = "/etc/zivpn/zivpn.crt"
+	KeyFile              = "/etc/zivpn/zivpn.key"
+	DefaultWebhookListen = ":8443"
+	WebhookCheckRounds   = 5 // getWebhookInfo polls, 2s apart, after setWebhook
+)
+
+// EncryptedMagic prefixes backups encrypted by the API.
+const EncryptedMagic = "ZIVPN-ENC-1\n"
+
+// MaxRestoreSize matches the API's upload limit for /restore.
+const MaxRestoreSize = 20 << 20
+
 var ApiUrl = "http://127.0.0.1:" + PortFile + "/api"
 
 var ApiKey = "AutoFtBot-agskjgdvsbdreiWG1234512SDKrqw"
@@ -41,8 +74,41 @@ type BotConfig struct {
 	AdminID  int64  `json:"admin_id"`
 	Mode     string `json:"mode"`   // "public" or "private"
 	Domain   string `json:"domain"` // Domain from setup
+	// Keep conversation state in memory only instead of StateFile
+	MemoryState bool `json:"memory_state,omitempty"`
+	// Number of chats handled concurrently (default DefaultWorkers)
+	Workers int `json:"workers,omitempty"`
+	// Receive updates via HTTPS webhook instead of long polling
+	WebhookURL    string `json:"webhook_url,omitempty"`
+	WebhookListen string `json:"webhook_listen,omitempty"` // default DefaultWebhookListen
+	WebhookSecret string `json:"webhook_secret,omitempty"` // random per start when empty
+	// Limits for non-admin users in public mode
+	Limits *UserLimits `json:"limits,omitempty"`
+	// Checks a new public user must pass before creating accounts
+	Verification VerifySettings `json:"verification"`
+	// Additional staff; AdminID is the owner with every permission
+	Staff []StaffMember       `json:"staff,omitempty"`
+	Roles map[string][]string `json:"roles,omitempty"` // extra or overridden roles
+}
+
+// UserLimits caps what a public user may create. Zero disables a limit.
+type UserLimits struct {
+	MaxAccounts     int `json:"max_accounts"`     // active accounts per user
+	MaxDays         int `json:"max_days"`         // days an account may run ahead
+	DailyCreates    int `json:"daily_creates"`    // creations per 24 hours
+	CooldownMinutes int `json:"cooldown_minutes"` // wait between two creations
+}
+
+// VerifySettings enables join verification. All checks are off by default.
+type VerifySettings struct {
+	Captcha   string `json:"captcha"`     // "", "math" or "button"
+	Channel   string `json:"channel"`     // @username or numeric ID users must join
+	MaxUserID int64  `json:"max_user_id"` // Telegram IDs grow over time; higher IDs are rejected as too new
 }
 
+// DefaultLimits applies when bot-config.json has no "limits" block.
+var DefaultLimits = UserLimits{MaxAccounts: 3, MaxDays: 30, DailyCreates: 3, CooldownMinutes: 10}
+
 type IpInfo struct {
 	City  string `json:"city"`
 	Isp   string `json:"isp"`
@@ -54,15 +120,13 @@ type UserData struct {
 	Expired  string `json:"expired"`
 	Status   string `json:"status"`
 	IpLimit  int    `json:"ip_limit"`
+	Owner    int64  `json:"owner"` // creator's Telegram ID, 0 = admin-owned
 }
 
 // ==========================================
 // Global State
 // ==========================================
 
-var userStates = make(map[int64]string)
-var tempUserData = make(map[int64]map[string]string)
-var lastMessageIDs = make(map[int64]int)
 
 // ==========================================
 // Main Entry Point
@@ -86,6 +150,18 @@ func main() {
 		log.Fatal("Gagal memuat konfigurasi bot:", err)
 	}
 
+	// Restore in-progress flows unless state is kept in memory only
+	if !config.MemoryState {
+		states = loadStateStore(StateFile)
+	}
+	go runStateJanitor()
+	quotas = loadQuotaStore(QuotaFile)
+	verified = loadVerifyStore(VerifiedFile)
+	botUsers = loadUserRegistry(UsersFile)
+	go botUsers.flushLoop()
+	loadCatalogs(LangDir)
+	writeCatalogTemplate(LangTemplate)
+
 	// Initialize Bot
 	bot, err := tgbotapi.NewBotAPI(config.BotToken)
 	if err != nil {
@@ -95,17 +171,258 @@ func main() {
 	bot.Debug = false
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	updates := startUpdates(bot, &config)
+	go registerCommands(bot, &config)
+
+	// Notify admin about API events (expiry runs, failed restarts)
+	go watchApiEvents(bot, &config)
 
 	// Main Loop
+	dispatcher := newDispatcher(config.Workers, func(update tgbotapi.Update) {
+		handleUpdate(bot, update, &config)
+	})
 	for update := range updates {
-		if update.Message != nil {
-			handleMessage(bot, update.Message, &config)
-		} else if update.CallbackQuery != nil {
-			handleCallback(bot, update.CallbackQuery, &config)
+		dispatcher.Dispatch(update)
+	}
+}
+
+// ==========================================
+// Update Delivery (polling / webhook)
+// ==========================================
+
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// startUpdates uses the webhook when configured and falls back to long polling.
+func startUpdates(bot *tgbotapi.BotAPI, config *BotConfig) tgbotapi.UpdatesChannel {
+	if config.WebhookURL != "" {
+		updates, err := startWebhook(bot, config)
+		if err == nil {
+			return updates
+		}
+		log.Printf("Webhook tidak aktif, kembali ke polling: %v", err)
+	}
+
+	// getUpdates is refused while a webhook is still registered
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Gagal menghapus webhook: %v", err)
+	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	return bot.GetUpdatesChan(u)
+}
+
+func startWebhook(bot *tgbotapi.BotAPI, config *BotConfig) (tgbotapi.UpdatesChannel, error) {
+	link, err := url.Parse(config.WebhookURL)
+	if err != nil || link.Scheme != "https" || link.Host == "" {
+		return nil, fmt.Errorf("webhook_url harus berupa URL https")
+	}
+	secret := config.WebhookSecret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	} else if !webhookSecretPattern.MatchString(secret) {
+		return nil, fmt.Errorf("webhook_secret hanya boleh berisi A-Z, a-z, 0-9, _ dan -")
+	}
+
+	cert, err := tls.LoadX509KeyPair(CertFile, KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("sertifikat ZiVPN tidak valid: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("sertifikat ZiVPN tidak valid: %v", err)
+	}
+
+	listen := config.WebhookListen
+	if listen == "" {
+		listen = DefaultWebhookListen
+	}
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan tgbotapi.Update, 100)
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		update, err := bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates <- *update
+	})
+	server := &http.Server{
+		Handler:      mux,
+		TLSConfig:    &tls.Config{Certificates: []tls.Certificate{cert}},
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	params := tgbotapi.Params{
+		"url":          link.String(),
+		"secret_token": secret,
+	}
+	since := time.Now()
+	// Telegram only trusts a self-signed certificate when it is uploaded with setWebhook
+	if leaf.CheckSignatureFrom(leaf) == nil {
+		_, err = bot.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(CertFile),
+		}})
+	} else {
+		_, err = bot.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setWebhook gagal: %v", err)
+	}
+
+	go func() {
+		if err := server.ServeTLS(ln, "", ""); err != nil {
+			log.Printf("Webhook server berhenti: %v", err)
+		}
+	}()
+	if err := checkWebhook(bot, since); err != nil {
+		server.Close()
+		return nil, err
+	}
+	log.Printf("Webhook aktif di %s (listen %s)", link.String(), listen)
+	return updates, nil
+}
+
+// checkWebhook catches a webhook Telegram accepted but cannot deliver to,
+// such as a certificate it does not trust. Only errors newer than since
+// count, since getWebhookInfo keeps the last one from earlier runs.
+func checkWebhook(bot *tgbotapi.BotAPI, since time.Time) error {
+	for i := 0; i < WebhookCheckRounds; i++ {
+		time.Sleep(2 * time.Second)
+		info, err := bot.GetWebhookInfo()
+		if err != nil {
+			return fmt.Errorf("getWebhookInfo gagal: %v", err)
+		}
+		if info.LastErrorMessage != "" && int64(info.LastErrorDate) >= since.Unix() {
+			return fmt.Errorf("Telegram gagal mengirim ke webhook: %s", info.LastErrorMessage)
+		}
+		if info.PendingUpdateCount == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ==========================================
+// Update Dispatcher
+// ==========================================
+
+// DefaultWorkers bounds how many chats are served at the same time.
+const DefaultWorkers = 8
+
+// Dispatcher runs updates concurrently across chats while keeping the
+// updates of a single chat in arrival order.
+type Dispatcher struct {
+	mu      sync.Mutex
+	pending map[int64][]tgbotapi.Update // queued updates per busy chat
+	slots   chan struct{}
+	handle  func(tgbotapi.Update)
+}
+
+func newDispatcher(workers int, handle func(tgbotapi.Update)) *Dispatcher {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Dispatcher{
+		pending: make(map[int64][]tgbotapi.Update),
+		slots:   make(chan struct{}, workers),
+		handle:  handle,
+	}
+}
+
+// Dispatch queues the update behind any update still running for the same chat.
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) {
+	chatID := updateChatID(update)
+	d.mu.Lock()
+	if queue, busy := d.pending[chatID]; busy {
+		d.pending[chatID] = append(queue, update)
+		d.mu.Unlock()
+		return
+	}
+	d.pending[chatID] = nil
+	d.mu.Unlock()
+	go d.drain(chatID, update)
+}
+
+func (d *Dispatcher) drain(chatID int64, update tgbotapi.Update) {
+	d.slots <- struct{}{}
+	defer func() { <-d.slots }()
+	for {
+		d.run(update)
+		d.mu.Lock()
+		queue := d.pending[chatID]
+		if len(queue) == 0 {
+			delete(d.pending, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update, d.pending[chatID] = queue[0], queue[1:]
+		d.mu.Unlock()
+	}
+}
+
+// run handles one update, recovering from panics so the bot keeps serving.
+func (d *Dispatcher) run(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic while handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(update)
+}
+
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From.ID
+	case update.InlineQuery != nil:
+		return update.InlineQuery.From.ID
+	}
+	return 0
+}
+
+// handleUpdate routes one update and tells the user when a handler crashed.
+func handleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, config *BotConfig) {
+	defer func() {
+		if r := recover(); r != nil {
+			if chatID := updateChatID(update); chatID != 0 {
+				bot.Send(tgbotapi.NewMessage(chatID, tr(chatID, "❌ Terjadi kesalahan internal. Silakan coba lagi.")))
+			}
+			panic(r)
 		}
+	}()
+	botUsers.Touch(updateSender(update))
+	if update.Message != nil {
+		handleMessage(bot, update.Message, config)
+	} else if update.CallbackQuery != nil {
+		handleCallback(bot, update.CallbackQuery, config)
+	} else if update.InlineQuery != nil {
+		handleInlineQuery(bot, update.InlineQuery, config)
 	}
 }
 
@@ -116,91 +433,260 @@ func main() {
 func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
 	// Access Control
 	if !isAllowed(config, msg.From.ID) {
-		replyError(bot, msg.Chat.ID, "⛔ Akses Ditolak. Bot ini Private.")
+		replyError(bot, msg.Chat.ID, tr(msg.Chat.ID, "⛔ Akses Ditolak. Bot ini Private."))
 		return
 	}
 
 	// Handle Document Upload (Restore)
-	if msg.Document != nil && msg.From.ID == config.AdminID {
-		if state, exists := userStates[msg.From.ID]; exists && state == "waiting_restore_file" {
+	if msg.Document != nil && can(config, msg.From.ID, PermRestore) {
+		if states.State(msg.From.ID) == "waiting_restore_file" {
 			processRestoreFile(bot, msg, config)
 			return
 		}
 	}
 
 	// Handle State (User Input)
-	if state, exists := userStates[msg.From.ID]; exists {
-		handleState(bot, msg, state, config)
-		return
+	if state := states.State(msg.From.ID); state != "" {
+		if msg.IsCommand() && msg.Command() == "start" {
+			offerResume(bot, msg.Chat.ID)
+			return
+		}
+		// Any other command abandons the flow in progress
+		if !msg.IsCommand() {
+			handleState(bot, msg, state, config)
+			return
+		}
+		resetState(msg.From.ID)
 	}
 
 	// Handle Commands
 	if msg.IsCommand() {
-		switch msg.Command() {
-		case "start":
-			showMainMenu(bot, msg.Chat.ID, config)
-		default:
-			replyError(bot, msg.Chat.ID, "Perintah tidak dikenal.")
-		}
+		handleCommand(bot, msg, config)
 	}
 }
 
 func handleCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, config *BotConfig) {
 	// Access Control (Special case for toggle_mode)
 	if !isAllowed(config, query.From.ID) {
-		if query.Data != "toggle_mode" || query.From.ID != config.AdminID {
-			bot.Request(tgbotapi.NewCallback(query.ID, "Akses Ditolak"))
+		if query.Data != "toggle_mode" || !can(config, query.From.ID, PermConfigEdit) {
+			bot.Request(tgbotapi.NewCallback(query.ID, tr(query.From.ID, "Akses Ditolak")))
 			return
 		}
 	}
 
-	chatID := query.Message.Chat.ID
+	// Buttons on inline-mode messages have no Message; answer in private chat
+	chatID := query.From.ID
+	if query.Message != nil {
+		chatID = query.Message.Chat.ID
+	}
 	userID := query.From.ID
 
 	switch {
 	// --- Menu Navigation ---
 	case query.Data == "menu_create":
-		startCreateUser(bot, chatID, userID)
+		startCreateUser(bot, chatID, userID, config)
 	case query.Data == "menu_delete":
-		showUserSelection(bot, chatID, 1, "delete")
+		showUserSelection(bot, chatID, userID, 1, "delete", config)
 	case query.Data == "menu_renew":
-		showUserSelection(bot, chatID, 1, "renew")
+		showUserSelection(bot, chatID, userID, 1, "renew", config)
 	case query.Data == "menu_list":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
 			listUsers(bot, chatID)
 		}
 	case query.Data == "menu_info":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, "") {
 			systemInfo(bot, chatID, config)
 		}
+	case query.Data == "menu_limits":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			showLimits(bot, chatID, config)
+		}
+	case query.Data == "menu_verify":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			showVerification(bot, chatID, config)
+		}
+	case query.Data == "verify_captcha_cycle":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			cycleCaptcha(bot, chatID, config)
+		}
+	case query.Data == "verify_revoke":
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			states.SetState(userID, "admin_verify_revoke")
+			sendMessage(bot, chatID, tr(chatID, "🚫 Masukkan Telegram ID yang verifikasinya akan dicabut:"))
+		}
+	case strings.HasPrefix(query.Data, "limit_set:"):
+		field := strings.TrimPrefix(query.Data, "limit_set:")
+		if _, ok := limitLabels[field]; ok && requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			states.Reset(userID, map[string]string{"field": field})
+			states.SetState(userID, "admin_limit_input")
+			sendMessage(bot, chatID, tr(chatID, "📏 Masukkan batas baru untuk %s (0 = tanpa batas):", tr(chatID, limitLabels[field])))
+		}
 	case query.Data == "menu_backup_restore":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, "") {
 			showBackupRestoreMenu(bot, chatID)
 		}
 	case query.Data == "menu_backup_action":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
 			performBackup(bot, chatID)
 		}
 	case query.Data == "menu_restore_action":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermRestore) {
 			startRestore(bot, chatID, userID)
 		}
+	case query.Data == "restore_confirm":
+		if requirePerm(bot, chatID, config, userID, PermRestore) {
+			confirmRestore(bot, chatID, userID, config)
+		}
+	case query.Data == "restore_cancel":
+		cancelRestore(bot, chatID, userID, config)
+	case query.Data == "menu_backup_encryption":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			showBackupEncryption(bot, chatID)
+		}
+	case query.Data == "backup_pass_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_passphrase")
+			sendMessage(bot, chatID, tr(chatID, "🔑 Masukkan passphrase backup baru (minimal 8 karakter):"))
+		}
+	case query.Data == "backup_pass_clear":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			setBackupPassphrase(bot, chatID, "")
+		}
+	case query.Data == "menu_backup_schedule":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			showBackupSchedule(bot, chatID)
+		}
+	case query.Data == "backup_run_now":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			runBackupNow(bot, chatID)
+		}
+	case query.Data == "backup_tg_toggle":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			toggleBackupTelegram(bot, chatID)
+		}
+	case query.Data == "backup_schedule_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_schedule")
+			sendMessage(bot, chatID, tr(chatID, "🕒 Masukkan jadwal cron (menit jam tanggal bulan hari), contoh \"0 3 * * *\" untuk setiap hari jam 03:00.\nKetik \"off\" untuk mematikan."))
+		}
+	case query.Data == "backup_keep_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_keep")
+			sendMessage(bot, chatID, tr(chatID, "🗂️ Masukkan jumlah backup harian dan mingguan yang disimpan (contoh: 7 4):"))
+		}
+	case query.Data == "menu_service":
+		if requirePerm(bot, chatID, config, userID, "") {
+			showServiceMenu(bot, chatID)
+		}
+	case query.Data == "svc_status":
+		if requirePerm(bot, chatID, config, userID, "") {
+			serviceStatus(bot, chatID)
+		}
+	case query.Data == "svc_logs":
+		if requirePerm(bot, chatID, config, userID, "") {
+			serviceLogs(bot, chatID)
+		}
+	case query.Data == "svc_restart", query.Data == "svc_start", query.Data == "svc_stop_confirm":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			serviceAction(bot, chatID, strings.TrimSuffix(strings.TrimPrefix(query.Data, "svc_"), "_confirm"))
+		}
+	case query.Data == "svc_stop":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			confirmServiceStop(bot, chatID)
+		}
+	case query.Data == "menu_obfs":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			startEditObfs(bot, chatID, userID)
+		}
+	case query.Data == "menu_staff":
+		if isOwner(config, userID) {
+			showStaff(bot, chatID, config)
+		}
+	case query.Data == "staff_add":
+		if isOwner(config, userID) {
+			states.SetState(userID, "owner_staff_input")
+			sendMessage(bot, chatID, tr(chatID, "👑 Masukkan: <TelegramID> <role> [nama]\nContoh: 7251232303 support Budi\nRole: ")+strings.Join(roleNames(config), ", "))
+		}
+	case strings.HasPrefix(query.Data, "staff_remove:"):
+		if isOwner(config, userID) {
+			id, _ := strconv.ParseInt(strings.TrimPrefix(query.Data, "staff_remove:"), 10, 64)
+			if err := removeStaff(config, id); err != nil {
+				replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+				break
+			}
+			go dropCommands(bot, id)
+			showStaff(bot, chatID, config)
+		}
+	case query.Data == "menu_bot_users":
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			showBotUsers(bot, chatID, 1, "")
+		}
+	case strings.HasPrefix(query.Data, "botusers:"):
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			handleBotUsersPage(bot, chatID, query.Data)
+		}
+	case query.Data == "botusers_search":
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			states.SetState(userID, "admin_botuser_search")
+			sendMessage(bot, chatID, tr(chatID, "🔍 Masukkan ID, username atau nama pengguna:"))
+		}
 	case query.Data == "cancel":
 		cancelOperation(bot, chatID, userID, config)
+	case query.Data == "resume_flow":
+		resumeFlow(bot, chatID, userID, config)
+	case query.Data == "menu_lang":
+		showLanguageMenu(bot, chatID, userID)
+	case strings.HasPrefix(query.Data, "lang_set:"):
+		setLanguage(bot, chatID, userID, strings.TrimPrefix(query.Data, "lang_set:"), config)
+	case query.Data == "verify_check":
+		startVerification(bot, chatID, userID, config)
+	case strings.HasPrefix(query.Data, "verify_pick:"):
+		checkButtonCaptcha(bot, chatID, userID, strings.TrimPrefix(query.Data, "verify_pick:"), config)
 
 	// --- Pagination ---
 	case strings.HasPrefix(query.Data, "page_"):
-		handlePagination(bot, chatID, query.Data)
+		handlePagination(bot, chatID, userID, query.Data, config)
 
 	// --- Action Selection ---
 	case strings.HasPrefix(query.Data, "select_renew:"):
-		startRenewUser(bot, chatID, userID, query.Data)
+		if !ownsAccount(userID, strings.TrimPrefix(query.Data, "select_renew:"), config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			break
+		}
+		startRenewUser(bot, chatID, userID, query.Data, config)
+	case query.Data == "menu_search":
+		startSearch(bot, chatID, userID)
+	case strings.HasPrefix(query.Data, "acct:"):
+		showAccountCard(bot, chatID, userID, strings.TrimPrefix(query.Data, "acct:"), config)
+	case strings.HasPrefix(query.Data, "acct_lock:"):
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			password := strings.TrimPrefix(query.Data, "acct_lock:")
+			lockUser(bot, chatID, password)
+			showAccountCard(bot, chatID, userID, password, config)
+		}
+	case strings.HasPrefix(query.Data, "acct_passwd:"):
+		password := strings.TrimPrefix(query.Data, "acct_passwd:")
+		if !ownsAccount(userID, password, config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			break
+		}
+		states.Reset(userID, map[string]string{"username": password})
+		states.SetState(userID, "change_password")
+		sendMessage(bot, chatID, tr(chatID, "🔑 Masukkan password baru untuk %s:", password))
 	case strings.HasPrefix(query.Data, "select_delete:"):
+		if !ownsAccount(userID, strings.TrimPrefix(query.Data, "select_delete:"), config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			break
+		}
 		confirmDeleteUser(bot, chatID, query.Data)
 
 	// --- Action Confirmation ---
 	case strings.HasPrefix(query.Data, "confirm_delete:"):
 		username := strings.TrimPrefix(query.Data, "confirm_delete:")
+		if !ownsAccount(userID, username, config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			break
+		}
 		deleteUser(bot, chatID, username, config)
 
 	// --- Admin Actions ---
@@ -221,102 +707,2359 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		if !validateUsername(bot, chatID, text) {
 			return
 		}
-		tempUserData[userID]["username"] = text
-		userStates[userID] = "create_days"
-		sendMessage(bot, chatID, "⏳ Masukkan Durasi (hari):")
+		states.Set(userID, "username", text)
+		states.SetState(userID, "create_days")
+		sendMessage(bot, chatID, tr(chatID, "⏳ Masukkan Durasi (hari):"))
 
 	case "create_days":
-		_, ok := validateNumber(bot, chatID, text, 1, 9999, "Durasi")
+		maxDays := 9999
+		if limits := currentLimits(config); !unlimited(config, userID) && limits.MaxDays > 0 {
+			maxDays = limits.MaxDays
+		}
+		_, ok := validateNumber(bot, chatID, text, 1, maxDays, "Durasi")
 		if !ok {
 			return
 		}
-		tempUserData[userID]["days"] = text
+		states.Set(userID, "days", text)
 		
 		days, _ := strconv.Atoi(text)
-		createUser(bot, chatID, tempUserData[userID]["username"], days, config)
+		username := states.Get(userID, "username")
 		resetState(userID)
+		// Quota may have been used up by another flow in the meantime
+		if !unlimited(config, userID) {
+			quota, err := userQuota(userID, config)
+			if err != nil {
+				replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
+				return
+			}
+			if reason := quota.blocked(currentLimits(config)); reason != "" {
+				replyError(bot, chatID, reason)
+				return
+			}
+		}
+		createUser(bot, chatID, userID, username, days, config)
 
 	case "renew_days":
-		days, ok := validateNumber(bot, chatID, text, 1, 9999, "Durasi")
+		username := states.Get(userID, "username")
+		maxDays := 9999
+		if limits := currentLimits(config); !unlimited(config, userID) && limits.MaxDays > 0 {
+			maxDays = renewAllowance(username, limits.MaxDays)
+			if maxDays < 1 {
+				resetState(userID)
+				replyError(bot, chatID, tr(chatID, "Akun sudah mencapai batas masa aktif %d hari.", limits.MaxDays))
+				return
+			}
+		}
+		days, ok := validateNumber(bot, chatID, text, 1, maxDays, "Durasi")
+		if !ok {
+			return
+		}
+		resetState(userID)
+		if !ownsAccount(userID, username, config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			return
+		}
+		renewUser(bot, chatID, username, days, config)
+
+	case "search_query":
+		resetState(userID)
+		showSearchResults(bot, chatID, userID, text, config)
+
+	case "change_password":
+		if !validateUsername(bot, chatID, text) {
+			return
+		}
+		username := states.Get(userID, "username")
+		resetState(userID)
+		if !ownsAccount(userID, username, config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			return
+		}
+		changePassword(bot, chatID, userID, username, text, config)
+
+	case "admin_botuser_search":
+		if !can(config, userID, PermUsersManage) {
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		// Callback data is capped at 64 bytes and carries the query
+		for len(text) > 40 {
+			_, size := utf8.DecodeLastRuneInString(text)
+			text = text[:len(text)-size]
+		}
+		showBotUsers(bot, chatID, 1, strings.ReplaceAll(text, ":", ""))
+
+	case "verify_captcha":
+		checkMathCaptcha(bot, chatID, userID, text, config)
+
+	case "admin_verify_revoke":
+		if !can(config, userID, PermUsersManage) {
+			resetState(userID)
+			return
+		}
+		tid, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			sendMessage(bot, chatID, tr(chatID, "❌ Telegram ID harus angka. Coba lagi:"))
+			return
+		}
+		resetState(userID)
+		if verified.Revoke(tid) {
+			sendMessage(bot, chatID, tr(chatID, "✅ Verifikasi user %d dicabut.", tid))
+		} else {
+			sendMessage(bot, chatID, tr(chatID, "User %d belum terverifikasi.", tid))
+		}
+
+	case "admin_limit_input":
+		if !can(config, userID, PermConfigEdit) {
+			resetState(userID)
+			return
+		}
+		val, ok := validateNumber(bot, chatID, text, 0, 9999, "Batas")
 		if !ok {
 			return
 		}
-		renewUser(bot, chatID, tempUserData[userID]["username"], days, config)
+		field := states.Get(userID, "field")
+		resetState(userID)
+		if err := setLimit(config, field, val); err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+			return
+		}
+		showLimits(bot, chatID, config)
+
+	case "owner_staff_input":
+		if !isOwner(config, userID) {
+			resetState(userID)
+			return
+		}
+		parts := strings.Fields(text)
+		if len(parts) < 2 {
+			sendMessage(bot, chatID, tr(chatID, "❌ Format salah. Contoh: 7251232303 support Budi"))
+			return
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id == config.AdminID {
+			sendMessage(bot, chatID, tr(chatID, "❌ Telegram ID tidak valid. Coba lagi:"))
+			return
+		}
+		if _, ok := rolePermissions(config, parts[1]); !ok {
+			sendMessage(bot, chatID, tr(chatID, "❌ Role tidak dikenal. Pilih: ")+strings.Join(roleNames(config), ", "))
+			return
+		}
+		resetState(userID)
+		if err := setStaff(config, StaffMember{ID: id, Role: parts[1], Name: strings.Join(parts[2:], " ")}); err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+			return
+		}
+		go setCommands(bot, tgbotapi.NewBotCommandScopeChat(id), "", commandsFor(config, id, userLanguage(id)))
+		showStaff(bot, chatID, config)
+
+	case "admin_obfs_input":
+		if !can(config, userID, PermConfigEdit) {
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		updateObfs(bot, chatID, text)
+
+	case "admin_backup_passphrase":
+		if !can(config, userID, PermBackup) {
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+		setBackupPassphrase(bot, chatID, text)
+
+	case "waiting_restore_secret":
+		if !can(config, userID, PermRestore) {
+			resetState(userID)
+			return
+		}
+		bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+		previewRestore(bot, chatID, userID, text)
+
+	case "admin_backup_schedule":
+		if !can(config, userID, PermBackup) {
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		if strings.EqualFold(text, "off") {
+			text = ""
+		}
+		updateBackupSettings(bot, chatID, map[string]interface{}{"schedule": text})
+
+	case "admin_backup_keep":
+		if !can(config, userID, PermBackup) {
+			resetState(userID)
+			return
+		}
+		parts := strings.Fields(text)
+		if len(parts) != 2 {
+			sendMessage(bot, chatID, tr(chatID, "❌ Format salah. Contoh: 7 4"))
+			return
+		}
+		daily, err1 := strconv.Atoi(parts[0])
+		weekly, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			sendMessage(bot, chatID, tr(chatID, "❌ Jumlah harus angka. Contoh: 7 4"))
+			return
+		}
 		resetState(userID)
+		updateBackupSettings(bot, chatID, map[string]interface{}{"keep_daily": daily, "keep_weekly": weekly})
 	}
 }
 
 // ==========================================
-// Feature Implementation
+// Conversation State
 // ==========================================
 
-func startCreateUser(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "create_username"
-	tempUserData[userID] = make(map[string]string)
-	sendMessage(bot, chatID, "👤 Masukkan Password:")
+// StatePrompts is repeated when a user resumes an unfinished flow.
+var StatePrompts = map[string]string{
+	"create_username":         "👤 Masukkan Password:",
+	"create_days":             "⏳ Masukkan Durasi (hari):",
+	"renew_days":              "⏳ Masukkan Durasi (hari):",
+	"admin_obfs_input":        "🔐 Masukkan obfs baru (1-64 karakter, tanpa spasi):",
+	"admin_limit_input":       "📏 Masukkan batas baru (0 = tanpa batas):",
+	"admin_verify_revoke":     "🚫 Masukkan Telegram ID yang verifikasinya akan dicabut:",
+	"admin_backup_passphrase": "🔑 Masukkan passphrase backup baru (minimal 8 karakter):",
+	"admin_backup_schedule":   "🕒 Masukkan jadwal cron, contoh \"0 3 * * *\", atau \"off\":",
+	"admin_backup_keep":       "🗂️ Masukkan jumlah backup harian dan mingguan (contoh: 7 4):",
+	"waiting_restore_file":    "⬆️ Silakan kirim file backup (.zip atau .zip.enc).",
+	"waiting_restore_secret":  "🔒 Kirim passphrase atau secret key (ZIVPNSEC1...):",
+	"owner_staff_input":       "👑 Masukkan: <TelegramID> <role> [nama]",
+	"search_query":            "🔍 Masukkan sebagian password yang dicari:",
+	"change_password":         "🔑 Masukkan password baru:",
+	"admin_botuser_search":    "🔍 Masukkan ID, username atau nama pengguna:",
 }
 
-func startRenewUser(bot *tgbotapi.BotAPI, chatID int64, userID int64, data string) {
-	username := strings.TrimPrefix(data, "select_renew:")
-	tempUserData[userID] = map[string]string{"username": username}
-	userStates[userID] = "renew_days"
-	sendMessage(bot, chatID, fmt.Sprintf("🔄 Renewing %s\n⏳ Masukkan Tambahan Durasi (hari):", username))
-}
+// DefaultStateTTL is how long an unfinished flow is kept before it expires.
+const DefaultStateTTL = 30 * time.Minute
 
-func confirmDeleteUser(bot *tgbotapi.BotAPI, chatID int64, data string) {
-	username := strings.TrimPrefix(data, "select_delete:")
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❓ Yakin ingin menghapus user `%s`?", username))
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Ya, Hapus", "confirm_delete:"+username),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "cancel"),
-		),
-	)
-	sendAndTrack(bot, msg)
+// StateTTLs overrides DefaultStateTTL for individual states.
+var StateTTLs = map[string]time.Duration{
+	"waiting_restore_file":   10 * time.Minute,
+	"waiting_restore_secret": 10 * time.Minute,
 }
 
-func cancelOperation(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
-	resetState(userID)
-	showMainMenu(bot, chatID, config)
+// UserState is a user's position in a multi-step flow and the values
+// collected so far.
+type UserState struct {
+	State     string            `json:"state"`
+	Data      map[string]string `json:"data"`
+	UpdatedAt time.Time         `json:"updated_at"`
 }
 
-func handlePagination(bot *tgbotapi.BotAPI, chatID int64, data string) {
-	parts := strings.Split(data, ":")
-	action := parts[0][5:] // remove "page_"
-	page, _ := strconv.Atoi(parts[1])
-	showUserSelection(bot, chatID, page, action)
+// StateStore holds all conversation state behind one lock. With a path it
+// is mirrored to disk after every change, so flows survive a restart.
+// Last-message IDs change on every reply, so they only mark the store dirty
+// and are written by the janitor.
+type StateStore struct {
+	mu       sync.Mutex
+	path     string
+	Users    map[int64]*UserState `json:"users"`
+	Messages map[int64]int        `json:"messages"` // last tracked bot message per chat
+	secrets  map[int64]string     // never written to disk
+	dirty    bool
 }
 
-func toggleMode(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
-	if userID != config.AdminID {
-		return
-	}
-	if config.Mode == "public" {
-		config.Mode = "private"
-	} else {
-		config.Mode = "public"
+var states = newStateStore("")
+
+func newStateStore(path string) *StateStore {
+	return &StateStore{
+		path:     path,
+		Users:    make(map[int64]*UserState),
+		Messages: make(map[int64]int),
+		secrets:  make(map[int64]string),
 	}
-	saveConfig(config)
-	showMainMenu(bot, chatID, config)
 }
 
-func createUser(bot *tgbotapi.BotAPI, chatID int64, username string, days int, config *BotConfig) {
-	res, err := apiCall("POST", "/user/create", map[string]interface{}{
-		"password": username,
-		"days":     days,
-	})
-
+// loadStateStore restores the store from path, starting empty when the file
+// is missing or unreadable.
+func loadStateStore(path string) *StateStore {
+	s := newStateStore(path)
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
-		return
+		return s
 	}
-
-	if res["success"] == true {
+	if err := json.Unmarshal(data, s); err != nil {
+		log.Printf("State tersimpan tidak valid, mulai kosong: %v", err)
+		return newStateStore(path)
+	}
+	if s.Users == nil {
+		s.Users = make(map[int64]*UserState)
+	}
+	if s.Messages == nil {
+		s.Messages = make(map[int64]int)
+	}
+	s.Expire()
+	return s
+}
+
+// State returns the user's current state, or "" when there is none.
+func (s *StateStore) State(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok && !u.expired(time.Now()) {
+		return u.State
+	}
+	return ""
+}
+
+func (s *StateStore) SetState(userID int64, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(userID).State = state
+	s.save()
+}
+
+// ClearState ends the flow but keeps the collected data.
+func (s *StateStore) ClearState(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok {
+		u.State = ""
+		u.UpdatedAt = time.Now()
+		s.prune(userID)
+		s.save()
+	}
+}
+
+func (s *StateStore) Get(userID int64, key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok && !u.expired(time.Now()) {
+		return u.Data[key]
+	}
+	return ""
+}
+
+func (s *StateStore) Set(userID int64, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(userID).Data[key] = value
+	s.save()
+}
+
+// Reset replaces the user's data, keeping the current state.
+func (s *StateStore) Reset(userID int64, data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(userID)
+	u.Data = make(map[string]string)
+	for k, v := range data {
+		u.Data[k] = v
+	}
+	s.save()
+}
+
+func (s *StateStore) ClearData(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok {
+		u.Data = make(map[string]string)
+		s.prune(userID)
+		s.save()
+	}
+}
+
+// Clear drops both the state and the data of a user.
+func (s *StateStore) Clear(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, userID)
+	if _, ok := s.Users[userID]; ok {
+		delete(s.Users, userID)
+		s.save()
+	}
+}
+
+// SetSecret keeps a value such as a backup passphrase in memory only.
+func (s *StateStore) SetSecret(userID int64, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[userID] = secret
+}
+
+// TakeSecret returns and forgets the user's secret.
+func (s *StateStore) TakeSecret(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secret := s.secrets[userID]
+	delete(s.secrets, userID)
+	return secret
+}
+
+func (s *StateStore) SetLastMessage(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages[chatID] = messageID
+	s.dirty = true
+}
+
+// TakeLastMessage returns and forgets the last tracked message of a chat.
+func (s *StateStore) TakeLastMessage(chatID int64) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.Messages[chatID]
+	if ok {
+		delete(s.Messages, chatID)
+		s.dirty = true
+	}
+	return id, ok
+}
+
+// Expire drops flows that have been idle longer than their TTL and flushes
+// pending last-message changes.
+func (s *StateStore) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	changed := false
+	for userID, u := range s.Users {
+		if u.expired(now) {
+			delete(s.Users, userID)
+			changed = true
+		}
+	}
+	if changed || s.dirty {
+		s.save()
+	}
+}
+
+// user returns the entry for userID, creating it, and marks it as active.
+// Callers hold s.mu.
+func (s *StateStore) user(userID int64) *UserState {
+	u, ok := s.Users[userID]
+	if !ok || u.expired(time.Now()) {
+		u = &UserState{Data: make(map[string]string)}
+		s.Users[userID] = u
+	}
+	u.UpdatedAt = time.Now()
+	return u
+}
+
+func (s *StateStore) prune(userID int64) {
+	if u := s.Users[userID]; u.State == "" && len(u.Data) == 0 {
+		delete(s.Users, userID)
+	}
+}
+
+// save writes the store to disk atomically. Callers hold s.mu.
+func (s *StateStore) save() {
+	s.dirty = false
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+	}
+}
+
+func (u *UserState) expired(now time.Time) bool {
+	ttl, ok := StateTTLs[u.State]
+	if !ok {
+		ttl = DefaultStateTTL
+	}
+	return now.Sub(u.UpdatedAt) > ttl
+}
+
+// runStateJanitor expires abandoned flows and flushes the store once a
+// minute.
+func runStateJanitor() {
+	for range time.Tick(time.Minute) {
+		states.Expire()
+	}
+}
+
+// offerResume is shown on /start when the user left a flow unfinished.
+func offerResume(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "⏸️ Anda memiliki proses yang belum selesai. Lanjutkan?"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Lanjutkan"), "resume_flow"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+// resumeFlow repeats the prompt of the user's current state.
+func resumeFlow(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	// A fresh challenge is simpler than replaying the old one
+	if strings.HasPrefix(states.State(userID), "verify_") {
+		startVerification(bot, chatID, userID, config)
+		return
+	}
+	prompt, ok := StatePrompts[states.State(userID)]
+	if !ok {
+		prompt = "Silakan kirim input berikutnya."
+	}
+	sendMessage(bot, chatID, "▶️ "+tr(chatID, prompt))
+}
+
+// ==========================================
+// Quotas
+// ==========================================
+
+// QuotaWindow is the period DailyCreates is counted over.
+const QuotaWindow = 24 * time.Hour
+
+// QuotaStore remembers when each public user created accounts.
+type QuotaStore struct {
+	mu      sync.Mutex
+	path    string
+	Creates map[int64][]time.Time `json:"creates"`
+}
+
+var quotas = &QuotaStore{Creates: make(map[int64][]time.Time)}
+
+func loadQuotaStore(path string) *QuotaStore {
+	q := &QuotaStore{path: path, Creates: make(map[int64][]time.Time)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return q
+	}
+	if err := json.Unmarshal(data, q); err != nil || q.Creates == nil {
+		log.Printf("Data kuota tidak valid, mulai kosong: %v", err)
+		return &QuotaStore{path: path, Creates: make(map[int64][]time.Time)}
+	}
+	return q
+}
+
+// Record counts a successful creation for the user.
+func (q *QuotaStore) Record(userID int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Creates[userID] = append(q.recent(userID), time.Now())
+	q.save()
+}
+
+// Usage returns the creations inside QuotaWindow, oldest first.
+func (q *QuotaStore) Usage(userID int64) []time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]time.Time(nil), q.recent(userID)...)
+}
+
+// Callers hold q.mu.
+func (q *QuotaStore) recent(userID int64) []time.Time {
+	var kept []time.Time
+	for _, t := range q.Creates[userID] {
+		if time.Since(t) < QuotaWindow {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(q.Creates, userID)
+	} else {
+		q.Creates[userID] = kept
+	}
+	return kept
+}
+
+// Callers hold q.mu.
+func (q *QuotaStore) save() {
+	if q.path == "" {
+		return
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return
+	}
+	tmp := q.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan kuota: %v", err)
+		return
+	}
+	os.Rename(tmp, q.path)
+}
+
+// Quota is a snapshot of what a user has used.
+type Quota struct {
+	UserID  int64
+	Active  int
+	Creates []time.Time
+}
+
+func userQuota(userID int64, config *BotConfig) (Quota, error) {
+	users, err := getUsers()
+	if err != nil {
+		return Quota{}, err
+	}
+	quota := Quota{UserID: userID, Creates: quotas.Usage(userID)}
+	for _, u := range ownedUsers(users, userID, config) {
+		if u.Status != "Expired" {
+			quota.Active++
+		}
+	}
+	return quota, nil
+}
+
+// blocked explains why the user cannot create another account, or returns "".
+func (q Quota) blocked(l *UserLimits) string {
+	if l.MaxAccounts > 0 && q.Active >= l.MaxAccounts {
+		return tr(q.UserID, "Batas akun aktif tercapai (%d/%d). Hapus akun lama atau tunggu hingga expired.", q.Active, l.MaxAccounts)
+	}
+	if l.DailyCreates > 0 && len(q.Creates) >= l.DailyCreates {
+		wait := QuotaWindow - time.Since(q.Creates[len(q.Creates)-l.DailyCreates])
+		return tr(q.UserID, "Batas pembuatan harian tercapai (%d/%d). Coba lagi dalam %s.", len(q.Creates), l.DailyCreates, formatWait(q.UserID, wait))
+	}
+	if l.CooldownMinutes > 0 && len(q.Creates) > 0 {
+		cooldown := time.Duration(l.CooldownMinutes) * time.Minute
+		if wait := cooldown - time.Since(q.Creates[len(q.Creates)-1]); wait > 0 {
+			return tr(q.UserID, "Tunggu %s sebelum membuat akun lagi.", formatWait(q.UserID, wait))
+		}
+	}
+	return ""
+}
+
+func (q Quota) summary(l *UserLimits) string {
+	limit := func(used, max int) string {
+		if max <= 0 {
+			return tr(q.UserID, "%d (tanpa batas)", used)
+		}
+		left := max - used
+		if left < 0 {
+			left = 0
+		}
+		return tr(q.UserID, "%d/%d, sisa %d", used, max, left)
+	}
+	days := tr(q.UserID, "tanpa batas")
+	if l.MaxDays > 0 {
+		days = tr(q.UserID, "%d hari", l.MaxDays)
+	}
+	return tr(q.UserID, "📊 Kuota Anda\n• Akun aktif: %s\n• Dibuat 24 jam terakhir: %s\n• Maks durasi: %s",
+		limit(q.Active, l.MaxAccounts), limit(len(q.Creates), l.DailyCreates), days)
+}
+
+// renewAllowance is how many days can be added before the account runs
+// more than maxDays ahead.
+func renewAllowance(password string, maxDays int) int {
+	users, err := getUsers()
+	if err != nil {
+		return 0
+	}
+	for _, u := range users {
+		if u.Password != password {
+			continue
+		}
+		exp, err := time.Parse("2006-01-02", u.Expired)
+		if err != nil {
+			return maxDays
+		}
+		remaining := int(time.Until(exp).Hours() / 24)
+		if remaining < 0 {
+			remaining = 0
+		}
+		return maxDays - remaining
+	}
+	return 0
+}
+
+func formatWait(userID int64, d time.Duration) string {
+	if d < time.Minute {
+		return tr(userID, "1 menit")
+	}
+	if d < time.Hour {
+		return tr(userID, "%d menit", int(d.Minutes()+0.5))
+	}
+	return tr(userID, "%d jam %d menit", int(d.Hours()), int(d.Minutes())%60)
+}
+
+var limitLabels = map[string]string{
+	"max_accounts":     "Maks akun aktif",
+	"max_days":         "Maks hari per akun",
+	"daily_creates":    "Pembuatan per 24 jam",
+	"cooldown_minutes": "Jeda antar pembuatan (menit)",
+}
+
+// setLimit changes one public user limit and saves the config.
+func setLimit(config *BotConfig, field string, val int) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	switch field {
+	case "max_accounts":
+		config.Limits.MaxAccounts = val
+	case "max_days":
+		config.Limits.MaxDays = val
+	case "daily_creates":
+		config.Limits.DailyCreates = val
+	case "cooldown_minutes":
+		config.Limits.CooldownMinutes = val
+	}
+	return saveConfig(config)
+}
+
+func showLimits(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	value := func(v int) string {
+		if v <= 0 {
+			return tr(chatID, "tanpa batas")
+		}
+		return strconv.Itoa(v)
+	}
+	l := currentLimits(config)
+	text := tr(chatID, "📏 Batas User Public (admin tidak dibatasi)\n\n• %s: %s\n• %s: %s\n• %s: %s\n• %s: %s",
+		tr(chatID, limitLabels["max_accounts"]), value(l.MaxAccounts),
+		tr(chatID, limitLabels["max_days"]), value(l.MaxDays),
+		tr(chatID, limitLabels["daily_creates"]), value(l.DailyCreates),
+		tr(chatID, limitLabels["cooldown_minutes"]), value(l.CooldownMinutes))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "👥 Maks Akun"), "limit_set:max_accounts"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📅 Maks Hari"), "limit_set:max_days"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔢 Per 24 Jam"), "limit_set:daily_creates"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⏱️ Cooldown"), "limit_set:cooldown_minutes"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+// ==========================================
+// Join Verification
+// ==========================================
+
+// MaxCaptchaTries is how many wrong answers lock a user out of verification.
+// Each lockout lasts twice as long as the one before, from CaptchaCooldown
+// up to MaxCaptchaCooldown, so guessing the button CAPTCHA does not pay.
+const (
+	MaxCaptchaTries    = 3
+	CaptchaCooldown    = time.Hour
+	MaxCaptchaCooldown = 24 * time.Hour
+)
+
+// CaptchaFailures counts a user's wrong answers across verification rounds.
+type CaptchaFailures struct {
+	Count    int       `json:"count"`
+	Lockouts int       `json:"lockouts"`
+	Until    time.Time `json:"until,omitempty"`
+	Last     time.Time `json:"last"`
+}
+
+// VerifyStore persists users who passed join verification and the failed
+// CAPTCHA answers of those who have not.
+type VerifyStore struct {
+	mu       sync.Mutex
+	path     string
+	Users    map[int64]time.Time         `json:"users"`
+	Failures map[int64]*CaptchaFailures `json:"failures,omitempty"`
+}
+
+var verified = &VerifyStore{Users: make(map[int64]time.Time), Failures: make(map[int64]*CaptchaFailures)}
+
+func loadVerifyStore(path string) *VerifyStore {
+	v := &VerifyStore{path: path, Users: make(map[int64]time.Time), Failures: make(map[int64]*CaptchaFailures)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return v
+	}
+	if err := json.Unmarshal(data, v); err != nil || v.Users == nil {
+		log.Printf("Data verifikasi tidak valid, mulai kosong: %v", err)
+		return &VerifyStore{path: path, Users: make(map[int64]time.Time), Failures: make(map[int64]*CaptchaFailures)}
+	}
+	if v.Failures == nil {
+		v.Failures = make(map[int64]*CaptchaFailures)
+	}
+	// Forget failures once a full lockout period has passed without any
+	for id, f := range v.Failures {
+		if time.Since(f.Last) > MaxCaptchaCooldown && time.Now().After(f.Until) {
+			delete(v.Failures, id)
+		}
+	}
+	return v
+}
+
+// Cooldown is how long the user must wait before the next CAPTCHA.
+func (v *VerifyStore) Cooldown(userID int64) time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if f, ok := v.Failures[userID]; ok {
+		if wait := time.Until(f.Until); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// Fail records a wrong answer and returns the lockout it triggered, if any.
+func (v *VerifyStore) Fail(userID int64) time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f, ok := v.Failures[userID]
+	if !ok {
+		f = &CaptchaFailures{}
+		v.Failures[userID] = f
+	}
+	f.Count++
+	f.Last = time.Now()
+	var wait time.Duration
+	if f.Count >= MaxCaptchaTries {
+		wait = CaptchaCooldown << f.Lockouts
+		if wait > MaxCaptchaCooldown || wait <= 0 {
+			wait = MaxCaptchaCooldown
+		}
+		f.Count = 0
+		f.Lockouts++
+		f.Until = f.Last.Add(wait)
+	}
+	v.save()
+	return wait
+}
+
+func (v *VerifyStore) Has(userID int64) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, ok := v.Users[userID]
+	return ok
+}
+
+func (v *VerifyStore) Add(userID int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.Users[userID] = time.Now()
+	delete(v.Failures, userID)
+	v.save()
+}
+
+// Revoke reports whether the user was verified.
+func (v *VerifyStore) Revoke(userID int64) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.Users[userID]; !ok {
+		return false
+	}
+	delete(v.Users, userID)
+	v.save()
+	return true
+}
+
+func (v *VerifyStore) Count() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.Users)
+}
+
+// Callers hold v.mu.
+func (v *VerifyStore) save() {
+	if v.path == "" {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	tmp := v.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan data verifikasi: %v", err)
+		return
+	}
+	os.Rename(tmp, v.path)
+}
+
+func verificationEnabled(v VerifySettings) bool {
+	return v.Captcha != "" || v.Channel != "" || v.MaxUserID > 0
+}
+
+func isVerified(userID int64, config *BotConfig) bool {
+	return unlimited(config, userID) || !verificationEnabled(verifySettings(config)) || verified.Has(userID)
+}
+
+// startVerification runs the configured checks in order: account age,
+// channel membership, then the CAPTCHA.
+func startVerification(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	v := verifySettings(config)
+	if v.MaxUserID > 0 && userID > v.MaxUserID {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Akun Telegram Anda terlalu baru untuk memakai bot ini. Silakan coba lagi nanti."))
+		return
+	}
+	if wait := verified.Cooldown(userID); wait > 0 {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Terlalu banyak jawaban salah. Coba verifikasi lagi dalam %s.", formatWait(chatID, wait)))
+		return
+	}
+
+	if v.Channel != "" {
+		member, err := isChannelMember(bot, v.Channel, userID)
+		if err != nil {
+			log.Printf("getChatMember %s gagal: %v", v.Channel, err)
+			replyError(bot, chatID, tr(chatID, "Gagal memeriksa keanggotaan channel. Coba lagi nanti."))
+			return
+		}
+		if !member {
+			showJoinChannel(bot, chatID, v.Channel)
+			return
+		}
+	}
+
+	switch v.Captcha {
+	case "math":
+		sendMathCaptcha(bot, chatID, userID, "")
+	case "button":
+		sendButtonCaptcha(bot, chatID, userID, "")
+	default:
+		completeVerification(bot, chatID, userID, config)
+	}
+}
+
+func completeVerification(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	verified.Add(userID)
+	states.Clear(userID)
+	deleteLastMessage(bot, chatID)
+	bot.Send(tgbotapi.NewMessage(chatID, tr(chatID, "✅ Verifikasi berhasil.")))
+	startCreateUser(bot, chatID, userID, config)
+}
+
+func isChannelMember(bot *tgbotapi.BotAPI, channel string, userID int64) (bool, error) {
+	chat := tgbotapi.ChatConfigWithUser{UserID: userID}
+	if strings.HasPrefix(channel, "@") {
+		chat.SuperGroupUsername = channel
+	} else {
+		id, err := strconv.ParseInt(channel, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("channel harus @username atau ID angka")
+		}
+		chat.ChatID = id
+	}
+	member, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{ChatConfigWithUser: chat})
+	if err != nil {
+		return false, err
+	}
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return member.IsMember, nil
+	}
+	return false, nil
+}
+
+func showJoinChannel(bot *tgbotapi.BotAPI, chatID int64, channel string) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if strings.HasPrefix(channel, "@") {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(tr(chatID, "📢 Gabung Channel"), "https://t.me/"+channel[1:]),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Sudah Bergabung"), "verify_check"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
+	))
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "🛡️ Sebelum membuat akun, silakan bergabung ke channel %s lalu tekan \"Sudah Bergabung\".", channel))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+func randInt(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(v.Int64())
+}
+
+func sendMathCaptcha(bot *tgbotapi.BotAPI, chatID int64, userID int64, notice string) {
+	a, b := randInt(20)+1, randInt(20)+1
+	states.Reset(userID, map[string]string{"answer": strconv.Itoa(a + b)})
+	states.SetState(userID, "verify_captcha")
+	sendMessage(bot, chatID, tr(chatID, "%s🛡️ Verifikasi: berapa %d + %d?", notice, a, b))
+}
+
+func checkMathCaptcha(bot *tgbotapi.BotAPI, chatID int64, userID int64, text string, config *BotConfig) {
+	if text == states.Get(userID, "answer") {
+		completeVerification(bot, chatID, userID, config)
+		return
+	}
+	if wait := verified.Fail(userID); wait > 0 {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Verifikasi gagal. Coba lagi dalam %s.", formatWait(chatID, wait)))
+		return
+	}
+	sendMathCaptcha(bot, chatID, userID, tr(chatID, "❌ Jawaban salah.\n"))
+}
+
+var captchaChoices = []string{"🍎 Apel", "🚗 Mobil", "🐱 Kucing", "⭐ Bintang", "🌙 Bulan", "🌲 Pohon", "🎈 Balon", "🐟 Ikan"}
+
+func sendButtonCaptcha(bot *tgbotapi.BotAPI, chatID int64, userID int64, notice string) {
+	// Pick four distinct choices and ask for one of them
+	picks := make([]int, 0, 4)
+	for len(picks) < 4 {
+		n := randInt(len(captchaChoices))
+		dup := false
+		for _, p := range picks {
+			dup = dup || p == n
+		}
+		if !dup {
+			picks = append(picks, n)
+		}
+	}
+	answer := picks[randInt(len(picks))]
+	states.Reset(userID, map[string]string{"answer": strconv.Itoa(answer)})
+	states.SetState(userID, "verify_button")
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range picks {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(strings.Fields(captchaChoices[p])[0], fmt.Sprintf("verify_pick:%d", p)))
+	}
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "%s🛡️ Verifikasi: tekan tombol %s", notice, tr(chatID, captchaChoices[answer])))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel")),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func checkButtonCaptcha(bot *tgbotapi.BotAPI, chatID int64, userID int64, pick string, config *BotConfig) {
+	if states.State(userID) != "verify_button" {
+		return
+	}
+	if pick == states.Get(userID, "answer") {
+		completeVerification(bot, chatID, userID, config)
+		return
+	}
+	if wait := verified.Fail(userID); wait > 0 {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Verifikasi gagal. Coba lagi dalam %s.", formatWait(chatID, wait)))
+		return
+	}
+	sendButtonCaptcha(bot, chatID, userID, tr(chatID, "❌ Salah tombol.\n"))
+}
+
+func showVerification(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	v := verifySettings(config)
+	captcha := v.Captcha
+	if captcha == "" {
+		captcha = "off"
+	}
+	channel := v.Channel
+	if channel == "" {
+		channel = "-"
+	}
+	maxID := "-"
+	if v.MaxUserID > 0 {
+		maxID = strconv.FormatInt(v.MaxUserID, 10)
+	}
+	status := tr(chatID, "Nonaktif")
+	if verificationEnabled(v) {
+		status = tr(chatID, "Aktif")
+	}
+	text := tr(chatID, "🛡️ Verifikasi User Public: %s\n\n• Captcha: %s\n• Wajib join channel: %s\n• Tolak Telegram ID di atas: %s\n• User terverifikasi: %d\n\nChannel dan batas ID diatur di bot-config.json (blok \"verification\").",
+		status, captcha, channel, maxID, verified.Count())
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🧮 Ganti Captcha"), "verify_captcha_cycle"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🚫 Cabut Verifikasi"), "verify_revoke"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+// cycleCaptcha switches off -> math -> button -> off.
+func cycleCaptcha(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	configMu.Lock()
+	switch config.Verification.Captcha {
+	case "":
+		config.Verification.Captcha = "math"
+	case "math":
+		config.Verification.Captcha = "button"
+	default:
+		config.Verification.Captcha = ""
+	}
+	err := saveConfig(config)
+	configMu.Unlock()
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+		return
+	}
+	showVerification(bot, chatID, config)
+}
+
+// ==========================================
+// Staff & Permissions
+// ==========================================
+
+// Permissions a staff role can grant. The owner (admin_id) has all of them.
+const (
+	PermBackup       = "backup"
+	PermRestore      = "restore"
+	PermUsersManage  = "users.manage"
+	PermWalletAdjust = "wallet.adjust"
+	PermBroadcast    = "broadcast"
+	PermConfigEdit   = "config.edit"
+)
+
+var AllPermissions = []string{PermBackup, PermRestore, PermUsersManage, PermWalletAdjust, PermBroadcast, PermConfigEdit}
+
+// DefaultRoles can be overridden or extended with "roles" in bot-config.json.
+var DefaultRoles = map[string][]string{
+	"admin":   AllPermissions,
+	"finance": {PermWalletAdjust, PermBackup},
+	"support": {PermUsersManage, PermBroadcast},
+}
+
+// StaffMember is a Telegram user with a role.
+type StaffMember struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+	Name string `json:"name,omitempty"`
+}
+
+// configMu guards the shared BotConfig, which admins edit while workers
+// read it. saveConfig must be called with it held.
+var configMu sync.RWMutex
+
+// currentLimits returns a copy of the public user limits.
+func currentLimits(config *BotConfig) *UserLimits {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	limits := *config.Limits
+	return &limits
+}
+
+func verifySettings(config *BotConfig) VerifySettings {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config.Verification
+}
+
+func publicMode(config *BotConfig) bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config.Mode == "public"
+}
+
+func rolePermissions(config *BotConfig, role string) ([]string, bool) {
+	if perms, ok := config.Roles[role]; ok {
+		return perms, true
+	}
+	perms, ok := DefaultRoles[role]
+	return perms, ok
+}
+
+func roleNames(config *BotConfig) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, roles := range []map[string][]string{DefaultRoles, config.Roles} {
+		for name := range roles {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func staffRole(config *BotConfig, userID int64) (string, bool) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	for _, m := range config.Staff {
+		if m.ID == userID {
+			return m.Role, true
+		}
+	}
+	return "", false
+}
+
+func isOwner(config *BotConfig, userID int64) bool {
+	return userID == config.AdminID
+}
+
+func isStaff(config *BotConfig, userID int64) bool {
+	if isOwner(config, userID) {
+		return true
+	}
+	_, ok := staffRole(config, userID)
+	return ok
+}
+
+// can reports whether the user holds perm. An empty perm only requires staff.
+func can(config *BotConfig, userID int64, perm string) bool {
+	if isOwner(config, userID) {
+		return true
+	}
+	role, ok := staffRole(config, userID)
+	if !ok {
+		return false
+	}
+	if perm == "" {
+		return true
+	}
+	perms, _ := rolePermissions(config, role)
+	for _, p := range perms {
+		if p == perm || p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// unlimited reports whether the user skips the public account limits and
+// verification. Only roles that manage users do; being staff is not enough.
+func unlimited(config *BotConfig, userID int64) bool {
+	return can(config, userID, PermUsersManage)
+}
+
+// requirePerm is can that tells staff members which permission they lack.
+// Non-staff are ignored silently, like the old admin-only checks.
+func requirePerm(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, userID int64, perm string) bool {
+	if can(config, userID, perm) {
+		return true
+	}
+	if isStaff(config, userID) {
+		replyError(bot, chatID, tr(chatID, "Role Anda tidak memiliki izin %s.", perm))
+	}
+	return false
+}
+
+func showStaff(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	var b strings.Builder
+	b.WriteString(tr(chatID, "👑 Kelola Staff\n\n"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	configMu.RLock()
+	if len(config.Staff) == 0 {
+		b.WriteString(tr(chatID, "Belum ada staff.\n"))
+	}
+	for _, m := range config.Staff {
+		label := strconv.FormatInt(m.ID, 10)
+		if m.Name != "" {
+			label += " (" + m.Name + ")"
+		}
+		b.WriteString(fmt.Sprintf("• %s — %s\n", label, m.Role))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗑️ Hapus ")+label, fmt.Sprintf("staff_remove:%d", m.ID)),
+		))
+	}
+	configMu.RUnlock()
+
+	b.WriteString(tr(chatID, "\nRole:\n"))
+	for _, name := range roleNames(config) {
+		perms, _ := rolePermissions(config, name)
+		b.WriteString(fmt.Sprintf("• %s: %s\n", name, strings.Join(perms, ", ")))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "➕ Tambah / Ubah Staff"), "staff_add")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")),
+	)
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// setStaff adds the member or changes their role and name.
+func setStaff(config *BotConfig, member StaffMember) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	for i, m := range config.Staff {
+		if m.ID == member.ID {
+			config.Staff[i] = member
+			return saveConfig(config)
+		}
+	}
+	config.Staff = append(config.Staff, member)
+	return saveConfig(config)
+}
+
+func removeStaff(config *BotConfig, userID int64) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	kept := config.Staff[:0:0]
+	for _, m := range config.Staff {
+		if m.ID != userID {
+			kept = append(kept, m)
+		}
+	}
+	config.Staff = kept
+	return saveConfig(config)
+}
+
+// ==========================================
+// Slash Commands
+// ==========================================
+
+type commandSpec struct {
+	Command     string
+	Args        string
+	Description string
+	Perm        string // required permission when not Public
+	Public      bool
+}
+
+var botCommands = []commandSpec{
+	{Command: "start", Description: "Menu utama", Public: true},
+	{Command: "create", Args: "<pass> <hari>", Description: "Buat akun", Public: true},
+	{Command: "renew", Args: "<pass> <hari>", Description: "Perpanjang akun", Public: true},
+	{Command: "delete", Args: "<pass>", Description: "Hapus akun", Public: true},
+	{Command: "info", Args: "<pass>", Description: "Detail akun", Public: true},
+	{Command: "lock", Args: "<pass>", Description: "Kunci akun", Perm: PermUsersManage},
+}
+
+func findCommand(name string) (commandSpec, bool) {
+	for _, c := range botCommands {
+		if c.Command == name {
+			return c, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+func (c commandSpec) allowed(config *BotConfig, userID int64) bool {
+	if c.Public {
+		return isAllowed(config, userID)
+	}
+	return can(config, userID, c.Perm)
+}
+
+func (c commandSpec) usage(lang string) string {
+	return strings.TrimSpace("/" + c.Command + " " + translate(lang, c.Args))
+}
+
+// commandsFor lists what the user may run, as shown in Telegram's menu.
+func commandsFor(config *BotConfig, userID int64, lang string) []tgbotapi.BotCommand {
+	var cmds []tgbotapi.BotCommand
+	for _, c := range botCommands {
+		if !c.allowed(config, userID) {
+			continue
+		}
+		desc := translate(lang, c.Description)
+		if c.Args != "" {
+			desc = translate(lang, c.Args) + " — " + desc
+		}
+		cmds = append(cmds, tgbotapi.BotCommand{Command: c.Command, Description: desc})
+	}
+	return cmds
+}
+
+// registerCommands publishes the user command list for all private chats,
+// once per language, and a per-chat list for the owner and every staff
+// member in their own language.
+func registerCommands(bot *tgbotapi.BotAPI, config *BotConfig) {
+	setCommands(bot, tgbotapi.NewBotCommandScopeAllPrivateChats(), "", commandsFor(config, 0, DefaultLanguage))
+	for _, lang := range languageCodes() {
+		// Telegram only accepts two-letter ISO 639-1 codes here
+		if lang != DefaultLanguage && len(lang) == 2 {
+			setCommands(bot, tgbotapi.NewBotCommandScopeAllPrivateChats(), lang, commandsFor(config, 0, lang))
+		}
+	}
+
+	ids := []int64{config.AdminID}
+	configMu.RLock()
+	for _, m := range config.Staff {
+		ids = append(ids, m.ID)
+	}
+	configMu.RUnlock()
+	for _, id := range ids {
+		setCommands(bot, tgbotapi.NewBotCommandScopeChat(id), "", commandsFor(config, id, userLanguage(id)))
+	}
+}
+
+// setCommands sets the list for scope, for users of lang or, with "",
+// everyone the scope covers.
+func setCommands(bot *tgbotapi.BotAPI, scope tgbotapi.BotCommandScope, lang string, cmds []tgbotapi.BotCommand) {
+	var err error
+	if len(cmds) == 0 {
+		_, err = bot.Request(tgbotapi.NewDeleteMyCommandsWithScopeAndLanguage(scope, lang))
+	} else {
+		_, err = bot.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope, lang, cmds...))
+	}
+	if err != nil {
+		log.Printf("Gagal mendaftarkan perintah (%s %d): %v", scope.Type, scope.ChatID, err)
+	}
+}
+
+// dropCommands removes a chat's own list so it falls back to the user list.
+func dropCommands(bot *tgbotapi.BotAPI, chatID int64) {
+	if _, err := bot.Request(tgbotapi.NewDeleteMyCommandsWithScope(tgbotapi.NewBotCommandScopeChat(chatID))); err != nil {
+		log.Printf("Gagal menghapus perintah chat %d: %v", chatID, err)
+	}
+}
+
+func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
+	userID := msg.From.ID
+	chatID := msg.Chat.ID
+
+	spec, ok := findCommand(msg.Command())
+	if !ok {
+		replyError(bot, chatID, tr(chatID, "Perintah tidak dikenal."))
+		return
+	}
+	if !spec.allowed(config, userID) {
+		replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk perintah ini."))
+		return
+	}
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != len(strings.Fields(spec.Args)) {
+		replyError(bot, chatID, tr(chatID, "Format: ")+spec.usage(userLanguage(userID)))
+		return
+	}
+
+	switch spec.Command {
+	case "start":
+		showMainMenu(bot, chatID, config)
+
+	case "create":
+		if !validateUsername(bot, chatID, args[0]) {
+			return
+		}
+		maxDays := 9999
+		if !unlimited(config, userID) {
+			if !isVerified(userID, config) {
+				startVerification(bot, chatID, userID, config)
+				return
+			}
+			quota, err := userQuota(userID, config)
+			if err != nil {
+				replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
+				return
+			}
+			limits := currentLimits(config)
+			if reason := quota.blocked(limits); reason != "" {
+				replyError(bot, chatID, reason)
+				return
+			}
+			if limits.MaxDays > 0 {
+				maxDays = limits.MaxDays
+			}
+		}
+		days, ok := validateNumber(bot, chatID, args[1], 1, maxDays, "Durasi")
+		if !ok {
+			return
+		}
+		createUser(bot, chatID, userID, args[0], days, config)
+
+	case "renew":
+		if !ownsAccount(userID, args[0], config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			return
+		}
+		maxDays := 9999
+		if limits := currentLimits(config); !unlimited(config, userID) && limits.MaxDays > 0 {
+			maxDays = renewAllowance(args[0], limits.MaxDays)
+			if maxDays < 1 {
+				replyError(bot, chatID, tr(chatID, "Akun sudah mencapai batas masa aktif %d hari.", limits.MaxDays))
+				return
+			}
+		}
+		days, ok := validateNumber(bot, chatID, args[1], 1, maxDays, "Durasi")
+		if !ok {
+			return
+		}
+		renewUser(bot, chatID, args[0], days, config)
+
+	case "delete":
+		if !ownsAccount(userID, args[0], config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			return
+		}
+		deleteUser(bot, chatID, args[0], config)
+
+	case "info":
+		if !ownsAccount(userID, args[0], config) {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			return
+		}
+		showAccountCard(bot, chatID, userID, args[0], config)
+
+	case "lock":
+		lockUser(bot, chatID, args[0])
+	}
+}
+
+func lockUser(bot *tgbotapi.BotAPI, chatID int64, password string) {
+	res, err := apiCall("POST", "/user/lock", map[string]interface{}{
+		"password": password,
+	})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+	reply := tgbotapi.NewMessage(chatID, tr(chatID, "🔒 Akun `%s` dikunci. Perpanjang untuk membukanya kembali.", password))
+	reply.ParseMode = "Markdown"
+	bot.Send(reply)
+}
+
+// ==========================================
+// Account Detail
+// ==========================================
+
+// Clients may connect on any port in this range; install.sh DNATs it to
+// the core's listen port.
+const (
+	PortRangeStart = 6000
+	PortRangeEnd   = 19999
+)
+
+// Hysteria v1 clients refuse a profile without bandwidth, and the server
+// does not limit it, so the QR carries a generous default.
+const (
+	ClientUpMbps   = 100
+	ClientDownMbps = 100
+)
+
+// ConnInfo is the server half of a client's connection settings.
+type ConnInfo struct {
+	Domain string
+	Obfs   string
+}
+
+func connectionInfo(config *BotConfig) ConnInfo {
+	conn := ConnInfo{Domain: config.Domain, }
+	if conn.Domain == "" {
+		ipInfo, _ := getIpInfo()
+		conn.Domain = ipInfo.Query
+	}
+	if res, err := apiCall("GET", "/config", nil); err == nil && res["success"] == true {
+		if data, ok := res["data"].(map[string]interface{}); ok {
+			conn.Obfs, _ = data["obfs"].(string)
+		}
+	}
+	return conn
+}
+
+// clientURI is the hysteria:// link encoded in the account QR code, in the
+// Hysteria v1 URI scheme that client apps import.
+func clientURI(password string, conn ConnInfo) string {
+	q := url.Values{}
+	q.Set("protocol", "udp")
+	q.Set("auth", password)
+	q.Set("peer", conn.Domain)
+	q.Set("insecure", "1")
+	q.Set("upmbps", strconv.Itoa(ClientUpMbps))
+	q.Set("downmbps", strconv.Itoa(ClientDownMbps))
+	q.Set("obfs", "xplus")
+	q.Set("obfsParam", conn.Obfs)
+	q.Set("mport", fmt.Sprintf("%d-%d", PortRangeStart, PortRangeEnd))
+	return fmt.Sprintf("hysteria://%s:%d?%s#%s", conn.Domain, PortRangeStart, q.Encode(), url.PathEscape(password))
+}
+
+// daysRemaining counts whole days left, 0 once the account has expired.
+func daysRemaining(expired string) int {
+	exp, err := time.Parse("2006-01-02", expired)
+	if err != nil {
+		return 0
+	}
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	if days := int(exp.Sub(today).Hours() / 24); days > 0 {
+		return days
+	}
+	return 0
+}
+
+// accountCardText renders the card in the language of userID, the viewer.
+func accountCardText(userID int64, u UserData, conn ConnInfo, showOwner bool) string {
+	domain := conn.Domain
+	if domain == "" {
+		domain = tr(userID, "(Belum Diatur)")
+	}
+	obfs := conn.Obfs
+	if obfs == "" {
+		obfs = "-"
+	}
+	text := tr(userID, "📇 *Detail Akun*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Aktif s/d: %s\n⏳ Sisa     : %d hari\n📌 Status   : %s %s",
+		u.Password, domain, PortRangeStart, PortRangeEnd, obfs, u.Expired, daysRemaining(u.Expired), statusIcon(u.Status), statusLabel(userID, u.Status))
+	if showOwner {
+		owner := "admin"
+		if u.Owner != 0 {
+			owner = strconv.FormatInt(u.Owner, 10)
+		}
+		text += tr(userID, "\n👤 Pemilik  : ") + owner
+	}
+	return text
+}
+
+// sendAccountCard sends the card as a photo of the connection QR code, or as
+// plain text if the QR cannot be generated. It is not tracked, so the card
+// stays in the chat after the user moves on.
+func sendAccountCard(bot *tgbotapi.BotAPI, chatID int64, u UserData, showOwner bool, markup tgbotapi.InlineKeyboardMarkup, config *BotConfig) {
+	conn := connectionInfo(config)
+	text := accountCardText(chatID, u, conn, showOwner)
+	deleteLastMessage(bot, chatID)
+
+	// Without the domain or obfs the QR would import a profile that cannot connect
+	var png []byte
+	if conn.Domain != "" && conn.Obfs != "" {
+		var err error
+		if png, err = qrcode.Encode(clientURI(u.Password, conn), qrcode.Medium, 512); err != nil {
+			log.Printf("Gagal membuat QR untuk %s: %v", u.Password, err)
+		}
+	}
+	if png == nil {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = markup
+		bot.Send(msg)
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "zivpn-" + u.Password + ".png", Bytes: png})
+	photo.Caption = text + tr(chatID, "\n\n📷 Scan QR untuk mengimpor akun ke aplikasi.")
+	photo.ParseMode = "Markdown"
+	photo.ReplyMarkup = markup
+	if _, err := bot.Send(photo); err != nil {
+		log.Printf("Gagal mengirim kartu akun %s: %v", u.Password, err)
+	}
+}
+
+// showAccountCard shows one account with the actions the user may take on it.
+func showAccountCard(bot *tgbotapi.BotAPI, chatID int64, userID int64, password string, config *BotConfig) {
+	u, ok := findAccount(userID, password, config)
+	if !ok {
+		replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+		return
+	}
+	manage := can(config, userID, PermUsersManage)
+
+	actions := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔄 Perpanjang"), "select_renew:"+u.Password),
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗑️ Hapus"), "select_delete:"+u.Password),
+	)
+	if manage && u.Status != "Locked" {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔒 Kunci"), "acct_lock:"+u.Password))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		actions,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔑 Ganti Password"), "acct_passwd:"+u.Password)),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🏠 Menu Utama"), "cancel")),
+	)
+	sendAccountCard(bot, chatID, u, manage, markup, config)
+}
+
+// ==========================================
+// Account Search
+// ==========================================
+
+// SearchLimit caps the buttons in one search reply; inline results page
+// through InlinePageSize at a time.
+const (
+	SearchLimit    = 20
+	InlinePageSize = 50
+)
+
+// searchAccounts matches passwords case-insensitively, prefix matches first.
+func searchAccounts(users []UserData, query string) []UserData {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return users
+	}
+	var prefix, substr []UserData
+	for _, u := range users {
+		pw := strings.ToLower(u.Password)
+		if strings.HasPrefix(pw, query) {
+			prefix = append(prefix, u)
+		} else if strings.Contains(pw, query) {
+			substr = append(substr, u)
+		}
+	}
+	return append(prefix, substr...)
+}
+
+func statusIcon(status string) string {
+	switch status {
+	case "Expired":
+		return "🔴"
+	case "Locked":
+		return "🔒"
+	}
+	return "🟢"
+}
+
+// statusLabel translates the account status reported by the API.
+func statusLabel(userID int64, status string) string {
+	switch status {
+	case "Active":
+		return tr(userID, "Aktif")
+	case "Locked":
+		return tr(userID, "Terkunci")
+	case "Expired":
+		return tr(userID, "Kedaluwarsa")
+	}
+	return status
+}
+
+func startSearch(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	states.Reset(userID, nil)
+	states.SetState(userID, "search_query")
+	text := tr(chatID, "🔍 Masukkan sebagian password yang dicari:")
+	if bot.Self.UserName != "" {
+		text += tr(chatID, "\n\nTips: ketik `@%s <kata>` di chat mana pun untuk mencari langsung.", bot.Self.UserName)
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel")),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func showSearchResults(bot *tgbotapi.BotAPI, chatID int64, userID int64, query string, config *BotConfig) {
+	users, err := getUsers()
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
+		return
+	}
+	found := searchAccounts(ownedUsers(users, userID, config), query)
+	if len(found) == 0 {
+		sendMessage(bot, chatID, tr(chatID, "🔍 Tidak ada akun yang cocok dengan \"%s\".", query))
+		return
+	}
+
+	text := tr(chatID, "🔍 Hasil untuk \"%s\" (%d):", query, len(found))
+	if len(found) > SearchLimit {
+		text += tr(chatID, "\nMenampilkan %d teratas, perjelas kata kunci untuk mempersempit.", SearchLimit)
+		found = found[:SearchLimit]
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, u := range found {
+		label := fmt.Sprintf("%s %s (%s)", statusIcon(u.Status), u.Password, u.Expired)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "acct:"+u.Password),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔍 Cari Lagi"), "menu_search"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+	))
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// handleInlineQuery answers "@bot <query>" with the caller's matching accounts.
+// Picking a result posts a short card whose button opens the full card in
+// the private chat with the bot.
+func handleInlineQuery(bot *tgbotapi.BotAPI, query *tgbotapi.InlineQuery, config *BotConfig) {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       []interface{}{},
+		IsPersonal:    true,
+	}
+	if !isAllowed(config, query.From.ID) {
+		bot.Request(answer)
+		return
+	}
+	users, err := getUsers()
+	if err != nil {
+		log.Printf("Inline query gagal mengambil user: %v", err)
+		bot.Request(answer)
+		return
+	}
+	found := searchAccounts(ownedUsers(users, query.From.ID, config), query.Query)
+	conn := connectionInfo(config)
+
+	offset, _ := strconv.Atoi(query.Offset)
+	if offset < 0 || offset > len(found) {
+		offset = len(found)
+	}
+	end := offset + InlinePageSize
+	if end < len(found) {
+		answer.NextOffset = strconv.Itoa(end)
+	} else {
+		end = len(found)
+	}
+
+	for _, u := range found[offset:end] {
+		article := tgbotapi.NewInlineQueryResultArticleMarkdown(u.Password, statusIcon(u.Status)+" "+u.Password,
+			accountCardText(query.From.ID, u, conn, false))
+		article.Description = tr(query.From.ID, "%s • Exp: %s", statusLabel(query.From.ID, u.Status), u.Expired)
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(query.From.ID, "📇 Buka Detail"), "acct:"+u.Password),
+		))
+		article.ReplyMarkup = &markup
+		answer.Results = append(answer.Results, article)
+	}
+	if _, err := bot.Request(answer); err != nil {
+		log.Printf("Gagal menjawab inline query: %v", err)
+	}
+}
+
+func findAccount(userID int64, password string, config *BotConfig) (UserData, bool) {
+	users, err := getUsers()
+	if err != nil {
+		return UserData{}, false
+	}
+	for _, u := range ownedUsers(users, userID, config) {
+		if u.Password == password {
+			return u, true
+		}
+	}
+	return UserData{}, false
+}
+
+func changePassword(bot *tgbotapi.BotAPI, chatID int64, userID int64, oldPassword, newPassword string, config *BotConfig) {
+	res, err := apiCall("POST", "/user/password", map[string]interface{}{
+		"password":     oldPassword,
+		"new_password": newPassword,
+	})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, tr(chatID, "✅ Password %s diganti menjadi %s.", oldPassword, newPassword)))
+	showAccountCard(bot, chatID, userID, newPassword, config)
+}
+
+// ==========================================
+// User Registry
+// ==========================================
+
+// RegistryFlushInterval batches last-seen updates; new users and block
+// changes are written immediately.
+const RegistryFlushInterval = time.Minute
+
+// BotUser is everyone who has sent the bot an update.
+type BotUser struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username,omitempty"`
+	FirstName    string    `json:"first_name,omitempty"`
+	LanguageCode string    `json:"language_code,omitempty"`
+	Language     string    `json:"language,omitempty"` // picked with the language button
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Blocked      bool      `json:"blocked,omitempty"` // a send failed with 403
+}
+
+// Label is the user's @username, else first name, else Telegram ID.
+func (u BotUser) Label() string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
+
+type UserRegistry struct {
+	mu    sync.Mutex
+	path  string
+	dirty bool
+	Users map[int64]*BotUser `json:"users"`
+}
+
+var botUsers = &UserRegistry{Users: make(map[int64]*BotUser)}
+
+func loadUserRegistry(path string) *UserRegistry {
+	r := &UserRegistry{path: path, Users: make(map[int64]*BotUser)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r
+	}
+	if err := json.Unmarshal(data, r); err != nil || r.Users == nil {
+		log.Printf("Data pengguna bot tidak valid, mulai kosong: %v", err)
+		return &UserRegistry{path: path, Users: make(map[int64]*BotUser)}
+	}
+	return r
+}
+
+// Touch records an update from the user. Hearing from a user also means
+// they no longer block the bot.
+func (r *UserRegistry) Touch(from *tgbotapi.User) {
+	if from == nil || from.IsBot {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	u, ok := r.Users[from.ID]
+	if !ok {
+		u = &BotUser{ID: from.ID, FirstSeen: now}
+		r.Users[from.ID] = u
+	}
+	unblocked := u.Blocked
+	u.Username = from.UserName
+	u.FirstName = from.FirstName
+	u.LanguageCode = from.LanguageCode
+	u.LastSeen = now
+	u.Blocked = false
+	r.dirty = true
+	if !ok || unblocked {
+		r.save()
+	}
+}
+
+func (r *UserRegistry) MarkBlocked(userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok && !u.Blocked {
+		u.Blocked = true
+		r.save()
+	}
+}
+
+// SetLanguage records the user's choice, which wins over language_code.
+func (r *UserRegistry) SetLanguage(userID int64, lang string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok {
+		u.Language = lang
+		r.save()
+	}
+}
+
+func (r *UserRegistry) Get(userID int64) (BotUser, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok {
+		return *u, true
+	}
+	return BotUser{}, false
+}
+
+// Search matches the ID, username or first name, most recently seen first.
+// An empty query returns everyone.
+func (r *UserRegistry) Search(query string) []BotUser {
+	query = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	r.mu.Lock()
+	var found []BotUser
+	for _, u := range r.Users {
+		if query == "" || strings.Contains(strconv.FormatInt(u.ID, 10), query) ||
+			strings.Contains(strings.ToLower(u.Username), query) ||
+			strings.Contains(strings.ToLower(u.FirstName), query) {
+			found = append(found, *u)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(found, func(i, j int) bool { return found[i].LastSeen.After(found[j].LastSeen) })
+	return found
+}
+
+func (r *UserRegistry) flushLoop() {
+	for range time.Tick(RegistryFlushInterval) {
+		r.mu.Lock()
+		if r.dirty {
+			r.save()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Callers hold r.mu.
+func (r *UserRegistry) save() {
+	r.dirty = false
+	if r.path == "" {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan data pengguna bot: %v", err)
+		return
+	}
+	os.Rename(tmp, r.path)
+}
+
+func updateSender(update tgbotapi.Update) *tgbotapi.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From
+	case update.InlineQuery != nil:
+		return update.InlineQuery.From
+	}
+	return nil
+}
+
+// BotUsersPageSize is how many users one page of the admin view lists.
+const BotUsersPageSize = 10
+
+// showBotUsers pages through the registry, optionally filtered by query.
+func showBotUsers(bot *tgbotapi.BotAPI, chatID int64, page int, query string) {
+	all := botUsers.Search("")
+	found := all
+	if query != "" {
+		found = botUsers.Search(query)
+	}
+	active, blocked := 0, 0
+	for _, u := range all {
+		if time.Since(u.LastSeen) < 7*24*time.Hour {
+			active++
+		}
+		if u.Blocked {
+			blocked++
+		}
+	}
+
+	totalPages := (len(found) + BotUsersPageSize - 1) / BotUsersPageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+	start := (page - 1) * BotUsersPageSize
+	end := start + BotUsersPageSize
+	if end > len(found) {
+		end = len(found)
+	}
+
+	var b strings.Builder
+	b.WriteString(tr(chatID, "👥 Pengguna Bot\nTotal %d • aktif 7 hari %d • memblokir bot %d\n", len(all), active, blocked))
+	if query != "" {
+		b.WriteString(tr(chatID, "🔍 \"%s\": %d hasil\n", query, len(found)))
+	}
+	b.WriteString(tr(chatID, "Halaman %d/%d\n", page, totalPages))
+	if len(found) == 0 {
+		b.WriteString(tr(chatID, "\nTidak ada pengguna."))
+	}
+	for _, u := range found[start:end] {
+		line := fmt.Sprintf("\n• %d %s", u.ID, u.Label())
+		if u.Username != "" && u.FirstName != "" {
+			line += " (" + u.FirstName + ")"
+		}
+		if u.LanguageCode != "" {
+			line += " 🌐" + u.LanguageCode
+		}
+		if u.Blocked {
+			line += " 🚫"
+		}
+		line += tr(chatID, "\n  pertama %s • terakhir %s", u.FirstSeen.Format("2006-01-02"), u.LastSeen.Format("2006-01-02 15:04"))
+		b.WriteString(line)
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬅️ Sebelumnya"), fmt.Sprintf("botusers:%d:%s", page-1, query)))
+	}
+	if page < totalPages {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "Berikutnya ➡️"), fmt.Sprintf("botusers:%d:%s", page+1, query)))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔍 Cari Pengguna"), "botusers_search")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")),
+	)
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// handleBotUsersPage parses "botusers:<page>:<query>".
+func handleBotUsersPage(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	parts := strings.SplitN(strings.TrimPrefix(data, "botusers:"), ":", 2)
+	page, _ := strconv.Atoi(parts[0])
+	query := ""
+	if len(parts) == 2 {
+		query = parts[1]
+	}
+	showBotUsers(bot, chatID, page, query)
+}
+
+// isBlockedError reports a 403: the user blocked the bot or deleted the account.
+func isBlockedError(err error) bool {
+	tgErr, ok := err.(*tgbotapi.Error)
+	return ok && tgErr.Code == 403
+}
+
+// ==========================================
+// Localization
+// ==========================================
+
+// Messages are written in Indonesian and the Indonesian text is the key,
+// gettext style: a catalog maps it to a translation, and anything missing
+// falls back to Indonesian. More languages are <code>.json files in LangDir,
+// shaped like the template the bot writes there on start:
+// {"name": "🇲🇾 Bahasa Melayu", "messages": {"<indonesian>": "<translation>"}}
+const DefaultLanguage = "id"
+
+// LanguageButton is the same in every language so anyone can find it.
+const LanguageButton = "🌐 Bahasa / Language"
+
+type Catalog struct {
+	Name     string            `json:"name"`
+	Messages map[string]string `json:"messages"`
+}
+
+// catalogs is filled before updates are served and only read afterwards.
+var catalogs = map[string]*Catalog{
+	"id": {Name: "🇮🇩 Bahasa Indonesia", Messages: map[string]string{}},
+	"en": {Name: "🇬🇧 English", Messages: catalogEN},
+}
+
+var langCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]+)?$`)
+
+// loadCatalogs adds the language files in dir. A file for a built-in
+// language overrides individual messages.
+func loadCatalogs(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		code := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !langCodePattern.MatchString(code) {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Printf("Gagal membaca bahasa %s: %v", file, err)
+			continue
+		}
+		var c Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			log.Printf("File bahasa %s tidak valid: %v", file, err)
+			continue
+		}
+		if existing, ok := catalogs[code]; ok {
+			if c.Name != "" {
+				existing.Name = c.Name
+			}
+			for id, text := range c.Messages {
+				existing.Messages[id] = text
+			}
+			continue
+		}
+		if c.Name == "" {
+			c.Name = code
+		}
+		if c.Messages == nil {
+			c.Messages = map[string]string{}
+		}
+		catalogs[code] = &c
+	}
+}
+
+// writeCatalogTemplate lists every message with its English translation as
+// a starting point for a new language file.
+func writeCatalogTemplate(path string) {
+	data, err := json.MarshalIndent(Catalog{Name: "", Messages: catalogEN}, "", "  ")
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Printf("Gagal menulis template bahasa: %v", err)
+	}
+}
+
+// languageCodes lists the available languages, built-in ones first.
+func languageCodes() []string {
+	codes := []string{"id", "en"}
+	var extra []string
+	for code := range catalogs {
+		if code != "id" && code != "en" {
+			extra = append(extra, code)
+		}
+	}
+	sort.Strings(extra)
+	return append(codes, extra...)
+}
+
+// userLanguage is the language the user picked, else the one Telegram
+// reports for them. Malay speakers read Indonesian more easily than
+// English, and other unknown languages get English.
+func userLanguage(userID int64) string {
+	u, _ := botUsers.Get(userID)
+	if _, ok := catalogs[u.Language]; ok {
+		return u.Language
+	}
+	code := strings.ToLower(u.LanguageCode)
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	base := strings.SplitN(code, "-", 2)[0]
+	if _, ok := catalogs[base]; ok {
+		return base
+	}
+	if base == "" || base == "ms" {
+		return DefaultLanguage
+	}
+	return "en"
+}
+
+// translate looks msg up in lang's catalog and formats it when args are given.
+func translate(lang, msg string, args ...interface{}) string {
+	if c, ok := catalogs[lang]; ok {
+		if text := c.Messages[msg]; text != "" {
+			msg = text
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// tr translates msg into the user's language.
+func tr(userID int64, msg string, args ...interface{}) string {
+	return translate(userLanguage(userID), msg, args...)
+}
+
+func showLanguageMenu(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	current := userLanguage(userID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, code := range languageCodes() {
+		label := catalogs[code].Name
+		if code == current {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "lang_set:"+code),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+	))
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "🌐 Pilih bahasa:"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// setLanguage saves the user's choice and refreshes their command list,
+// which staff get per chat.
+func setLanguage(bot *tgbotapi.BotAPI, chatID int64, userID int64, lang string, config *BotConfig) {
+	if _, ok := catalogs[lang]; !ok {
+		return
+	}
+	botUsers.SetLanguage(userID, lang)
+	if isStaff(config, userID) {
+		go setCommands(bot, tgbotapi.NewBotCommandScopeChat(userID), "", commandsFor(config, userID, lang))
+	}
+	showMainMenu(bot, chatID, config)
+}
+
+// ==========================================
+// Feature Implementation
+// ==========================================
+
+func startCreateUser(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	prompt := tr(chatID, "👤 Masukkan Password:")
+	if !unlimited(config, userID) {
+		if !isVerified(userID, config) {
+			startVerification(bot, chatID, userID, config)
+			return
+		}
+		quota, err := userQuota(userID, config)
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
+			return
+		}
+		limits := currentLimits(config)
+		if reason := quota.blocked(limits); reason != "" {
+			replyError(bot, chatID, reason)
+			return
+		}
+		prompt = quota.summary(limits) + "\n\n" + prompt
+	}
+	states.Reset(userID, nil)
+	states.SetState(userID, "create_username")
+	sendMessage(bot, chatID, prompt)
+}
+
+func startRenewUser(bot *tgbotapi.BotAPI, chatID int64, userID int64, data string, config *BotConfig) {
+	username := strings.TrimPrefix(data, "select_renew:")
+	prompt := tr(chatID, "🔄 Perpanjang %s\n⏳ Masukkan Tambahan Durasi (hari):", username)
+	if limits := currentLimits(config); !unlimited(config, userID) && limits.MaxDays > 0 {
+		allowance := renewAllowance(username, limits.MaxDays)
+		if allowance < 1 {
+			replyError(bot, chatID, tr(chatID, "Akun sudah mencapai batas masa aktif %d hari.", limits.MaxDays))
+			return
+		}
+		prompt = tr(chatID, "🔄 Perpanjang %s\n⏳ Masukkan Tambahan Durasi (maks %d hari):", username, allowance)
+	}
+	states.Reset(userID, map[string]string{"username": username})
+	states.SetState(userID, "renew_days")
+	sendMessage(bot, chatID, prompt)
+}
+
+func confirmDeleteUser(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	username := strings.TrimPrefix(data, "select_delete:")
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "❓ Yakin ingin menghapus user `%s`?", username))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Ya, Hapus"), "confirm_delete:"+username),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func cancelOperation(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	resetState(userID)
+	showMainMenu(bot, chatID, config)
+}
+
+func handlePagination(bot *tgbotapi.BotAPI, chatID int64, userID int64, data string, config *BotConfig) {
+	parts := strings.Split(data, ":")
+	action := parts[0][5:] // remove "page_"
+	page, _ := strconv.Atoi(parts[1])
+	showUserSelection(bot, chatID, userID, page, action, config)
+}
+
+func toggleMode(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	if !requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+		return
+	}
+	configMu.Lock()
+	if config.Mode == "public" {
+		config.Mode = "private"
+	} else {
+		config.Mode = "public"
+	}
+	saveConfig(config)
+	configMu.Unlock()
+	go registerCommands(bot, config)
+	showMainMenu(bot, chatID, config)
+}
+
+func createUser(bot *tgbotapi.BotAPI, chatID int64, ownerID int64, username string, days int, config *BotConfig) {
+	res, err := apiCall("POST", "/user/create", map[string]interface{}{
+		"password": username,
+		"days":     days,
+		"owner":    ownerID,
+	})
+
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+
+	if res["success"] == true {
+		if !unlimited(config, ownerID) {
+			quotas.Record(ownerID)
+		}
 		data := res["data"].(map[string]interface{})
 		sendAccountInfo(bot, chatID, data, config)
 	} else {
-		replyError(bot, chatID, fmt.Sprintf("Gagal: %s", res["message"]))
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		showMainMenu(bot, chatID, config)
 	}
 }
@@ -328,7 +3071,7 @@ func renewUser(bot *tgbotapi.BotAPI, chatID int64, username string, days int, co
 	})
 
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
 
@@ -338,7 +3081,7 @@ func renewUser(bot *tgbotapi.BotAPI, chatID int64, username string, days int, co
 		// But for now, let's just display what we have.
 		sendAccountInfo(bot, chatID, data, config)
 	} else {
-		replyError(bot, chatID, fmt.Sprintf("Gagal: %s", res["message"]))
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		showMainMenu(bot, chatID, config)
 	}
 }
@@ -349,17 +3092,17 @@ func deleteUser(bot *tgbotapi.BotAPI, chatID int64, username string, config *Bot
 	})
 
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
 
 	if res["success"] == true {
-		msg := tgbotapi.NewMessage(chatID, "✅ Password berhasil dihapus.")
+		msg := tgbotapi.NewMessage(chatID, tr(chatID, "✅ Password berhasil dihapus."))
 		deleteLastMessage(bot, chatID)
 		bot.Send(msg)
 		showMainMenu(bot, chatID, config)
 	} else {
-		replyError(bot, chatID, fmt.Sprintf("Gagal: %s", res["message"]))
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		showMainMenu(bot, chatID, config)
 	}
 }
@@ -367,18 +3110,18 @@ func deleteUser(bot *tgbotapi.BotAPI, chatID int64, username string, config *Bot
 func listUsers(bot *tgbotapi.BotAPI, chatID int64) {
 	res, err := apiCall("GET", "/users", nil)
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
 
 	if res["success"] == true {
 		users := res["data"].([]interface{})
 		if len(users) == 0 {
-			sendMessage(bot, chatID, "📂 Tidak ada user.")
+			sendMessage(bot, chatID, tr(chatID, "📂 Tidak ada user."))
 			return
 		}
 
-		msg := "📋 *List Passwords*\n"
+		msg := tr(chatID, "📋 *Daftar Password*\n")
 		for _, u := range users {
 			user := u.(map[string]interface{})
 			status := "🟢"
@@ -392,187 +3135,583 @@ func listUsers(bot *tgbotapi.BotAPI, chatID int64) {
 		reply.ParseMode = "Markdown"
 		sendAndTrack(bot, reply)
 	} else {
-		replyError(bot, chatID, "Gagal mengambil data.")
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data."))
+	}
+}
+
+func systemInfo(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	res, err := apiCall("GET", "/info", nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+
+	if res["success"] == true {
+		data := res["data"].(map[string]interface{})
+		ipInfo, _ := getIpInfo()
+
+		var info struct {
+			Uptime int64      `json:"uptime_seconds"`
+			Load   [3]float64 `json:"load"`
+			CPU    struct {
+				Cores        int     `json:"cores"`
+				UsagePercent float64 `json:"usage_percent"`
+			} `json:"cpu"`
+			Memory struct {
+				Total uint64 `json:"total"`
+				Used  uint64 `json:"used"`
+			} `json:"memory"`
+		}
+		dataBytes, _ := json.Marshal(data)
+		json.Unmarshal(dataBytes, &info)
+		uptime := time.Duration(info.Uptime) * time.Second
+
+		msg := tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Publik      : %s\nPort           : %s\nLayanan        : %s\nKota           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```",
+			config.Domain, data["public_ip"], data["port"], data["service"], ipInfo.City, ipInfo.Isp,
+			int(uptime.Hours())/24, int(uptime.Hours())%24,
+			info.CPU.Cores, info.CPU.UsagePercent,
+			info.Load[0], info.Load[1], info.Load[2],
+			info.Memory.Used/1024/1024, info.Memory.Total/1024/1024)
+
+		reply := tgbotapi.NewMessage(chatID, msg)
+		reply.ParseMode = "Markdown"
+		deleteLastMessage(bot, chatID)
+		bot.Send(reply)
+		showMainMenu(bot, chatID, config)
+	} else {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil info."))
+	}
+}
+
+func showBackupRestoreMenu(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "💾 *Backup & Restore*\nSilakan pilih menu:"))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬇️ Backup Data"), "menu_backup_action"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬆️ Restore Data"), "menu_restore_action"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔒 Enkripsi Backup"), "menu_backup_encryption"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗓️ Backup Otomatis"), "menu_backup_schedule"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func performBackup(bot *tgbotapi.BotAPI, chatID int64) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang membuat backup..."))
+
+	data, err := apiDownload("/backup")
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal membuat backup: ")+err.Error())
+		return
+	}
+
+	fileName := fmt.Sprintf("zivpn-backup-%s.zip", time.Now().Format("20060102-150405"))
+	caption := tr(chatID, "✅ Backup Data ZiVPN")
+	if bytes.HasPrefix(data, []byte(EncryptedMagic)) {
+		fileName += ".enc"
+		caption += tr(chatID, " (🔒 terenkripsi)")
+	}
+	
+	// Create a temporary file for the upload
+	tmpFile := "/tmp/" + fileName
+	if err := ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal membuat file backup."))
+		return
+	}
+	defer os.Remove(tmpFile)
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(tmpFile))
+	doc.Caption = caption
+	
+	deleteLastMessage(bot, chatID)
+	bot.Send(doc)
+}
+
+func startRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	states.SetState(userID, "waiting_restore_file")
+	sendMessage(bot, chatID, tr(chatID, "⬆️ *Restore Data*\n\nSilakan kirim file backup (.zip atau .zip.enc) Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!"))
+}
+
+func restoreTempFile(userID int64) string {
+	return fmt.Sprintf("/tmp/zivpn-restore-%d.zip", userID)
+}
+
+// processRestoreFile downloads the uploaded archive and asks the API for a
+// dry run, so the admin sees what will change before confirming.
+func processRestoreFile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
+	chatID := msg.Chat.ID
+	userID := msg.From.ID
+	
+	resetState(userID)
+	if msg.Document.FileSize > MaxRestoreSize {
+		replyError(bot, chatID, tr(chatID, "File terlalu besar (maksimal %d MB).", MaxRestoreSize>>20))
+		return
+	}
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang memproses file..."))
+
+	// Download file
+	fileID := msg.Document.FileID
+	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh file."))
+		return
+	}
+
+	fileUrl := file.Link(config.BotToken)
+	resp, err := http.Get(fileUrl)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh isi file."))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh isi file."))
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxRestoreSize+1))
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal membaca file."))
+		return
+	}
+	if len(body) > MaxRestoreSize {
+		replyError(bot, chatID, tr(chatID, "File terlalu besar (maksimal %d MB).", MaxRestoreSize>>20))
+		return
+	}
+
+	if err := ioutil.WriteFile(restoreTempFile(userID), body, 0600); err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan file sementara."))
+		return
+	}
+
+	previewRestore(bot, chatID, userID, "")
+}
+
+// previewRestore runs a dry run of the saved upload. Encrypted backups ask
+// for a passphrase or secret key first; the secret is kept until the admin
+// confirms or cancels.
+func previewRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, secret string) {
+	body, err := ioutil.ReadFile(restoreTempFile(userID))
+	if err != nil {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "File restore tidak ditemukan, silakan kirim ulang."))
+		return
+	}
+
+	res, err := apiUpload("/restore?dry_run=1", body, secret)
+	if err != nil {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		if data, ok := res["data"].(map[string]interface{}); ok && data["encrypted"] == true {
+			states.SetState(userID, "waiting_restore_secret")
+			text := tr(chatID, "🔒 Backup terenkripsi.\nKirim passphrase atau secret key (ZIVPNSEC1...):")
+			if secret != "" {
+				text = fmt.Sprintf("❌ %s\n\n%s", res["message"], text)
+			}
+			reply := tgbotapi.NewMessage(chatID, text)
+			reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "restore_cancel"),
+				),
+			)
+			sendAndTrack(bot, reply)
+			return
+		}
+		resetState(userID)
+		os.Remove(restoreTempFile(userID))
+		replyError(bot, chatID, tr(chatID, "Backup ditolak: %s", res["message"]))
+		return
+	}
+
+	states.ClearState(userID)
+	states.SetSecret(userID, secret)
+
+	report, _ := res["data"].(map[string]interface{})
+	text := tr(chatID, "🔍 Pratinjau Restore\n\n") + formatRestoreReport(chatID, report)
+	if report["encrypted"] == true {
+		text += tr(chatID, "\n🔒 Backup terenkripsi berhasil dibuka.")
+	}
+	if report["legacy"] == true {
+		text += tr(chatID, "\n⚠️ Backup lama tanpa manifest, checksum tidak diverifikasi.")
+	}
+	text += tr(chatID, "\n\nLanjutkan restore?")
+
+	reply := tgbotapi.NewMessage(chatID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Ya, Restore"), "restore_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "restore_cancel"),
+		),
+	)
+	sendAndTrack(bot, reply)
+}
+
+func confirmRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	path := restoreTempFile(userID)
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "File restore tidak ditemukan, silakan kirim ulang."))
+		return
+	}
+	os.Remove(path)
+	secret := states.TakeSecret(userID)
+
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang merestore data..."))
+	res, err := apiUpload("/restore", body, secret)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Restore gagal: %s", res["message"]))
+		return
+	}
+
+	report, _ := res["data"].(map[string]interface{})
+	msgSuccess := tgbotapi.NewMessage(chatID, tr(chatID, "✅ Restore Berhasil!\n\n")+formatRestoreReport(chatID, report))
+	deleteLastMessage(bot, chatID)
+	bot.Send(msgSuccess)
+
+	restartBot := false
+	if services, ok := report["restart_required"].([]interface{}); ok {
+		for _, svc := range services {
+			if svc == "zivpn-bot" {
+				restartBot = true
+			}
+		}
+	}
+	if restartBot {
+		go func() {
+			time.Sleep(2 * time.Second)
+			exec.Command("systemctl", "restart", "zivpn-bot").Run()
+		}()
+	}
+
+	showMainMenu(bot, chatID, config)
+}
+
+func cancelRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	os.Remove(restoreTempFile(userID))
+	states.TakeSecret(userID)
+	cancelOperation(bot, chatID, userID, config)
+}
+
+func formatRestoreReport(chatID int64, report map[string]interface{}) string {
+	join := func(key string) string {
+		items := []string{}
+		if list, ok := report[key].([]interface{}); ok {
+			for _, v := range list {
+				items = append(items, fmt.Sprintf("%v", v))
+			}
+		}
+		if len(items) == 0 {
+			return "-"
+		}
+		return strings.Join(items, ", ")
+	}
+	text := tr(chatID, "File: %s\nUser ditambah: %s\nUser dihapus: %s", join("files"), join("users_added"), join("users_removed"))
+	if skipped := join("skipped"); skipped != "-" {
+		text += tr(chatID, "\nDilewati: ") + skipped
+	}
+	if safety, ok := report["safety_backup"].(string); ok && safety != "" {
+		text += tr(chatID, "\nBackup pengaman: ") + safety
+	}
+	return text
+}
+
+func showBackupEncryption(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/settings", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil pengaturan backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+
+	passphrase := tr(chatID, "❌ Tidak aktif")
+	if data["passphrase_set"] == true {
+		passphrase = tr(chatID, "✅ Aktif")
+	}
+	recipients, _ := data["recipients"].([]interface{})
+	identity := tr(chatID, "Tidak ada")
+	if data["server_identity"] == true {
+		identity = tr(chatID, "Tersimpan di server")
+	}
+
+	text := tr(chatID, "🔒 Enkripsi Backup\n\nPassphrase: %s\nRecipient key: %d\nSecret key: %s\n\nRecipient key diatur lewat API /api/backup/settings.", passphrase, len(recipients), identity)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔑 Atur Passphrase"), "backup_pass_set"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🚫 Hapus Passphrase"), "backup_pass_clear"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func setBackupPassphrase(bot *tgbotapi.BotAPI, chatID int64, passphrase string) {
+	res, err := apiCall("PUT", "/backup/settings", map[string]string{"passphrase": passphrase})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan passphrase: %s", res["message"]))
+		return
+	}
+	if passphrase == "" {
+		sendMessage(bot, chatID, tr(chatID, "✅ Passphrase backup dihapus."))
+	} else {
+		sendMessage(bot, chatID, tr(chatID, "✅ Passphrase backup disimpan. Backup berikutnya akan terenkripsi."))
+	}
+	showBackupEncryption(bot, chatID)
+}
+
+// showBackupSchedule shows the scheduled backup status from /backup/status.
+func showBackupSchedule(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/status", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil status backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+
+	schedule := tr(chatID, "❌ Tidak aktif")
+	if s, _ := data["schedule"].(string); s != "" {
+		schedule = s
+	}
+	nextRun := "-"
+	if s, ok := data["next_run"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			nextRun = t.Local().Format("02 Jan 2006 15:04")
+		}
+	} else if s, _ := data["schedule"].(string); s != "" {
+		nextRun = tr(chatID, "Tidak ada jadwal berikutnya")
+	}
+	lastRun := tr(chatID, "Belum pernah")
+	if run, ok := data["last_run"].(map[string]interface{}); ok {
+		when := fmt.Sprintf("%v", run["time"])
+		if t, err := time.Parse(time.RFC3339, when); err == nil {
+			when = t.Local().Format("02 Jan 2006 15:04")
+		}
+		if run["success"] == true {
+			lastRun = fmt.Sprintf("✅ %s (%s)", when, formatBytes(run["size"]))
+		} else {
+			lastRun = fmt.Sprintf("❌ %s: %v", when, run["error"])
+		}
+	}
+	daily, _ := data["daily"].([]interface{})
+	weekly, _ := data["weekly"].([]interface{})
+	telegram := "OFF"
+	if data["telegram"] == true {
+		telegram = "ON"
+	}
+	encrypted := tr(chatID, "Tidak")
+	if data["encrypted"] == true {
+		encrypted = tr(chatID, "Ya")
 	}
+
+	text := tr(chatID, "🗓️ Backup Otomatis\n\nJadwal: %s\nBerikutnya: %s\nTerakhir: %s\nHarian: %d/%v\nMingguan: %d/%v\nTerenkripsi: %s\nKirim ke Telegram: %s\n\nFile disimpan di /var/backups/zivpn",
+		schedule, nextRun, lastRun, len(daily), data["keep_daily"], len(weekly), data["keep_weekly"], encrypted, telegram)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Backup Sekarang"), "backup_run_now"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📨 Telegram: ")+telegram, "backup_tg_toggle"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🕒 Ubah Jadwal"), "backup_schedule_set"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗂️ Retensi"), "backup_keep_set"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
 }
 
-func systemInfo(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
-	res, err := apiCall("GET", "/info", nil)
+func runBackupNow(bot *tgbotapi.BotAPI, chatID int64) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang membuat backup..."))
+	res, err := apiCall("POST", "/backup/run", nil)
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, fmt.Sprintf("%v", res["message"]))
 		return
 	}
+	showBackupSchedule(bot, chatID)
+}
 
-	if res["success"] == true {
-		data := res["data"].(map[string]interface{})
-		ipInfo, _ := getIpInfo()
+func toggleBackupTelegram(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/settings", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil pengaturan backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+	updateBackupSettings(bot, chatID, map[string]interface{}{"telegram": data["telegram"] != true})
+}
 
-		msg := fmt.Sprintf("```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Public      : %s\nPort           : %s\nService        : %s\nCITY           : %s\nISP            : %s\n━━━━━━━━━━━━━━━━━━━━━\n```",
-			config.Domain, data["public_ip"], data["port"], data["service"], ipInfo.City, ipInfo.Isp)
+func updateBackupSettings(bot *tgbotapi.BotAPI, chatID int64, settings map[string]interface{}) {
+	res, err := apiCall("PUT", "/backup/settings", settings)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan: %s", res["message"]))
+		return
+	}
+	showBackupSchedule(bot, chatID)
+}
 
-		reply := tgbotapi.NewMessage(chatID, msg)
-		reply.ParseMode = "Markdown"
-		deleteLastMessage(bot, chatID)
-		bot.Send(reply)
-		showMainMenu(bot, chatID, config)
-	} else {
-		replyError(bot, chatID, "Gagal mengambil info.")
+func formatBytes(v interface{}) string {
+	size, _ := v.(float64)
+	if size >= 1024*1024 {
+		return fmt.Sprintf("%.1f MB", size/1024/1024)
 	}
+	return fmt.Sprintf("%.1f KB", size/1024)
 }
 
-func showBackupRestoreMenu(bot *tgbotapi.BotAPI, chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "💾 *Backup & Restore*\nSilakan pilih menu:")
+func showServiceMenu(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "⚙️ *Service Core*\nSilakan pilih aksi:"))
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⬇️ Backup Data", "menu_backup_action"),
-			tgbotapi.NewInlineKeyboardButtonData("⬆️ Restore Data", "menu_restore_action"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📟 Status"), "svc_status"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📜 Log"), "svc_logs"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔄 Restart"), "svc_restart"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Start"), "svc_start"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⏹️ Stop"), "svc_stop"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Kembali", "cancel"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
 		),
 	)
 	sendAndTrack(bot, msg)
 }
 
-func performBackup(bot *tgbotapi.BotAPI, chatID int64) {
-	sendMessage(bot, chatID, "⏳ Sedang membuat backup...")
-
-	// Files to backup
-	files := []string{
-		"/etc/zivpn/config.json",
-		"/etc/zivpn/users.json",
-		"/etc/zivpn/domain",
-	}
-
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
-
-	for _, file := range files {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			continue
-		}
-
-		f, err := os.Open(file)
-		if err != nil {
-			continue
-		}
-		defer f.Close()
-
-		w, err := zipWriter.Create(filepath.Base(file))
-		if err != nil {
-			continue
-		}
+func confirmServiceStop(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "❓ Yakin ingin menghentikan zivpn.service? Semua user akan terputus."))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Ya, Stop"), "svc_stop_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "menu_service"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
 
-		if _, err := io.Copy(w, f); err != nil {
-			continue
-		}
+func serviceStatus(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/service/status", nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
 	}
-
-	zipWriter.Close()
-
-	fileName := fmt.Sprintf("zivpn-backup-%s.zip", time.Now().Format("20060102-150405"))
-	
-	// Create a temporary file for the upload
-	tmpFile := "/tmp/" + fileName
-	if err := ioutil.WriteFile(tmpFile, buf.Bytes(), 0644); err != nil {
-		replyError(bot, chatID, "Gagal membuat file backup.")
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		return
 	}
-	defer os.Remove(tmpFile)
-
-	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(tmpFile))
-	doc.Caption = "✅ Backup Data ZiVPN"
-	
-	deleteLastMessage(bot, chatID)
-	bot.Send(doc)
-}
 
-func startRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "waiting_restore_file"
-	sendMessage(bot, chatID, "⬆️ *Restore Data*\n\nSilakan kirim file ZIP backup Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!")
+	data, _ := res["data"].(map[string]interface{})
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    STATUS ZIVPN\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSejak    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```",
+		data["ActiveState"], data["SubState"], data["MainPID"], data["ActiveEnterTimestamp"], data["NRestarts"]))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "menu_service")),
+	)
+	sendAndTrack(bot, msg)
 }
 
-func processRestoreFile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
-	chatID := msg.Chat.ID
-	userID := msg.From.ID
-	
-	resetState(userID)
-	sendMessage(bot, chatID, "⏳ Sedang memproses file...")
-
-	// Download file
-	fileID := msg.Document.FileID
-	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+func serviceAction(bot *tgbotapi.BotAPI, chatID int64, action string) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Menjalankan %s...", action))
+	res, err := apiCall("POST", "/service/"+action, nil)
 	if err != nil {
-		replyError(bot, chatID, "Gagal mengunduh file.")
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
-
-	fileUrl := file.Link(config.BotToken)
-	resp, err := http.Get(fileUrl)
-	if err != nil {
-		replyError(bot, chatID, "Gagal mengunduh file content.")
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		return
 	}
-	defer resp.Body.Close()
+	serviceStatus(bot, chatID)
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func serviceLogs(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/logs?unit=zivpn&lines=30", nil)
 	if err != nil {
-		replyError(bot, chatID, "Gagal membaca file.")
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
-
-	// Unzip
-	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
-	if err != nil {
-		replyError(bot, chatID, "File bukan format ZIP yang valid.")
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		return
 	}
 
-	for _, f := range zipReader.File {
-		// Security check: only allow specific files
-		validFiles := map[string]bool{
-			"config.json": true,
-			"users.json": true,
-			"bot-config.json": true,
-			"domain": true,
-			"apikey": true,
-		}
-		
-		if !validFiles[f.Name] {
-			continue
+	lines, _ := res["data"].([]interface{})
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(fmt.Sprintf("%v\n", l))
+	}
+	text := strings.ReplaceAll(b.String(), "`", "'")
+	if text == "" {
+		text = tr(chatID, "(log kosong)\n")
+	}
+	// Telegram messages are capped at 4096 characters
+	if len(text) > 3800 {
+		cut := len(text) - 3800
+		for cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut++
 		}
+		text = "...\n" + text[cut:]
+	}
 
-		rc, err := f.Open()
-		if err != nil {
-			continue
-		}
-		defer rc.Close()
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "📜 Log zivpn.service\n```\n")+text+"```")
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "menu_service")),
+	)
+	sendAndTrack(bot, msg)
+}
 
-		dstPath := filepath.Join("/etc/zivpn", f.Name)
-		dst, err := os.Create(dstPath)
-		if err != nil {
-			continue
+func startEditObfs(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	current := "-"
+	if res, err := apiCall("GET", "/config", nil); err == nil && res["success"] == true {
+		if data, ok := res["data"].(map[string]interface{}); ok {
+			current = fmt.Sprintf("%v", data["obfs"])
 		}
-		defer dst.Close()
-
-		io.Copy(dst, rc)
 	}
+	states.SetState(userID, "admin_obfs_input")
+	sendMessage(bot, chatID, tr(chatID, "🔐 Obfs saat ini: %s\nMasukkan obfs baru (1-64 karakter, tanpa spasi):", current))
+}
 
-	// Restart Services
-	exec.Command("systemctl", "restart", "zivpn").Run()
-	exec.Command("systemctl", "restart", "zivpn-api").Run()
-	
-	msgSuccess := tgbotapi.NewMessage(chatID, "✅ Restore Berhasil!\nService ZiVPN, API, dan Bot telah direstart.")
-	bot.Send(msgSuccess)
-	
-	// Restart Bot with delay to allow message sending
-	go func() {
-		time.Sleep(2 * time.Second)
-		exec.Command("systemctl", "restart", "zivpn-bot").Run()
-	}()
-
-	showMainMenu(bot, chatID, config)
+func updateObfs(bot *tgbotapi.BotAPI, chatID int64, obfs string) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Menerapkan config dan merestart service..."))
+	res, err := apiCall("PUT", "/config", map[string]interface{}{"obfs": obfs})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+	sendMessage(bot, chatID, tr(chatID, "✅ Obfs diubah menjadi `%s`. Client harus memakai obfs baru.", obfs))
 }
 
 // ==========================================
@@ -583,10 +3722,10 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
 	ipInfo, _ := getIpInfo()
 	domain := config.Domain
 	if domain == "" {
-		domain = "(Not Configured)"
+		domain = tr(chatID, "(Belum Diatur)")
 	}
 
-	msgText := fmt.Sprintf("```\n━━━━━━━━━━━━━━━━━━━━━\n    MENU ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • City     : %s\n • ISP      : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\n👇 Silakan pilih menu dibawah ini:", domain, ipInfo.City, ipInfo.Isp)
+	msgText := tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    MENU ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • Kota     : %s\n • ISP      : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\n👇 Silakan pilih menu dibawah ini:", domain, ipInfo.City, ipInfo.Isp)
 
 	msg := tgbotapi.NewMessage(chatID, msgText)
 	msg.ParseMode = "Markdown"
@@ -598,32 +3737,57 @@ func getMainMenuKeyboard(config *BotConfig, userID int64) tgbotapi.InlineKeyboar
 	// Public Menu (Everyone)
 	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("👤 Create Password", "menu_create"),
-			tgbotapi.NewInlineKeyboardButtonData("🗑️ Delete Password", "menu_delete"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "👤 Buat Password"), "menu_create"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "🗑️ Hapus Password"), "menu_delete"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔄 Renew Password", "menu_renew"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "🔄 Perpanjang Password"), "menu_renew"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "🔍 Cari"), "menu_search"),
 		),
 	}
 
-	// Admin Menu (Admin Only)
-	if userID == config.AdminID {
-		modeLabel := "🔐 Mode: Private"
-		if config.Mode == "public" {
-			modeLabel = "🌍 Mode: Public"
+	// Staff Menu (buttons follow the role's permissions)
+	if isStaff(config, userID) {
+		if can(config, userID, PermUsersManage) {
+			rows[1] = append(rows[1], tgbotapi.NewInlineKeyboardButtonData(tr(userID, "📋 Daftar Password"), "menu_list"))
+			rows[2] = append(rows[2], tgbotapi.NewInlineKeyboardButtonData(tr(userID, "👥 Pengguna Bot"), "menu_bot_users"))
 		}
 
-		rows[1] = append(rows[1], tgbotapi.NewInlineKeyboardButtonData("📋 List Passwords", "menu_list"))
-		
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📊 System Info", "menu_info"),
-			tgbotapi.NewInlineKeyboardButtonData("💾 Backup & Restore", "menu_backup_restore"),
-		))
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(modeLabel, "toggle_mode"),
-		))
+		staffRow := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "📊 Info Sistem"), "menu_info"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(userID, "⚙️ Service Core"), "menu_service"),
+		)
+		if can(config, userID, PermBackup) || can(config, userID, PermRestore) {
+			staffRow = append(staffRow, tgbotapi.NewInlineKeyboardButtonData(tr(userID, "💾 Backup & Restore"), "menu_backup_restore"))
+		}
+		rows = append(rows, staffRow)
+
+		if can(config, userID, PermConfigEdit) {
+			modeLabel := tr(userID, "🔐 Mode: Privat")
+			if publicMode(config) {
+				modeLabel = tr(userID, "🌍 Mode: Publik")
+			}
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(userID, "🔐 Ubah Obfs"), "menu_obfs"),
+				tgbotapi.NewInlineKeyboardButtonData(modeLabel, "toggle_mode"),
+			))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(userID, "📏 Batas User"), "menu_limits"),
+				tgbotapi.NewInlineKeyboardButtonData(tr(userID, "🛡️ Verifikasi User"), "menu_verify"),
+			))
+		}
+		if isOwner(config, userID) {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr(userID, "👑 Kelola Staff"), "menu_staff"),
+			))
+		}
 	}
 
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(LanguageButton, "menu_lang"),
+	))
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
@@ -631,10 +3795,10 @@ func sendAccountInfo(bot *tgbotapi.BotAPI, chatID int64, data map[string]interfa
 	ipInfo, _ := getIpInfo()
 	domain := config.Domain
 	if domain == "" {
-		domain = "(Not Configured)"
+		domain = tr(chatID, "(Belum Diatur)")
 	}
 
-	msg := fmt.Sprintf("```\n━━━━━━━━━━━━━━━━━━━━━\n  ACCOUNT ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nCITY       : %s\nISP        : %s\nIP ISP     : %s\nDomain     : %s\nExpired On : %s\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	msg := tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    AKUN ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nKota       : %s\nISP        : %s\nIP ISP     : %s\nDomain     : %s\nMasa Aktif : %s\n━━━━━━━━━━━━━━━━━━━━━\n```",
 		data["password"],
 		ipInfo.City,
 		ipInfo.Isp,
@@ -645,20 +3809,28 @@ func sendAccountInfo(bot *tgbotapi.BotAPI, chatID int64, data map[string]interfa
 
 	reply := tgbotapi.NewMessage(chatID, msg)
 	reply.ParseMode = "Markdown"
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📇 Detail & QR"), fmt.Sprintf("acct:%v", data["password"])),
+	))
 	deleteLastMessage(bot, chatID)
 	bot.Send(reply)
 	showMainMenu(bot, chatID, config)
 }
 
-func showUserSelection(bot *tgbotapi.BotAPI, chatID int64, page int, action string) {
+func showUserSelection(bot *tgbotapi.BotAPI, chatID int64, userID int64, page int, action string, config *BotConfig) {
 	users, err := getUsers()
 	if err != nil {
-		replyError(bot, chatID, "Gagal mengambil data user.")
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
 		return
 	}
+	users = ownedUsers(users, userID, config)
 
 	if len(users) == 0 {
-		sendMessage(bot, chatID, "📂 Tidak ada user.")
+		if can(config, userID, PermUsersManage) {
+			sendMessage(bot, chatID, tr(chatID, "📂 Tidak ada user."))
+		} else {
+			sendMessage(bot, chatID, tr(chatID, "📂 Anda belum memiliki akun."))
+		}
 		return
 	}
 
@@ -680,12 +3852,7 @@ func showUserSelection(bot *tgbotapi.BotAPI, chatID int64, page int, action stri
 
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, u := range users[start:end] {
-		label := fmt.Sprintf("%s (%s)", u.Password, u.Status)
-		if u.Status == "Expired" {
-			label = fmt.Sprintf("🔴 %s", label)
-		} else {
-			label = fmt.Sprintf("🟢 %s", label)
-		}
+		label := fmt.Sprintf("%s %s (%s)", statusIcon(u.Status), u.Password, statusLabel(chatID, u.Status))
 		data := fmt.Sprintf("select_%s:%s", action, u.Password)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(label, data),
@@ -694,27 +3861,31 @@ func showUserSelection(bot *tgbotapi.BotAPI, chatID int64, page int, action stri
 
 	var navRow []tgbotapi.InlineKeyboardButton
 	if page > 1 {
-		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("page_%s:%d", action, page-1)))
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬅️ Sebelumnya"), fmt.Sprintf("page_%s:%d", action, page-1)))
 	}
 	if page < totalPages {
-		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("page_%s:%d", action, page+1)))
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "Berikutnya ➡️"), fmt.Sprintf("page_%s:%d", action, page+1)))
 	}
 	if len(navRow) > 0 {
 		rows = append(rows, navRow)
 	}
 
-	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "cancel")))
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel")))
 
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📋 Pilih User untuk %s (Halaman %d/%d):", strings.Title(action), page, totalPages))
+	title := tr(chatID, "Hapus")
+	if action == "renew" {
+		title = tr(chatID, "Perpanjang")
+	}
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "📋 Pilih User untuk %s (Halaman %d/%d):", title, page, totalPages))
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	sendAndTrack(bot, msg)
 }
 
 func sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, inState := userStates[chatID]; inState {
+	if states.State(chatID) != "" {
 		cancelKb := tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "cancel")),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel")),
 		)
 		msg.ReplyMarkup = cancelKb
 	}
@@ -729,21 +3900,21 @@ func sendAndTrack(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) {
 	deleteLastMessage(bot, msg.ChatID)
 	sentMsg, err := bot.Send(msg)
 	if err == nil {
-		lastMessageIDs[msg.ChatID] = sentMsg.MessageID
+		states.SetLastMessage(msg.ChatID, sentMsg.MessageID)
+	} else if isBlockedError(err) {
+		botUsers.MarkBlocked(msg.ChatID)
 	}
 }
 
 func deleteLastMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	if msgID, ok := lastMessageIDs[chatID]; ok {
+	if msgID, ok := states.TakeLastMessage(chatID); ok {
 		deleteMsg := tgbotapi.NewDeleteMessage(chatID, msgID)
 		bot.Request(deleteMsg)
-		delete(lastMessageIDs, chatID)
 	}
 }
 
 func resetState(userID int64) {
-	delete(userStates, userID)
-	delete(tempUserData, userID)
+	states.Clear(userID)
 }
 
 // ==========================================
@@ -752,11 +3923,11 @@ func resetState(userID int64) {
 
 func validateUsername(bot *tgbotapi.BotAPI, chatID int64, text string) bool {
 	if len(text) < 3 || len(text) > 20 {
-		sendMessage(bot, chatID, "❌ Password harus 3-20 karakter. Coba lagi:")
+		sendMessage(bot, chatID, tr(chatID, "❌ Password harus 3-20 karakter. Coba lagi:"))
 		return false
 	}
 	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(text) {
-		sendMessage(bot, chatID, "❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:")
+		sendMessage(bot, chatID, tr(chatID, "❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:"))
 		return false
 	}
 	return true
@@ -765,7 +3936,7 @@ func validateUsername(bot *tgbotapi.BotAPI, chatID int64, text string) bool {
 func validateNumber(bot *tgbotapi.BotAPI, chatID int64, text string, min, max int, fieldName string) (int, bool) {
 	val, err := strconv.Atoi(text)
 	if err != nil || val < min || val > max {
-		sendMessage(bot, chatID, fmt.Sprintf("❌ %s harus angka positif (%d-%d). Coba lagi:", fieldName, min, max))
+		sendMessage(bot, chatID, tr(chatID, "❌ %s harus angka positif (%d-%d). Coba lagi:", tr(chatID, fieldName), min, max))
 		return 0, false
 	}
 	return val, true
@@ -776,15 +3947,20 @@ func validateNumber(bot *tgbotapi.BotAPI, chatID int64, text string, min, max in
 // ==========================================
 
 func isAllowed(config *BotConfig, userID int64) bool {
-	return config.Mode == "public" || userID == config.AdminID
+	return publicMode(config) || isStaff(config, userID)
 }
 
+// saveConfig writes the config atomically. Callers hold configMu.
 func saveConfig(config *BotConfig) error {
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(BotConfigFile, data, 0644)
+	tmp := BotConfigFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, BotConfigFile)
 }
 
 func loadConfig() (BotConfig, error) {
@@ -794,6 +3970,10 @@ func loadConfig() (BotConfig, error) {
 		return config, err
 	}
 	err = json.Unmarshal(file, &config)
+	if config.Limits == nil {
+		limits := DefaultLimits
+		config.Limits = &limits
+	}
 
 	// Jika domain kosong di config, coba baca dari file domain
 	if config.Domain == "" {
@@ -842,20 +4022,241 @@ func apiCall(method, endpoint string, payload interface{}) (map[string]interface
 	return result, nil
 }
 
+// watchApiEvents follows the API's /api/events stream and tells the admin
+// about events that need attention, reconnecting with Last-Event-ID so no
+// event is missed across API restarts.
+func watchApiEvents(bot *tgbotapi.BotAPI, config *BotConfig) {
+	lastID := ""
+	for {
+		err := followApiEvents(&lastID, func(eventType string, data map[string]interface{}) {
+			notifyAdminEvent(bot, config, eventType, data)
+		})
+		if err != nil {
+			log.Printf("Event stream terputus: %v", err)
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func followApiEvents(lastID *string, handle func(string, map[string]interface{})) error {
+	req, err := http.NewRequest("GET", ApiUrl+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", ApiKey)
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var id, eventType, payload string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if payload != "" {
+				var ev struct {
+					Data map[string]interface{} `json:"data"`
+				}
+				if json.Unmarshal([]byte(payload), &ev) == nil {
+					handle(eventType, ev.Data)
+				}
+			}
+			if id != "" {
+				*lastID = id
+			}
+			id, eventType, payload = "", "", ""
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			payload += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func notifyAdminEvent(bot *tgbotapi.BotAPI, config *BotConfig, eventType string, data map[string]interface{}) {
+	admin := config.AdminID
+	var text string
+	switch eventType {
+	case "expire.run":
+		count, _ := data["revoked_count"].(float64)
+		if count == 0 && data["success"] != false {
+			return
+		}
+		names := []string{}
+		if list, ok := data["revoked"].([]interface{}); ok {
+			for _, p := range list {
+				names = append(names, fmt.Sprintf("%v", p))
+			}
+		}
+		text = tr(admin, "⏰ Pemeriksaan expired: %d akun dicabut.\n%s", int(count), strings.Join(names, "\n"))
+		if data["success"] == false {
+			text += tr(admin, "\n⚠️ Sebagian akun gagal dicabut, cek log zivpn-api.")
+		}
+	case "service.restarted":
+		if data["success"] == true {
+			return
+		}
+		text = tr(admin, "⚠️ Restart zivpn.service gagal: %v", data["error"])
+	case "backup.created":
+		if data["success"] != true {
+			text = tr(admin, "⚠️ Backup otomatis gagal: %v", data["error"])
+			break
+		}
+		if data["telegram"] != true {
+			return
+		}
+		// The stream replays buffered events on reconnect; only forward fresh ones
+		if t, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", data["time"])); err != nil || time.Since(t) > 10*time.Minute {
+			return
+		}
+		doc := tgbotapi.NewDocument(admin, tgbotapi.FilePath(fmt.Sprintf("%v", data["file"])))
+		doc.Caption = tr(admin, "🗓️ Backup otomatis ZiVPN")
+		if data["encrypted"] == true {
+			doc.Caption += tr(admin, " (🔒 terenkripsi)")
+		}
+		if _, err := bot.Send(doc); err != nil {
+			log.Printf("Gagal mengirim backup ke admin: %v", err)
+		}
+		return
+	default:
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(admin, text))
+}
+
+// apiDownload fetches a binary API response such as /backup.
+func apiDownload(endpoint string) ([]byte, error) {
+	req, err := http.NewRequest("GET", ApiUrl+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", ApiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var result map[string]interface{}
+		json.Unmarshal(body, &result)
+		return nil, fmt.Errorf("%v", result["message"])
+	}
+	return body, nil
+}
+
+// apiUpload posts a backup archive to the API and decodes the JSON response.
+// secret, when set, is the passphrase or secret key of an encrypted backup.
+func apiUpload(endpoint string, data []byte, secret string) (map[string]interface{}, error) {
+	req, err := http.NewRequest("POST", ApiUrl+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("X-API-Key", ApiKey)
+	if secret != "" {
+		req.Header.Set("X-Backup-Secret", secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Server location rarely changes; cache it so menus render without waiting on ip-api.com
+var ipInfoCache struct {
+	sync.Mutex
+	info    IpInfo
+	fetched time.Time
+}
+
+const IpInfoTTL = 6 * time.Hour
+
 func getIpInfo() (IpInfo, error) {
-	resp, err := http.Get("http://ip-api.com/json/")
+	ipInfoCache.Lock()
+	defer ipInfoCache.Unlock()
+	if !ipInfoCache.fetched.IsZero() && time.Since(ipInfoCache.fetched) < IpInfoTTL {
+		return ipInfoCache.info, nil
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://ip-api.com/json/")
 	if err != nil {
-		return IpInfo{}, err
+		return ipInfoCache.info, err
 	}
 	defer resp.Body.Close()
 
 	var info IpInfo
 	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return IpInfo{}, err
+		return ipInfoCache.info, err
 	}
+	ipInfoCache.info = info
+	ipInfoCache.fetched = time.Now()
 	return info, nil
 }
 
+// ownedUsers keeps the accounts a user may manage: all for staff with
+// users.manage, otherwise only those the user created.
+func ownedUsers(users []UserData, userID int64, config *BotConfig) []UserData {
+	if can(config, userID, PermUsersManage) {
+		return users
+	}
+	var owned []UserData
+	for _, u := range users {
+		if u.Owner == userID {
+			owned = append(owned, u)
+		}
+	}
+	return owned
+}
+
+func ownsAccount(userID int64, password string, config *BotConfig) bool {
+	if can(config, userID, PermUsersManage) {
+		return true
+	}
+	users, err := getUsers()
+	if err != nil {
+		return false
+	}
+	for _, u := range ownedUsers(users, userID, config) {
+		if u.Password == password {
+			return true
+		}
+	}
+	return false
+}
+
 func getUsers() ([]UserData, error) {
 	res, err := apiCall("GET", "/users", nil)
 	if err != nil {
@@ -871,3 +4272,254 @@ func getUsers() ([]UserData, error) {
 	json.Unmarshal(dataBytes, &users)
 	return users, nil
 }
+
+// ==========================================
+// Message Catalog (English)
+// ==========================================
+
+// catalogEN translates every message into English. Its keys double as the
+// list of messages in the template written to LangDir.
+var catalogEN = map[string]string{
+	"❌ Terjadi kesalahan internal. Silakan coba lagi.":        "❌ Internal error. Please try again.",
+	"⛔ Akses Ditolak. Bot ini Private.":                       "⛔ Access denied. This bot is private.",
+	"Akses Ditolak":                                           "Access denied",
+	"🚫 Masukkan Telegram ID yang verifikasinya akan dicabut:": "🚫 Enter the Telegram ID whose verification should be revoked:",
+	"📏 Masukkan batas baru untuk %s (0 = tanpa batas):":       "📏 Enter the new limit for %s (0 = unlimited):",
+	"🔑 Masukkan passphrase backup baru (minimal 8 karakter):": "🔑 Enter the new backup passphrase (at least 8 characters):",
+	"🕒 Masukkan jadwal cron (menit jam tanggal bulan hari), contoh \"0 3 * * *\" untuk setiap hari jam 03:00.\nKetik \"off\" untuk mematikan.": "🕒 Enter a cron schedule (minute hour day month weekday), e.g. \"0 3 * * *\" for every day at 03:00.\nType \"off\" to turn it off.",
+	"🗂️ Masukkan jumlah backup harian dan mingguan yang disimpan (contoh: 7 4):":                                                               "🗂️ Enter how many daily and weekly backups to keep (e.g. 7 4):",
+	"👑 Masukkan: <TelegramID> <role> [nama]\nContoh: 7251232303 support Budi\nRole: ":                                                          "👑 Enter: <TelegramID> <role> [name]\nExample: 7251232303 support Budi\nRoles: ",
+	"Gagal menyimpan config: ":                                                       "Failed to save config: ",
+	"🔍 Masukkan ID, username atau nama pengguna:":                                    "🔍 Enter an ID, username or name:",
+	"Akun tidak ditemukan atau bukan milik Anda.":                                    "Account not found or not yours.",
+	"🔑 Masukkan password baru untuk %s:":                                             "🔑 Enter the new password for %s:",
+	"⏳ Masukkan Durasi (hari):":                                                      "⏳ Enter the duration (days):",
+	"Gagal mengambil data user.":                                                     "Failed to fetch user data.",
+	"Akun sudah mencapai batas masa aktif %d hari.":                                  "The account has reached the %d-day validity limit.",
+	"❌ Telegram ID harus angka. Coba lagi:":                                          "❌ The Telegram ID must be a number. Try again:",
+	"✅ Verifikasi user %d dicabut.":                                                  "✅ Verification of user %d revoked.",
+	"User %d belum terverifikasi.":                                                   "User %d is not verified.",
+	"❌ Format salah. Contoh: 7251232303 support Budi":                                "❌ Wrong format. Example: 7251232303 support Budi",
+	"❌ Telegram ID tidak valid. Coba lagi:":                                          "❌ Invalid Telegram ID. Try again:",
+	"❌ Role tidak dikenal. Pilih: ":                                                  "❌ Unknown role. Choose: ",
+	"❌ Format salah. Contoh: 7 4":                                                    "❌ Wrong format. Example: 7 4",
+	"❌ Jumlah harus angka. Contoh: 7 4":                                              "❌ Both values must be numbers. Example: 7 4",
+	"⏸️ Anda memiliki proses yang belum selesai. Lanjutkan?":                         "⏸️ You have an unfinished step. Continue?",
+	"▶️ Lanjutkan":                                                                   "▶️ Continue",
+	"❌ Batal":                                                                        "❌ Cancel",
+	"Batas akun aktif tercapai (%d/%d). Hapus akun lama atau tunggu hingga expired.": "Active account limit reached (%d/%d). Delete an old account or wait until one expires.",
+	"Batas pembuatan harian tercapai (%d/%d). Coba lagi dalam %s.":                   "Daily creation limit reached (%d/%d). Try again in %s.",
+	"Tunggu %s sebelum membuat akun lagi.":                                           "Wait %s before creating another account.",
+	"%d (tanpa batas)":                                                               "%d (unlimited)",
+	"%d/%d, sisa %d":                                                                 "%d/%d, %d left",
+	"tanpa batas":                                                                    "unlimited",
+	"%d hari":                                                                        "%d days",
+	"📊 Kuota Anda\n• Akun aktif: %s\n• Dibuat 24 jam terakhir: %s\n• Maks durasi: %s": "📊 Your quota\n• Active accounts: %s\n• Created in the last 24 hours: %s\n• Max duration: %s",
+	"1 menit":         "1 minute",
+	"%d menit":        "%d minutes",
+	"%d jam %d menit": "%d h %d min",
+	"📏 Batas User Public (admin tidak dibatasi)\n\n• %s: %s\n• %s: %s\n• %s: %s\n• %s: %s": "📏 Public user limits (admins are not limited)\n\n• %s: %s\n• %s: %s\n• %s: %s\n• %s: %s",
+	"👥 Maks Akun":  "👥 Max Accounts",
+	"📅 Maks Hari":  "📅 Max Days",
+	"🔢 Per 24 Jam": "🔢 Per 24 Hours",
+	"⏱️ Cooldown":  "⏱️ Cooldown",
+	"❌ Kembali":    "❌ Back",
+	"Akun Telegram Anda terlalu baru untuk memakai bot ini. Silakan coba lagi nanti.": "Your Telegram account is too new to use this bot. Please try again later.",
+	"Gagal memeriksa keanggotaan channel. Coba lagi nanti.":                           "Could not check channel membership. Try again later.",
+	"✅ Verifikasi berhasil.":                                                          "✅ Verification successful.",
+	"📢 Gabung Channel":                                                                "📢 Join Channel",
+	"✅ Sudah Bergabung":                                                               "✅ I've Joined",
+	"🛡️ Sebelum membuat akun, silakan bergabung ke channel %s lalu tekan \"Sudah Bergabung\".": "🛡️ Before creating an account, please join the channel %s and then press \"I've Joined\".",
+	"%s🛡️ Verifikasi: berapa %d + %d?":                                                         "%s🛡️ Verification: what is %d + %d?",
+	"Verifikasi gagal. Coba lagi dalam %s.":                                                    "Verification failed. Try again in %s.",
+	"Terlalu banyak jawaban salah. Coba verifikasi lagi dalam %s.":                             "Too many wrong answers. Try verifying again in %s.",
+	"❌ Jawaban salah.\n":                                                                       "❌ Wrong answer.\n",
+	"%s🛡️ Verifikasi: tekan tombol %s":                                                         "%s🛡️ Verification: press the %s button",
+	"❌ Salah tombol.\n":                                                                        "❌ Wrong button.\n",
+	"Nonaktif":                                                                                 "Inactive",
+	"Aktif":                                                                                    "Active",
+	"🛡️ Verifikasi User Public: %s\n\n• Captcha: %s\n• Wajib join channel: %s\n• Tolak Telegram ID di atas: %s\n• User terverifikasi: %d\n\nChannel dan batas ID diatur di bot-config.json (blok \"verification\").": "🛡️ Public user verification: %s\n\n• Captcha: %s\n• Channel to join: %s\n• Reject Telegram IDs above: %s\n• Verified users: %d\n\nThe channel and ID limit are set in bot-config.json (the \"verification\" block).",
+	"🧮 Ganti Captcha":                              "🧮 Change Captcha",
+	"🚫 Cabut Verifikasi":                           "🚫 Revoke Verification",
+	"Role Anda tidak memiliki izin %s.":            "Your role lacks the %s permission.",
+	"👑 Kelola Staff\n\n":                           "👑 Manage Staff\n\n",
+	"Belum ada staff.\n":                           "No staff yet.\n",
+	"🗑️ Hapus ":                                    "🗑️ Remove ",
+	"\nRole:\n":                                    "\nRoles:\n",
+	"➕ Tambah / Ubah Staff":                        "➕ Add / Change Staff",
+	"Perintah tidak dikenal.":                      "Unknown command.",
+	"Anda tidak memiliki izin untuk perintah ini.": "You are not allowed to use this command.",
+	"Format: ":                                     "Usage: ",
+	"Error API: ":                                  "API error: ",
+	"Gagal: %s":                                    "Failed: %s",
+	"🔒 Akun `%s` dikunci. Perpanjang untuk membukanya kembali.": "🔒 Account `%s` locked. Renew it to unlock.",
+	"(Belum Diatur)": "(Not Configured)",
+	"📇 *Detail Akun*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Aktif s/d: %s\n⏳ Sisa     : %d hari\n📌 Status   : %s %s": "📇 *Account Details*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Expires  : %s\n⏳ Left     : %d days\n📌 Status   : %s %s",
+	"\n👤 Pemilik  : ": "\n👤 Owner    : ",
+	"\n\n📷 Scan QR untuk mengimpor akun ke aplikasi.": "\n\n📷 Scan the QR code to import the account into the app.",
+	"🔄 Perpanjang":     "🔄 Renew",
+	"🗑️ Hapus":         "🗑️ Delete",
+	"🔒 Kunci":          "🔒 Lock",
+	"🔑 Ganti Password": "🔑 Change Password",
+	"🏠 Menu Utama":     "🏠 Main Menu",
+	"Terkunci":         "Locked",
+	"Kedaluwarsa":      "Expired",
+	"🔍 Masukkan sebagian password yang dicari:":                             "🔍 Enter part of the password to search for:",
+	"\n\nTips: ketik `@%s <kata>` di chat mana pun untuk mencari langsung.": "\n\nTip: type `@%s <word>` in any chat to search directly.",
+	"🔍 Tidak ada akun yang cocok dengan \"%s\".":                            "🔍 No accounts match \"%s\".",
+	"🔍 Hasil untuk \"%s\" (%d):":                                            "🔍 Results for \"%s\" (%d):",
+	"\nMenampilkan %d teratas, perjelas kata kunci untuk mempersempit.":     "\nShowing the top %d, refine the search to narrow it down.",
+	"🔍 Cari Lagi":                       "🔍 Search Again",
+	"%s • Exp: %s":                      "%s • Exp: %s",
+	"📇 Buka Detail":                     "📇 Open Details",
+	"✅ Password %s diganti menjadi %s.": "✅ Password %s changed to %s.",
+	"👥 Pengguna Bot\nTotal %d • aktif 7 hari %d • memblokir bot %d\n": "👥 Bot Users\nTotal %d • active in 7 days %d • blocked the bot %d\n",
+	"🔍 \"%s\": %d hasil\n":         "🔍 \"%s\": %d results\n",
+	"Halaman %d/%d\n":              "Page %d/%d\n",
+	"\nTidak ada pengguna.":        "\nNo users.",
+	"\n  pertama %s • terakhir %s": "\n  first %s • last %s",
+	"⬅️ Sebelumnya":                "⬅️ Prev",
+	"Berikutnya ➡️":                "Next ➡️",
+	"🔍 Cari Pengguna":              "🔍 Search Users",
+	"🌐 Pilih bahasa:":              "🌐 Choose a language:",
+	"👤 Masukkan Password:":         "👤 Enter a password:",
+	"🔄 Perpanjang %s\n⏳ Masukkan Tambahan Durasi (hari):":         "🔄 Renewing %s\n⏳ Enter the days to add:",
+	"🔄 Perpanjang %s\n⏳ Masukkan Tambahan Durasi (maks %d hari):": "🔄 Renewing %s\n⏳ Enter the days to add (max %d):",
+	"❓ Yakin ingin menghapus user `%s`?":                          "❓ Are you sure you want to delete user `%s`?",
+	"✅ Ya, Hapus":                                                 "✅ Yes, Delete",
+	"✅ Password berhasil dihapus.":                                "✅ Password deleted.",
+	"📂 Tidak ada user.":                                           "📂 No users.",
+	"📋 *Daftar Password*\n":                                       "📋 *Password List*\n",
+	"Gagal mengambil data.":                                       "Failed to fetch data.",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Publik      : %s\nPort           : %s\nLayanan        : %s\nKota           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```": "```\n━━━━━━━━━━━━━━━━━━━━━\n    ZIVPN UDP INFO\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nPublic IP      : %s\nPort           : %s\nService        : %s\nCity           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	"Gagal mengambil info.":                     "Failed to fetch info.",
+	"💾 *Backup & Restore*\nSilakan pilih menu:": "💾 *Backup & Restore*\nPlease choose:",
+	"⬇️ Backup Data":                            "⬇️ Backup Data",
+	"⬆️ Restore Data":                           "⬆️ Restore Data",
+	"🔒 Enkripsi Backup":                         "🔒 Backup Encryption",
+	"🗓️ Backup Otomatis":                        "🗓️ Automatic Backup",
+	"⏳ Sedang membuat backup...":                "⏳ Creating backup...",
+	"Gagal membuat backup: ":                    "Failed to create backup: ",
+	"✅ Backup Data ZiVPN":                       "✅ ZiVPN Data Backup",
+	" (🔒 terenkripsi)":                          " (🔒 encrypted)",
+	"Gagal membuat file backup.":                "Failed to create the backup file.",
+	"⬆️ *Restore Data*\n\nSilakan kirim file backup (.zip atau .zip.enc) Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!": "⬆️ *Restore Data*\n\nPlease send your backup file (.zip or .zip.enc) now.\n\n⚠️ WARNING: Current data will be overwritten!",
+	"File terlalu besar (maksimal %d MB).":               "File too large (max %d MB).",
+	"⏳ Sedang memproses file...":                         "⏳ Processing file...",
+	"Gagal mengunduh file.":                              "Failed to download the file.",
+	"Gagal mengunduh isi file.":                          "Failed to download the file content.",
+	"Gagal membaca file.":                                "Failed to read the file.",
+	"Gagal menyimpan file sementara.":                    "Failed to save the temporary file.",
+	"File restore tidak ditemukan, silakan kirim ulang.": "Restore file not found, please send it again.",
+	"🔒 Backup terenkripsi.\nKirim passphrase atau secret key (ZIVPNSEC1...):": "🔒 Encrypted backup.\nSend the passphrase or secret key (ZIVPNSEC1...):",
+	"Backup ditolak: %s":                                            "Backup rejected: %s",
+	"🔍 Pratinjau Restore\n\n":                                       "🔍 Restore Preview\n\n",
+	"\n🔒 Backup terenkripsi berhasil dibuka.":                       "\n🔒 Encrypted backup opened successfully.",
+	"\n⚠️ Backup lama tanpa manifest, checksum tidak diverifikasi.": "\n⚠️ Old backup without a manifest, checksums not verified.",
+	"\n\nLanjutkan restore?":                                        "\n\nContinue the restore?",
+	"✅ Ya, Restore":                                                 "✅ Yes, Restore",
+	"⏳ Sedang merestore data...":                                    "⏳ Restoring data...",
+	"Restore gagal: %s":                                             "Restore failed: %s",
+	"✅ Restore Berhasil!\n\n":                                       "✅ Restore Successful!\n\n",
+	"File: %s\nUser ditambah: %s\nUser dihapus: %s":                 "Files: %s\nUsers added: %s\nUsers removed: %s",
+	"\nDilewati: ":                                                  "\nSkipped: ",
+	"\nBackup pengaman: ":                                           "\nSafety backup: ",
+	"Gagal mengambil pengaturan backup.":                            "Failed to fetch backup settings.",
+	"❌ Tidak aktif":                                                 "❌ Disabled",
+	"✅ Aktif":                                                       "✅ Enabled",
+	"Tidak ada":                                                     "None",
+	"Tersimpan di server":                                           "Stored on the server",
+	"🔒 Enkripsi Backup\n\nPassphrase: %s\nRecipient key: %d\nSecret key: %s\n\nRecipient key diatur lewat API /api/backup/settings.": "🔒 Backup Encryption\n\nPassphrase: %s\nRecipient keys: %d\nSecret key: %s\n\nRecipient keys are managed through the API at /api/backup/settings.",
+	"🔑 Atur Passphrase":              "🔑 Set Passphrase",
+	"🚫 Hapus Passphrase":             "🚫 Remove Passphrase",
+	"Gagal menyimpan passphrase: %s": "Failed to save passphrase: %s",
+	"✅ Passphrase backup dihapus.":   "✅ Backup passphrase removed.",
+	"✅ Passphrase backup disimpan. Backup berikutnya akan terenkripsi.": "✅ Backup passphrase saved. The next backups will be encrypted.",
+	"Tidak ada jadwal berikutnya":                                       "No upcoming run",
+	"Gagal mengambil status backup.":                                    "Failed to fetch backup status.",
+	"Belum pernah":                                                      "Never",
+	"Tidak":                                                             "No",
+	"Ya":                                                                "Yes",
+	"🗓️ Backup Otomatis\n\nJadwal: %s\nBerikutnya: %s\nTerakhir: %s\nHarian: %d/%v\nMingguan: %d/%v\nTerenkripsi: %s\nKirim ke Telegram: %s\n\nFile disimpan di /var/backups/zivpn": "🗓️ Automatic Backup\n\nSchedule: %s\nNext: %s\nLast: %s\nDaily: %d/%v\nWeekly: %d/%v\nEncrypted: %s\nSend to Telegram: %s\n\nFiles are stored in /var/backups/zivpn",
+	"▶️ Backup Sekarang":                     "▶️ Back Up Now",
+	"📨 Telegram: ":                           "📨 Telegram: ",
+	"🕒 Ubah Jadwal":                          "🕒 Change Schedule",
+	"🗂️ Retensi":                             "🗂️ Retention",
+	"Gagal menyimpan: %s":                    "Failed to save: %s",
+	"⚙️ *Service Core*\nSilakan pilih aksi:": "⚙️ *Service Core*\nPlease choose an action:",
+	"📟 Status":                               "📟 Status",
+	"📜 Log":                                  "📜 Logs",
+	"🔄 Restart":                              "🔄 Restart",
+	"▶️ Start":                               "▶️ Start",
+	"⏹️ Stop":                                "⏹️ Stop",
+	"❓ Yakin ingin menghentikan zivpn.service? Semua user akan terputus.": "❓ Are you sure you want to stop zivpn.service? All users will be disconnected.",
+	"✅ Ya, Stop": "✅ Yes, Stop",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    STATUS ZIVPN\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSejak    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```": "```\n━━━━━━━━━━━━━━━━━━━━━\n    ZIVPN STATUS\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSince    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	"⏳ Menjalankan %s...":        "⏳ Running %s...",
+	"(log kosong)\n":             "(log is empty)\n",
+	"📜 Log zivpn.service\n```\n": "📜 zivpn.service logs\n```\n",
+	"🔐 Obfs saat ini: %s\nMasukkan obfs baru (1-64 karakter, tanpa spasi):": "🔐 Current obfs: %s\nEnter the new obfs (1-64 characters, no spaces):",
+	"⏳ Menerapkan config dan merestart service...":                          "⏳ Applying config and restarting the service...",
+	"✅ Obfs diubah menjadi `%s`. Client harus memakai obfs baru.":           "✅ Obfs changed to `%s`. Clients must use the new obfs.",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    MENU ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • Kota     : %s\n • ISP      : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\n👇 Silakan pilih menu dibawah ini:": "```\n━━━━━━━━━━━━━━━━━━━━━\n    ZIVPN UDP MENU\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • City     : %s\n • ISP      : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\n👇 Please choose from the menu below:",
+	"👤 Buat Password":       "👤 Create Password",
+	"🗑️ Hapus Password":     "🗑️ Delete Password",
+	"🔄 Perpanjang Password": "🔄 Renew Password",
+	"🔍 Cari":                "🔍 Search",
+	"📋 Daftar Password":     "📋 List Passwords",
+	"👥 Pengguna Bot":        "👥 Bot Users",
+	"📊 Info Sistem":         "📊 System Info",
+	"⚙️ Service Core":       "⚙️ Service Core",
+	"💾 Backup & Restore":    "💾 Backup & Restore",
+	"🔐 Mode: Privat":        "🔐 Mode: Private",
+	"🌍 Mode: Publik":        "🌍 Mode: Public",
+	"🔐 Ubah Obfs":           "🔐 Change Obfs",
+	"📏 Batas User":          "📏 User Limits",
+	"🛡️ Verifikasi User":    "🛡️ User Verification",
+	"👑 Kelola Staff":        "👑 Manage Staff",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    AKUN ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nKota       : %s\nISP        : %s\nIP ISP     : %s\nDomain     : %s\nMasa Aktif : %s\n━━━━━━━━━━━━━━━━━━━━━\n```": "```\n━━━━━━━━━━━━━━━━━━━━━\n  ZIVPN UDP ACCOUNT\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nCity       : %s\nISP        : %s\nIP ISP     : %s\nDomain     : %s\nExpires On : %s\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	"📇 Detail & QR":               "📇 Details & QR",
+	"📂 Anda belum memiliki akun.": "📂 You don't have any accounts yet.",
+	"Hapus":                       "Delete",
+	"Perpanjang":                  "Renew",
+	"📋 Pilih User untuk %s (Halaman %d/%d):":                       "📋 Choose a user to %s (page %d/%d):",
+	"❌ Password harus 3-20 karakter. Coba lagi:":                   "❌ The password must be 3-20 characters. Try again:",
+	"❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:":     "❌ The password may only contain letters, numbers, - and _. Try again:",
+	"❌ %s harus angka positif (%d-%d). Coba lagi:":                 "❌ %s must be a positive number (%d-%d). Try again:",
+	"⏰ Pemeriksaan expired: %d akun dicabut.\n%s":                  "⏰ Expiry check: %d accounts revoked.\n%s",
+	"\n⚠️ Sebagian akun gagal dicabut, cek log zivpn-api.":         "\n⚠️ Some accounts could not be revoked, check the zivpn-api log.",
+	"⚠️ Restart zivpn.service gagal: %v":                           "⚠️ Restarting zivpn.service failed: %v",
+	"⚠️ Backup otomatis gagal: %v":                                 "⚠️ Automatic backup failed: %v",
+	"🗓️ Backup otomatis ZiVPN":                                     "🗓️ ZiVPN automatic backup",
+	"🔐 Masukkan obfs baru (1-64 karakter, tanpa spasi):":           "🔐 Enter the new obfs (1-64 characters, no spaces):",
+	"📏 Masukkan batas baru (0 = tanpa batas):":                     "📏 Enter the new limit (0 = unlimited):",
+	"🕒 Masukkan jadwal cron, contoh \"0 3 * * *\", atau \"off\":":  "🕒 Enter a cron schedule, e.g. \"0 3 * * *\", or \"off\":",
+	"🗂️ Masukkan jumlah backup harian dan mingguan (contoh: 7 4):": "🗂️ Enter how many daily and weekly backups to keep (e.g. 7 4):",
+	"⬆️ Silakan kirim file backup (.zip atau .zip.enc).":           "⬆️ Please send the backup file (.zip or .zip.enc).",
+	"🔒 Kirim passphrase atau secret key (ZIVPNSEC1...):":           "🔒 Send the passphrase or secret key (ZIVPNSEC1...):",
+	"👑 Masukkan: <TelegramID> <role> [nama]":                       "👑 Enter: <TelegramID> <role> [name]",
+	"🔑 Masukkan password baru:":                                    "🔑 Enter the new password:",
+	"Maks akun aktif":                                              "Max active accounts",
+	"Maks hari per akun":                                           "Max days per account",
+	"Pembuatan per 24 jam":                                         "Creations per 24 hours",
+	"Jeda antar pembuatan (menit)":                                 "Delay between creations (minutes)",
+	"🍎 Apel":                                                       "🍎 Apple",
+	"🚗 Mobil":                                                      "🚗 Car",
+	"🐱 Kucing":                                                     "🐱 Cat",
+	"⭐ Bintang":                                                    "⭐ Star",
+	"🌙 Bulan":                                                      "🌙 Moon",
+	"🌲 Pohon":                                                      "🌲 Tree",
+	"🎈 Balon":                                                      "🎈 Balloon",
+	"🐟 Ikan":                                                       "🐟 Fish",
+	"Menu utama":                                                   "Main menu",
+	"<pass> <hari>":                                                "<pass> <days>",
+	"Buat akun":                                                    "Create an account",
+	"Perpanjang akun":                                              "Renew an account",
+	"<pass>":                                                       "<pass>",
+	"Hapus akun":                                                   "Delete an account",
+	"Detail akun":                                                  "Account details",
+	"Kunci akun":                                                   "Lock an account",
+	"Durasi":                                                       "Duration",
+	"Batas":                                                        "Limit",
+	"Silakan kirim input berikutnya.":                              "Please send the next input.",
+}
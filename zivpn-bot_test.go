@@ -0,0 +1,268 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMain(m *testing.M) {
+	// Recovered panics log a stack trace; keep test output readable
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func messageUpdate(id int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+// waitTimeout fails the test when wg is not done within a few seconds.
+func waitTimeout(t *testing.T, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the dispatcher")
+	}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	const perChat = 50
+	chats := []int64{1, 2, 3}
+
+	var mu sync.Mutex
+	seen := make(map[int64][]int)
+	var wg sync.WaitGroup
+	wg.Add(perChat * len(chats))
+
+	d := newDispatcher(4, func(update tgbotapi.Update) {
+		defer wg.Done()
+		time.Sleep(time.Duration(update.UpdateID%3) * time.Millisecond)
+		chatID := updateChatID(update)
+		mu.Lock()
+		seen[chatID] = append(seen[chatID], update.UpdateID)
+		mu.Unlock()
+	})
+	for i := 0; i < perChat; i++ {
+		for _, chatID := range chats {
+			d.Dispatch(messageUpdate(i, chatID))
+		}
+	}
+	waitTimeout(t, &wg)
+
+	for _, chatID := range chats {
+		ids := seen[chatID]
+		if len(ids) != perChat {
+			t.Fatalf("chat %d handled %d updates, want %d", chatID, len(ids), perChat)
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("chat %d handled %v, want arrival order", chatID, ids)
+			}
+		}
+	}
+	// drain forgets a chat right after its last handler returns
+	deadline := time.Now().Add(time.Second)
+	for {
+		d.mu.Lock()
+		left := len(d.pending)
+		d.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d chats still queued after their updates ran", left)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherBoundsWorkers(t *testing.T) {
+	const workers = 2
+	var mu sync.Mutex
+	running, peak := 0, 0
+	var wg sync.WaitGroup
+	wg.Add(6)
+
+	d := newDispatcher(workers, func(update tgbotapi.Update) {
+		defer wg.Done()
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	for chatID := int64(1); chatID <= 6; chatID++ {
+		d.Dispatch(messageUpdate(int(chatID), chatID))
+	}
+	waitTimeout(t, &wg)
+
+	if peak != workers {
+		t.Errorf("peak concurrency %d, want %d", peak, workers)
+	}
+}
+
+func TestDispatcherRecoversFromPanic(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	d := newDispatcher(1, func(update tgbotapi.Update) {
+		defer wg.Done()
+		if update.UpdateID == 1 {
+			panic("handler bug")
+		}
+		mu.Lock()
+		handled = append(handled, update.UpdateID)
+		mu.Unlock()
+	})
+	for i := 1; i <= 3; i++ {
+		d.Dispatch(messageUpdate(i, 42))
+	}
+	waitTimeout(t, &wg)
+
+	if len(handled) != 2 || handled[0] != 2 || handled[1] != 3 {
+		t.Errorf("handled %v after the panic, want [2 3]", handled)
+	}
+}
+
+func TestUpdateChatID(t *testing.T) {
+	user := &tgbotapi.User{ID: 7}
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		want   int64
+	}{
+		{"message", messageUpdate(1, 5), 5},
+		{"callback with message", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 9}}}}, 9},
+		{"inline callback", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user}}, 7},
+		{"inline query", tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{From: user}}, 7},
+		{"other", tgbotapi.Update{}, 0},
+	}
+	for _, tt := range tests {
+		if got := updateChatID(tt.update); got != tt.want {
+			t.Errorf("%s: updateChatID = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentLimitsIsACopy(t *testing.T) {
+	config := &BotConfig{Limits: &UserLimits{MaxAccounts: 2, MaxDays: 30}}
+
+	limits := currentLimits(config)
+	limits.MaxAccounts = 99
+	if config.Limits.MaxAccounts != 2 {
+		t.Errorf("editing the copy changed the config to %d", config.Limits.MaxAccounts)
+	}
+
+	// Readers and a writer holding configMu must not race
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = currentLimits(config).MaxDays
+				_ = publicMode(config)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		configMu.Lock()
+		config.Limits.MaxDays = j
+		config.Mode = "public"
+		configMu.Unlock()
+	}
+	wg.Wait()
+}
+
+func TestQuotaStoreWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	q := loadQuotaStore(path)
+	now := time.Now()
+	q.Creates[1] = []time.Time{now.Add(-25 * time.Hour), now.Add(-23 * time.Hour), now.Add(-time.Hour)}
+	q.Creates[2] = []time.Time{now.Add(-48 * time.Hour)}
+
+	if got := len(q.Usage(1)); got != 2 {
+		t.Errorf("user 1 has %d creations in the window, want 2", got)
+	}
+	if got := len(q.Usage(2)); got != 0 {
+		t.Errorf("user 2 has %d creations in the window, want 0", got)
+	}
+	if _, ok := q.Creates[2]; ok {
+		t.Error("user 2 with only expired creations was not forgotten")
+	}
+
+	q.Record(1)
+	reloaded := loadQuotaStore(path)
+	if got := len(reloaded.Usage(1)); got != 3 {
+		t.Errorf("after reload user 1 has %d creations, want 3", got)
+	}
+}
+
+func TestQuotaBlocked(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	limits := &UserLimits{MaxAccounts: 2, DailyCreates: 3, CooldownMinutes: 10}
+
+	tests := []struct {
+		name  string
+		quota Quota
+		want  string // substring of the refusal, "" when allowed
+	}{
+		{"fresh user", Quota{}, ""},
+		{"one active", Quota{Active: 1, Creates: []time.Time{ago(time.Hour)}}, ""},
+		{"max accounts", Quota{Active: 2}, "Batas akun aktif tercapai (2/2)"},
+		{"daily limit", Quota{Creates: []time.Time{ago(20*time.Hour - 30*time.Second), ago(5 * time.Hour), ago(time.Hour)}}, "Batas pembuatan harian tercapai (3/3). Coba lagi dalam 4 jam 0 menit"},
+		{"cooldown", Quota{Creates: []time.Time{ago(4 * time.Minute)}}, "Tunggu 6 menit"},
+		{"cooldown over", Quota{Creates: []time.Time{ago(11 * time.Minute)}}, ""},
+	}
+	for _, tt := range tests {
+		got := tt.quota.blocked(limits)
+		if tt.want == "" && got != "" {
+			t.Errorf("%s: blocked = %q, want allowed", tt.name, got)
+		}
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: blocked = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := (Quota{Active: 50, Creates: []time.Time{now}}).blocked(&UserLimits{}); got != "" {
+		t.Errorf("zero limits blocked with %q", got)
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "1 menit"},
+		{90 * time.Second, "2 menit"},
+		{59 * time.Minute, "59 menit"},
+		{time.Hour, "1 jam 0 menit"},
+		{23*time.Hour + 5*time.Minute, "23 jam 5 menit"},
+	}
+	for _, tt := range tests {
+		if got := formatWait(0, tt.d); got != tt.want {
+			t.Errorf("formatWait(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -1,24 +1,35 @@
 package main
 
 import (
-	"archive/zip"
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 // ==========================================
@@ -33,8 +44,26 @@ const (
 	PortFile	  = "/etc/zivpn/port"
 	WalletFile    = "/etc/zivpn/wallets.json"
 	MetricsFile   = "/etc/zivpn/metrics.json"
+	StateFile     = "/etc/zivpn/paid-bot-state.json"
+	UsersFile     = "/etc/zivpn/paid-bot-users.json"
+	LangDir       = "/etc/zivpn/lang"
+	LangTemplate  = "/etc/zivpn/lang/template-paid-bot.json"
 )
 
+// ZiVPN certificate reused for the webhook listener
+const (
+	CertFile             = "/etc/zivpn/zivpn.crt"
+	KeyFile              = "/etc/zivpn/zivpn.key"
+	DefaultWebhookListen = ":8443"
+	WebhookCheckRounds   = 5 // getWebhookInfo polls, 2s apart, after setWebhook
+)
+
+// EncryptedMagic prefixes backups encrypted by the API.
+const EncryptedMagic = "ZIVPN-ENC-1\n"
+
+// MaxRestoreSize matches the API's upload limit for /restore.
+const MaxRestoreSize = 20 << 20
+
 var ApiUrl = "http://127.0.0.1:" + PortFile + "/api"
 
 var ApiKey = "AutoFtBot-agskjgdvsbdreiWG1234512SDKrqw"
@@ -47,6 +76,14 @@ type BotConfig struct {
 	PakasirSlug    string `json:"pakasir_slug"`
 	PakasirApiKey  string `json:"pakasir_api_key"`
 	DailyPrice     int    `json:"daily_price"`
+	MetricsPort    int    `json:"metrics_port,omitempty"` // 0 disables /metrics
+	MemoryState    bool   `json:"memory_state,omitempty"` // do not persist conversation state to StateFile
+	Workers        int    `json:"workers,omitempty"`      // chats handled concurrently, 0 uses DefaultWorkers
+	Staff          []StaffMember       `json:"staff,omitempty"` // admin_id is the owner
+	Roles          map[string][]string `json:"roles,omitempty"` // extra or overridden roles
+	WebhookURL     string `json:"webhook_url,omitempty"`    // empty keeps long polling
+	WebhookListen  string `json:"webhook_listen,omitempty"` // default DefaultWebhookListen
+	WebhookSecret  string `json:"webhook_secret,omitempty"` // random per start when empty
 }
 
 type IpInfo struct {
@@ -58,6 +95,7 @@ type UserData struct {
 	Password string `json:"password"`
 	Expired  string `json:"expired"`
 	Status   string `json:"status"`
+	Owner    int64  `json:"owner"` // buyer's Telegram ID, 0 = admin-owned
 }
 
 type WalletEntry struct {
@@ -74,10 +112,26 @@ type WalletEntry struct {
 // Global State
 // ==========================================
 
-var userStates = make(map[int64]string)
-var tempUserData = make(map[int64]map[string]string)
-var lastMessageIDs = make(map[int64]int)
-var mutex = &sync.Mutex{}
+
+// Sales counters exposed on the optional Prometheus port
+type salesCounters struct {
+	mu              sync.Mutex
+	topups          int64
+	topupAmount     int64
+	purchases       map[string]int64
+	purchaseAmounts map[string]int64
+}
+
+var sales = &salesCounters{
+	purchases:       make(map[string]int64),
+	purchaseAmounts: make(map[string]int64),
+}
+
+// Serializes read-modify-write of WalletFile and MetricsFile across update workers
+var (
+	walletMu  sync.Mutex
+	metricsMu sync.Mutex
+)
 
 // ==========================================
 // Main Entry Point
@@ -99,6 +153,17 @@ func main() {
 		log.Fatal("Gagal memuat konfigurasi bot:", err)
 	}
 
+	// Restore in-progress flows and pending payments unless state is kept
+	// in memory only
+	if !config.MemoryState {
+		states = loadStateStore(StateFile)
+	}
+	go runStateJanitor()
+	botUsers = loadUserRegistry(UsersFile)
+	go botUsers.flushLoop()
+	loadCatalogs(LangDir)
+	writeCatalogTemplate(LangTemplate)
+
 	bot, err := tgbotapi.NewBotAPI(config.BotToken)
 	if err != nil {
 		log.Panic(err)
@@ -107,20 +172,260 @@ func main() {
 	bot.Debug = false
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	updates := startUpdates(bot, &config)
+	go registerCommands(bot, &config)
 
 	// Start Payment Checker
 	go startPaymentChecker(bot, &config)
 
+	// Notify admin about API events (expiry runs, failed restarts)
+	go watchApiEvents(bot, &config)
+
+	if config.MetricsPort > 0 {
+		go serveMetrics(config.MetricsPort)
+	}
+
+	dispatcher := newDispatcher(config.Workers, func(update tgbotapi.Update) {
+		handleUpdate(bot, update, &config)
+	})
 	for update := range updates {
-		if update.Message != nil {
-			handleMessage(bot, update.Message, &config)
-		} else if update.CallbackQuery != nil {
-			handleCallback(bot, update.CallbackQuery, &config)
+		dispatcher.Dispatch(update)
+	}
+}
+
+// ==========================================
+// Update Delivery (polling / webhook)
+// ==========================================
+
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// startUpdates uses the webhook when configured and falls back to long polling.
+func startUpdates(bot *tgbotapi.BotAPI, config *BotConfig) tgbotapi.UpdatesChannel {
+	if config.WebhookURL != "" {
+		updates, err := startWebhook(bot, config)
+		if err == nil {
+			return updates
+		}
+		log.Printf("Webhook tidak aktif, kembali ke polling: %v", err)
+	}
+
+	// getUpdates is refused while a webhook is still registered
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Gagal menghapus webhook: %v", err)
+	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	return bot.GetUpdatesChan(u)
+}
+
+func startWebhook(bot *tgbotapi.BotAPI, config *BotConfig) (tgbotapi.UpdatesChannel, error) {
+	link, err := url.Parse(config.WebhookURL)
+	if err != nil || link.Scheme != "https" || link.Host == "" {
+		return nil, fmt.Errorf("webhook_url harus berupa URL https")
+	}
+	secret := config.WebhookSecret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	} else if !webhookSecretPattern.MatchString(secret) {
+		return nil, fmt.Errorf("webhook_secret hanya boleh berisi A-Z, a-z, 0-9, _ dan -")
+	}
+
+	cert, err := tls.LoadX509KeyPair(CertFile, KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("sertifikat ZiVPN tidak valid: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("sertifikat ZiVPN tidak valid: %v", err)
+	}
+
+	listen := config.WebhookListen
+	if listen == "" {
+		listen = DefaultWebhookListen
+	}
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan tgbotapi.Update, 100)
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		update, err := bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates <- *update
+	})
+	server := &http.Server{
+		Handler:      mux,
+		TLSConfig:    &tls.Config{Certificates: []tls.Certificate{cert}},
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	params := tgbotapi.Params{
+		"url":          link.String(),
+		"secret_token": secret,
+	}
+	since := time.Now()
+	// Telegram only trusts a self-signed certificate when it is uploaded with setWebhook
+	if leaf.CheckSignatureFrom(leaf) == nil {
+		_, err = bot.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(CertFile),
+		}})
+	} else {
+		_, err = bot.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setWebhook gagal: %v", err)
+	}
+
+	go func() {
+		if err := server.ServeTLS(ln, "", ""); err != nil {
+			log.Printf("Webhook server berhenti: %v", err)
+		}
+	}()
+	if err := checkWebhook(bot, since); err != nil {
+		server.Close()
+		return nil, err
+	}
+	log.Printf("Webhook aktif di %s (listen %s)", link.String(), listen)
+	return updates, nil
+}
+
+// checkWebhook catches a webhook Telegram accepted but cannot deliver to,
+// such as a certificate it does not trust. Only errors newer than since
+// count, since getWebhookInfo keeps the last one from earlier runs.
+func checkWebhook(bot *tgbotapi.BotAPI, since time.Time) error {
+	for i := 0; i < WebhookCheckRounds; i++ {
+		time.Sleep(2 * time.Second)
+		info, err := bot.GetWebhookInfo()
+		if err != nil {
+			return fmt.Errorf("getWebhookInfo gagal: %v", err)
+		}
+		if info.LastErrorMessage != "" && int64(info.LastErrorDate) >= since.Unix() {
+			return fmt.Errorf("Telegram gagal mengirim ke webhook: %s", info.LastErrorMessage)
+		}
+		if info.PendingUpdateCount == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ==========================================
+// Update Dispatcher
+// ==========================================
+
+// DefaultWorkers bounds how many chats are served at the same time.
+const DefaultWorkers = 8
+
+// Dispatcher runs updates concurrently across chats while keeping the
+// updates of a single chat in arrival order.
+type Dispatcher struct {
+	mu      sync.Mutex
+	pending map[int64][]tgbotapi.Update // queued updates per busy chat
+	slots   chan struct{}
+	handle  func(tgbotapi.Update)
+}
+
+func newDispatcher(workers int, handle func(tgbotapi.Update)) *Dispatcher {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Dispatcher{
+		pending: make(map[int64][]tgbotapi.Update),
+		slots:   make(chan struct{}, workers),
+		handle:  handle,
+	}
+}
+
+// Dispatch queues the update behind any update still running for the same chat.
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) {
+	chatID := updateChatID(update)
+	d.mu.Lock()
+	if queue, busy := d.pending[chatID]; busy {
+		d.pending[chatID] = append(queue, update)
+		d.mu.Unlock()
+		return
+	}
+	d.pending[chatID] = nil
+	d.mu.Unlock()
+	go d.drain(chatID, update)
+}
+
+func (d *Dispatcher) drain(chatID int64, update tgbotapi.Update) {
+	d.slots <- struct{}{}
+	defer func() { <-d.slots }()
+	for {
+		d.run(update)
+		d.mu.Lock()
+		queue := d.pending[chatID]
+		if len(queue) == 0 {
+			delete(d.pending, chatID)
+			d.mu.Unlock()
+			return
 		}
+		update, d.pending[chatID] = queue[0], queue[1:]
+		d.mu.Unlock()
+	}
+}
+
+// run handles one update, recovering from panics so the bot keeps serving.
+func (d *Dispatcher) run(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic while handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(update)
+}
+
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From.ID
+	}
+	return 0
+}
+
+// handleUpdate routes one update and tells the user when a handler crashed.
+func handleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, config *BotConfig) {
+	defer func() {
+		if r := recover(); r != nil {
+			if chatID := updateChatID(update); chatID != 0 {
+				bot.Send(tgbotapi.NewMessage(chatID, tr(chatID, "❌ Terjadi kesalahan internal. Silakan coba lagi.")))
+			}
+			panic(r)
+		}
+	}()
+	botUsers.Touch(updateSender(update))
+	if update.Message != nil {
+		handleMessage(bot, update.Message, config)
+	} else if update.CallbackQuery != nil {
+		handleCallback(bot, update.CallbackQuery, config)
 	}
 }
 
@@ -132,26 +437,29 @@ func handleMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfi
 	// In Paid Bot, everyone can access, but actions are restricted/paid
 	// Admin still has full control
 
-	if state, exists := userStates[msg.From.ID]; exists {
-		handleState(bot, msg, state, config)
-		return
-	}
-
 	// Handle Document Upload (Restore) - Admin Only
-	if msg.Document != nil && msg.From.ID == config.AdminID {
-		if state, exists := userStates[msg.From.ID]; exists && state == "waiting_restore_file" {
+	if msg.Document != nil && can(config, msg.From.ID, PermRestore) {
+		if states.State(msg.From.ID) == "waiting_restore_file" {
 			processRestoreFile(bot, msg, config)
 			return
 		}
 	}
 
-	if msg.IsCommand() {
-		switch msg.Command() {
-		case "start":
-			showMainMenu(bot, msg.Chat.ID, config, msg.From.ID)
-		default:
-			replyError(bot, msg.Chat.ID, "Perintah tidak dikenal.")
+	if state := states.State(msg.From.ID); state != "" {
+		if msg.IsCommand() && msg.Command() == "start" {
+			offerResume(bot, msg.Chat.ID)
+			return
 		}
+		// Any other command abandons the flow in progress
+		if !msg.IsCommand() {
+			handleState(bot, msg, state, config)
+			return
+		}
+		resetState(msg.From.ID)
+	}
+
+	if msg.IsCommand() {
+		handleCommand(bot, msg, config)
 	}
 }
 
@@ -164,69 +472,211 @@ func handleCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery, config
 		startCreateUser(bot, chatID, userID)
 	case query.Data == "menu_info":
 		systemInfo(bot, chatID, config)
+	case query.Data == "menu_staff":
+		if isOwner(config, userID) {
+			showStaff(bot, chatID, config)
+		}
+	case query.Data == "staff_add":
+		if isOwner(config, userID) {
+			states.SetState(userID, "owner_staff_input")
+			sendMessage(bot, chatID, tr(chatID, "👑 Masukkan: <TelegramID> <role> [nama]\nContoh: 7251232303 support Budi\nRole: ")+strings.Join(roleNames(config), ", "))
+		}
+	case strings.HasPrefix(query.Data, "staff_remove:"):
+		if isOwner(config, userID) {
+			id, _ := strconv.ParseInt(strings.TrimPrefix(query.Data, "staff_remove:"), 10, 64)
+			if err := removeStaff(config, id); err != nil {
+				replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+				break
+			}
+			go dropCommands(bot, id)
+			showStaff(bot, chatID, config)
+		}
+	case query.Data == "menu_bot_users":
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			showBotUsers(bot, chatID, 1, "")
+		}
+	case strings.HasPrefix(query.Data, "botusers:"):
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			handleBotUsersPage(bot, chatID, query.Data)
+		}
+	case query.Data == "botusers_search":
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			states.SetState(userID, "admin_botuser_search")
+			sendMessage(bot, chatID, tr(chatID, "🔍 Masukkan ID, username atau nama pengguna:"))
+		}
 	case query.Data == "cancel":
 		cancelOperation(bot, chatID, userID, config)
+	case query.Data == "resume_flow":
+		resumeFlow(bot, chatID, userID)
+	case query.Data == "menu_lang":
+		showLanguageMenu(bot, chatID, userID)
+	case strings.HasPrefix(query.Data, "lang_set:"):
+		setLanguage(bot, chatID, userID, strings.TrimPrefix(query.Data, "lang_set:"), config)
 
 	// New Paid Menu handlers
 	case query.Data == "menu_trial":
 		startTrial(bot, chatID, userID)
 	case query.Data == "menu_renew":
 		startRenew(bot, chatID, userID)
+	case query.Data == "menu_my_accounts":
+		showMyAccounts(bot, chatID, userID)
+	case strings.HasPrefix(query.Data, "acct:"):
+		showAccountCard(bot, chatID, userID, strings.TrimPrefix(query.Data, "acct:"), config)
+	case strings.HasPrefix(query.Data, "acct_renew:"):
+		password := strings.TrimPrefix(query.Data, "acct_renew:")
+		if _, ok := findAccount(userID, password, config); !ok {
+			replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+			break
+		}
+		states.Reset(userID, map[string]string{
+			"password": password,
+			"chat_id":  strconv.FormatInt(chatID, 10),
+		})
+		states.SetState(userID, "renew_days")
+		sendMessage(bot, chatID, tr(chatID, "🔁 Perpanjang %s\n⏳ Masukkan Durasi Perpanjangan (hari):", password))
 	case query.Data == "menu_list":
 		listAccounts(bot, chatID)
 	case query.Data == "menu_topup":
 		startTopup(bot, chatID, userID)
 
 	case query.Data == "menu_admin":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, "") {
 			showBackupRestoreMenu(bot, chatID)
 		}
 	case query.Data == "menu_admin_manage":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, "") {
 			showAdminManageMenu(bot, chatID)
 		}
 	case query.Data == "admin_add_balance":
-		if userID == config.AdminID {
-			userStates[userID] = "admin_add_balance_input"
-			sendMessage(bot, chatID, "🟢 Masukkan TelegramID dan jumlah untuk ditambahkan (contoh: 7251232303 50000):")
+		if requirePerm(bot, chatID, config, userID, PermWalletAdjust) {
+			states.SetState(userID, "admin_add_balance_input")
+			sendMessage(bot, chatID, tr(chatID, "🟢 Masukkan TelegramID dan jumlah untuk ditambahkan (contoh: 7251232303 50000):"))
 		}
 	case query.Data == "admin_remove_balance":
-		if userID == config.AdminID {
-			userStates[userID] = "admin_remove_balance_input"
-			sendMessage(bot, chatID, "🔴 Masukkan TelegramID dan jumlah untuk dikurangkan (contoh: 7251232303 50000):")
+		if requirePerm(bot, chatID, config, userID, PermWalletAdjust) {
+			states.SetState(userID, "admin_remove_balance_input")
+			sendMessage(bot, chatID, tr(chatID, "🔴 Masukkan TelegramID dan jumlah untuk dikurangkan (contoh: 7251232303 50000):"))
 		}
 	case query.Data == "admin_ban":
-		if userID == config.AdminID {
-			userStates[userID] = "admin_ban_input"
-			sendMessage(bot, chatID, "⛔ Masukkan TelegramID untuk diban (contoh: 7251232303):")
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			states.SetState(userID, "admin_ban_input")
+			sendMessage(bot, chatID, tr(chatID, "⛔ Masukkan TelegramID untuk diban (contoh: 7251232303):"))
 		}
 	case query.Data == "admin_unban":
-		if userID == config.AdminID {
-			userStates[userID] = "admin_unban_input"
-			sendMessage(bot, chatID, "✅ Masukkan TelegramID untuk di-unban (contoh: 7251232303):")
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
+			states.SetState(userID, "admin_unban_input")
+			sendMessage(bot, chatID, tr(chatID, "✅ Masukkan TelegramID untuk di-unban (contoh: 7251232303):"))
 		}
 	case query.Data == "admin_view_activity":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, "") {
 			today, week, month, _ := computeMetrics()
-			sendMessage(bot, chatID, fmt.Sprintf("📈 Aktivitas: Hari ini %d • Minggu ini %d • Bulan ini %d", today, week, month))
+			sendMessage(bot, chatID, tr(chatID, "📈 Aktivitas: Hari ini %d • Minggu ini %d • Bulan ini %d", today, week, month))
+		}
+	case query.Data == "admin_broadcast":
+		if requirePerm(bot, chatID, config, userID, PermBroadcast) {
+			showBroadcastMenu(bot, chatID)
+		}
+	case strings.HasPrefix(query.Data, "bc_seg:"):
+		if requirePerm(bot, chatID, config, userID, PermBroadcast) {
+			segment := strings.TrimPrefix(query.Data, "bc_seg:")
+			if segment == "expiring" {
+				states.Reset(userID, map[string]string{"segment": segment})
+				states.SetState(userID, "admin_broadcast_days")
+				sendMessage(bot, chatID, tr(chatID, "⏳ Kirim ke pemilik akun yang expired dalam berapa hari? (1-365)"))
+				break
+			}
+			startBroadcastCompose(bot, chatID, userID, segment, 0)
+		}
+	case query.Data == "bc_send":
+		if requirePerm(bot, chatID, config, userID, PermBroadcast) {
+			startBroadcast(bot, chatID, userID)
+		}
+	case query.Data == "bc_cancel":
+		if requirePerm(bot, chatID, config, userID, PermBroadcast) {
+			stopBroadcast(bot, chatID)
 		}
 	case query.Data == "admin_forward_mode":
-		if userID == config.AdminID {
-			userStates[userID] = "admin_forward_mode"
-			sendMessage(bot, chatID, "📨 Silakan forward pesan dari pengguna ke chat ini. Setelah diterima, Anda akan diberi opsi tindakan.")
+		if requirePerm(bot, chatID, config, userID, PermBroadcast) {
+			states.SetState(userID, "admin_forward_mode")
+			sendMessage(bot, chatID, tr(chatID, "📨 Silakan forward pesan dari pengguna ke chat ini. Setelah diterima, Anda akan diberi opsi tindakan."))
 		}
 	case query.Data == "menu_admin_create_free":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermUsersManage) {
 			startAdminCreateFree(bot, chatID, userID, config)
 		}
 	case query.Data == "menu_backup_action":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
 			performBackup(bot, chatID)
 		}
 	case query.Data == "menu_restore_action":
-		if userID == config.AdminID {
+		if requirePerm(bot, chatID, config, userID, PermRestore) {
 			startRestore(bot, chatID, userID)
 		}
+	case query.Data == "restore_confirm":
+		if requirePerm(bot, chatID, config, userID, PermRestore) {
+			confirmRestore(bot, chatID, userID, config)
+		}
+	case query.Data == "restore_cancel":
+		cancelRestore(bot, chatID, userID, config)
+	case query.Data == "menu_backup_encryption":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			showBackupEncryption(bot, chatID)
+		}
+	case query.Data == "backup_pass_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_passphrase")
+			sendMessage(bot, chatID, tr(chatID, "🔑 Masukkan passphrase backup baru (minimal 8 karakter):"))
+		}
+	case query.Data == "backup_pass_clear":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			setBackupPassphrase(bot, chatID, "")
+		}
+	case query.Data == "menu_backup_schedule":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			showBackupSchedule(bot, chatID)
+		}
+	case query.Data == "backup_run_now":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			runBackupNow(bot, chatID)
+		}
+	case query.Data == "backup_tg_toggle":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			toggleBackupTelegram(bot, chatID)
+		}
+	case query.Data == "backup_schedule_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_schedule")
+			sendMessage(bot, chatID, tr(chatID, "🕒 Masukkan jadwal cron (menit jam tanggal bulan hari), contoh \"0 3 * * *\" untuk setiap hari jam 03:00.\nKetik \"off\" untuk mematikan."))
+		}
+	case query.Data == "backup_keep_set":
+		if requirePerm(bot, chatID, config, userID, PermBackup) {
+			states.SetState(userID, "admin_backup_keep")
+			sendMessage(bot, chatID, tr(chatID, "🗂️ Masukkan jumlah backup harian dan mingguan yang disimpan (contoh: 7 4):"))
+		}
+	case query.Data == "menu_service":
+		if requirePerm(bot, chatID, config, userID, "") {
+			showServiceMenu(bot, chatID)
+		}
+	case query.Data == "svc_status":
+		if requirePerm(bot, chatID, config, userID, "") {
+			serviceStatus(bot, chatID)
+		}
+	case query.Data == "svc_logs":
+		if requirePerm(bot, chatID, config, userID, "") {
+			serviceLogs(bot, chatID)
+		}
+	case query.Data == "svc_restart", query.Data == "svc_start", query.Data == "svc_stop_confirm":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			serviceAction(bot, chatID, strings.TrimSuffix(strings.TrimPrefix(query.Data, "svc_"), "_confirm"))
+		}
+	case query.Data == "svc_stop":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			confirmServiceStop(bot, chatID)
+		}
+	case query.Data == "menu_obfs":
+		if requirePerm(bot, chatID, config, userID, PermConfigEdit) {
+			startEditObfs(bot, chatID, userID)
+		}
 	}
 
 	bot.Request(tgbotapi.NewCallback(query.ID, ""))
@@ -242,28 +692,24 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		if !validatePassword(bot, chatID, text) {
 			return
 		}
-		mutex.Lock()
-		tempUserData[userID]["password"] = text
-		mutex.Unlock()
-		userStates[userID] = "create_days"
-		sendMessage(bot, chatID, fmt.Sprintf("⏳ Masukkan Durasi (hari)\nHarga: Rp %d / hari:", config.DailyPrice))
+		states.Set(userID, "password", text)
+		states.SetState(userID, "create_days")
+		sendMessage(bot, chatID, tr(chatID, "⏳ Masukkan Durasi (hari)\nHarga: Rp %d / hari:", config.DailyPrice))
 
 	case "create_days":
 		days, ok := validateNumber(bot, chatID, text, 1, 365, "Durasi")
 		if !ok {
 			return
 		}
-		mutex.Lock()
-		tempUserData[userID]["days"] = text
-		mutex.Unlock()
+		states.Set(userID, "days", text)
 
 		// Create account via balance deduction (Topup model)
 		required := days * config.DailyPrice
 		balance := getBalance(userID)
 		if balance < required {
-			sendMessage(bot, chatID, fmt.Sprintf("⚠️ Saldo Anda: Rp %d. Diperlukan Rp %d untuk membuat akun %d hari. Silakan Topup minimal Rp 5000.", balance, required, days))
+			sendMessage(bot, chatID, tr(chatID, "⚠️ Saldo Anda: Rp %d. Diperlukan Rp %d untuk membuat akun %d hari. Silakan Topup minimal Rp 5000.", balance, required, days))
 			// Store attempted purchase in wallet so it can be completed after topup
-			if err := setPendingPurchase(userID, tempUserData[userID]["password"], days); err != nil {
+			if err := setPendingPurchase(userID, states.Get(userID, "password"), days); err != nil {
 				log.Printf("Failed to set pending purchase for %d: %v", userID, err)
 			}
 			resetState(userID)
@@ -271,15 +717,24 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		}
 
 		// Deduct and create
-		if err := deductBalance(userID, required); err != nil {
-			replyError(bot, chatID, "Gagal memproses saldo: "+err.Error())
+		paid, err := deductIfSufficient(userID, required)
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal memproses saldo: ")+err.Error())
+			resetState(userID)
+			return
+		}
+		if !paid {
+			sendMessage(bot, chatID, tr(chatID, "⚠️ Saldo tidak mencukupi. Diperlukan Rp %d. Silakan Topup minimal Rp 5000.", required))
 			resetState(userID)
 			return
 		}
-		password := tempUserData[userID]["password"]
-		createUser(bot, chatID, userID, password, days, config)
-		delete(tempUserData, userID)
-		delete(userStates, userID)
+		password := states.Get(userID, "password")
+		if createUser(bot, chatID, userID, password, days, config) {
+			recordPurchase("create", required)
+		} else {
+			refundPurchase(bot, chatID, userID, required)
+		}
+		states.Clear(userID)
     
 	// Trial flow: ask for password, then create with 1 day
 	case "trial_password":
@@ -287,11 +742,11 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 			return
 		}
 		// Enforce trial policy: if user has zero balance, allow only once
-		// Admin users are allowed unlimited trials
-		if msg.From.ID != config.AdminID {
+		// Staff are allowed unlimited trials
+		if !isStaff(config, msg.From.ID) {
 			if getBalance(userID) == 0 {
 				if hasUsedTrial(userID) {
-					sendMessage(bot, chatID, "⚠️ Anda sudah menggunakan trial. Untuk mendapatkan trial lagi, silakan Topup minimal Rp 5000.")
+					sendMessage(bot, chatID, tr(chatID, "⚠️ Anda sudah menggunakan trial. Untuk mendapatkan trial lagi, silakan Topup minimal Rp 5000."))
 					resetState(userID)
 					return
 				}
@@ -304,7 +759,7 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 			"days":     1,
 		})
 		if err != nil {
-			replyError(bot, chatID, "Error API: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 			resetState(userID)
 			return
 		}
@@ -318,35 +773,34 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 			sendAccountInfo(bot, chatID, data, config)
 		} else {
 			resetState(userID)
-			replyError(bot, chatID, fmt.Sprintf("Gagal membuat trial: %s", res["message"]))
+			replyError(bot, chatID, tr(chatID, "Gagal membuat trial: %s", res["message"]))
 		}
 
 	// Renew flow
 	case "renew_password":
-		mutex.Lock()
-		tempUserData[userID] = make(map[string]string)
-		tempUserData[userID]["password"] = text
-		tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-		mutex.Unlock()
-		userStates[userID] = "renew_days"
-		sendMessage(bot, chatID, "⏳ Masukkan Durasi Perpanjangan (hari):")
+		states.Reset(userID, map[string]string{
+			"password": text,
+			"chat_id":  strconv.FormatInt(chatID, 10),
+		})
+		states.SetState(userID, "renew_days")
+		sendMessage(bot, chatID, tr(chatID, "⏳ Masukkan Durasi Perpanjangan (hari):"))
 
 	case "renew_days":
 		days, ok := validateNumber(bot, chatID, text, 1, 3650, "Durasi")
 		if !ok {
 			return
 		}
-		pwd := tempUserData[userID]["password"]
+		pwd := states.Get(userID, "password")
 		// Renew via balance deduction
 		required := days * config.DailyPrice
-		if getBalance(userID) < required {
-			sendMessage(bot, chatID, fmt.Sprintf("⚠️ Saldo tidak mencukupi. Diperlukan Rp %d. Silakan Topup minimal Rp 5000.", required))
+		paid, err := deductIfSufficient(userID, required)
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal memproses saldo: ")+err.Error())
 			resetState(userID)
 			return
 		}
-		// Deduct and call renew API
-		if err := deductBalance(userID, required); err != nil {
-			replyError(bot, chatID, "Gagal memproses saldo: "+err.Error())
+		if !paid {
+			sendMessage(bot, chatID, tr(chatID, "⚠️ Saldo tidak mencukupi. Diperlukan Rp %d. Silakan Topup minimal Rp 5000.", required))
 			resetState(userID)
 			return
 		}
@@ -356,38 +810,40 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		})
 		resetState(userID)
 		if err != nil {
-			replyError(bot, chatID, "Error API: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+			refundPurchase(bot, chatID, userID, required)
 			return
 		}
 		if res["success"] == true {
+			recordPurchase("renew", required)
 			data := res["data"].(map[string]interface{})
-			sendMessage(bot, chatID, fmt.Sprintf("✅ User %s berhasil diperpanjang. Expired: %s\nSaldo tersisa: Rp %d", data["password"], data["expired"], getBalance(userID)))
+			sendMessage(bot, chatID, tr(chatID, "✅ User %s berhasil diperpanjang. Expired: %s\nSaldo tersisa: Rp %d", data["password"], data["expired"], getBalance(userID)))
 			showMainMenu(bot, chatID, config, userID)
 		} else {
-			replyError(bot, chatID, fmt.Sprintf("Gagal memperpanjang: %s", res["message"]))
+			replyError(bot, chatID, tr(chatID, "Gagal memperpanjang: %s", res["message"]))
+			refundPurchase(bot, chatID, userID, required)
 		}
 
 	// Admin create free flow
 	case "admin_create_password":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermUsersManage) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
 		if !validatePassword(bot, chatID, text) {
 			return
 		}
-		mutex.Lock()
-		tempUserData[userID] = make(map[string]string)
-		tempUserData[userID]["password"] = text
-		tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-		mutex.Unlock()
-		userStates[userID] = "admin_create_days"
-		sendMessage(bot, chatID, "⏳ Masukkan Durasi (hari) untuk akun gratis:")
+		states.Reset(userID, map[string]string{
+			"password": text,
+			"chat_id":  strconv.FormatInt(chatID, 10),
+		})
+		states.SetState(userID, "admin_create_days")
+		sendMessage(bot, chatID, tr(chatID, "⏳ Masukkan Durasi (hari) untuk akun gratis:"))
 
 	case "admin_create_days":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermUsersManage) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
@@ -395,86 +851,75 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		if !ok {
 			return
 		}
-		pwd := tempUserData[userID]["password"]
-		res, err := apiCall("POST", "/user/create", map[string]interface{}{
-			"password": pwd,
-			"days":     days,
-		})
+		pwd := states.Get(userID, "password")
 		resetState(userID)
-		if err != nil {
-			replyError(bot, chatID, "Error API: "+err.Error())
-			return
-		}
-		if res["success"] == true {
-			data := res["data"].(map[string]interface{})
-			sendMessage(bot, chatID, fmt.Sprintf("✅ Akun gratis dibuat: %s\nExpired: %s", data["password"], data["expired"]))
+		if createFreeAccount(bot, chatID, userID, pwd, days, config) {
 			showMainMenu(bot, chatID, config, userID)
-		} else {
-			replyError(bot, chatID, fmt.Sprintf("Gagal membuat akun: %s", res["message"]))
 		}
 
 	case "admin_add_balance_input":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermWalletAdjust) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
 		parts := strings.Fields(text)
 		if len(parts) != 2 {
-			sendMessage(bot, chatID, "Format salah. Contoh: 7251232303 50000")
+			sendMessage(bot, chatID, tr(chatID, "Format salah. Contoh: 7251232303 50000"))
 			return
 		}
 		tid, err1 := strconv.ParseInt(parts[0], 10, 64)
 		amt, err2 := strconv.Atoi(parts[1])
 		if err1 != nil || err2 != nil {
-			sendMessage(bot, chatID, "ID atau jumlah tidak valid.")
+			sendMessage(bot, chatID, tr(chatID, "ID atau jumlah tidak valid."))
 			resetState(userID)
 			return
 		}
 		if err := addBalance(tid, amt); err != nil {
-			replyError(bot, chatID, "Gagal menambah saldo: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Gagal menambah saldo: ")+err.Error())
 		} else {
-			sendMessage(bot, chatID, fmt.Sprintf("✅ Berhasil menambah Rp %d ke user %d", amt, tid))
+			sendMessage(bot, chatID, tr(chatID, "✅ Berhasil menambah Rp %d ke user %d", amt, tid))
 		}
 		resetState(userID)
 
 	case "admin_remove_balance_input":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermWalletAdjust) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
 		parts := strings.Fields(text)
 		if len(parts) != 2 {
-			sendMessage(bot, chatID, "Format salah. Contoh: 7251232303 50000")
+			sendMessage(bot, chatID, tr(chatID, "Format salah. Contoh: 7251232303 50000"))
 			return
 		}
 		tid, err1 := strconv.ParseInt(parts[0], 10, 64)
 		amt, err2 := strconv.Atoi(parts[1])
 		if err1 != nil || err2 != nil {
-			sendMessage(bot, chatID, "ID atau jumlah tidak valid.")
+			sendMessage(bot, chatID, tr(chatID, "ID atau jumlah tidak valid."))
 			resetState(userID)
 			return
 		}
 		if err := deductBalance(tid, amt); err != nil {
-			replyError(bot, chatID, "Gagal mengurangi saldo: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Gagal mengurangi saldo: ")+err.Error())
 		} else {
-			sendMessage(bot, chatID, fmt.Sprintf("✅ Berhasil mengurangi Rp %d dari user %d", amt, tid))
+			sendMessage(bot, chatID, tr(chatID, "✅ Berhasil mengurangi Rp %d dari user %d", amt, tid))
 		}
 		resetState(userID)
 
 	case "admin_ban_input":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermUsersManage) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
 		tid, err := strconv.ParseInt(text, 10, 64)
 		if err != nil {
-			sendMessage(bot, chatID, "TelegramID tidak valid.")
+			sendMessage(bot, chatID, tr(chatID, "TelegramID tidak valid."))
 			resetState(userID)
 			return
 		}
+		walletMu.Lock()
 		wallets, _ := loadWallets()
 		idx := getWalletIndex(wallets, tid)
 		if idx == -1 {
@@ -483,61 +928,95 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 			wallets[idx].Banned = true
 		}
 		saveWallets(wallets)
-		sendMessage(bot, chatID, fmt.Sprintf("⛔ User %d telah diban.", tid))
+		walletMu.Unlock()
+		sendMessage(bot, chatID, tr(chatID, "⛔ User %d telah diban.", tid))
 		resetState(userID)
 
 	case "admin_unban_input":
-		if msg.From.ID != config.AdminID {
-			replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+		if !can(config, userID, PermUsersManage) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 			resetState(userID)
 			return
 		}
 		tid, err := strconv.ParseInt(text, 10, 64)
 		if err != nil {
-			sendMessage(bot, chatID, "TelegramID tidak valid.")
+			sendMessage(bot, chatID, tr(chatID, "TelegramID tidak valid."))
 			resetState(userID)
 			return
 		}
+		walletMu.Lock()
 		wallets, _ := loadWallets()
 		idx := getWalletIndex(wallets, tid)
 		if idx != -1 {
 			wallets[idx].Banned = false
 			saveWallets(wallets)
-			sendMessage(bot, chatID, fmt.Sprintf("✅ User %d telah di-unban.", tid))
+		}
+		walletMu.Unlock()
+		if idx != -1 {
+			sendMessage(bot, chatID, tr(chatID, "✅ User %d telah di-unban.", tid))
 		} else {
-			sendMessage(bot, chatID, "User tidak ditemukan di wallet.")
+			sendMessage(bot, chatID, tr(chatID, "User tidak ditemukan di wallet."))
+		}
+		resetState(userID)
+
+	case "admin_botuser_search":
+		if !can(config, userID, PermUsersManage) {
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		// Callback data is capped at 64 bytes and carries the query
+		for len(text) > 40 {
+			_, size := utf8.DecodeLastRuneInString(text)
+			text = text[:len(text)-size]
+		}
+		showBotUsers(bot, chatID, 1, strings.ReplaceAll(text, ":", ""))
+
+	case "admin_broadcast_days":
+		if !can(config, userID, PermBroadcast) {
+			resetState(userID)
+			return
+		}
+		days, ok := validateNumber(bot, chatID, text, 1, 365, "Jumlah hari")
+		if !ok {
+			return
 		}
 		resetState(userID)
+		startBroadcastCompose(bot, chatID, userID, "expiring", days)
+
+	case "admin_broadcast_content":
+		if !can(config, userID, PermBroadcast) {
+			resetState(userID)
+			return
+		}
+		confirmBroadcast(bot, msg, userID)
 
 	case "admin_forward_mode":
 		// Expect a forwarded message from admin
 		if msg.ForwardFrom == nil {
-			sendMessage(bot, chatID, "Silakan forward pesan dari pengguna yang ingin Anda tindak lanjuti.")
+			sendMessage(bot, chatID, tr(chatID, "Silakan forward pesan dari pengguna yang ingin Anda tindak lanjuti."))
 			return
 		}
 		orig := msg.ForwardFrom.ID
 		// store target and any text
-		mutex.Lock()
-		tempUserData[userID] = make(map[string]string)
-		tempUserData[userID]["forward_target"] = strconv.FormatInt(orig, 10)
+		states.Reset(userID, map[string]string{"forward_target": strconv.FormatInt(orig, 10)})
 		if msg.Text != "" {
-			tempUserData[userID]["forward_text"] = msg.Text
+			states.Set(userID, "forward_text", msg.Text)
 		} else if msg.Caption != "" {
-			tempUserData[userID]["forward_text"] = msg.Caption
+			states.Set(userID, "forward_text", msg.Caption)
 		}
-		mutex.Unlock()
 		// prompt admin to type a message to send to the user
-		userStates[userID] = "admin_forward_compose"
-		sendMessage(bot, chatID, fmt.Sprintf("Forward diterima dari %d. Ketik pesan yang ingin Anda kirim ke pengguna ini:", orig))
+		states.SetState(userID, "admin_forward_compose")
+		sendMessage(bot, chatID, tr(chatID, "Forward diterima dari %d. Ketik pesan yang ingin Anda kirim ke pengguna ini:", orig))
 
 	case "admin_forward_compose":
 		// admin types message to forward to previously forwarded user
 		targetStr := ""
-		if tmp, ok := tempUserData[userID]["forward_target"]; ok {
+		if tmp := states.Get(userID, "forward_target"); tmp != "" {
 			targetStr = tmp
 		}
 		if targetStr == "" {
-			sendMessage(bot, chatID, "Target tidak ditemukan. Mulai ulang mode forward.")
+			sendMessage(bot, chatID, tr(chatID, "Target tidak ditemukan. Mulai ulang mode forward."))
 			resetState(userID)
 			return
 		}
@@ -545,139 +1024,1913 @@ func handleState(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state string, conf
 		// send the admin's text to the target user
 		pm := tgbotapi.NewMessage(tid, text)
 		if _, err := bot.Send(pm); err != nil {
-			replyError(bot, chatID, "Gagal mengirim pesan ke user: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Gagal mengirim pesan ke user: ")+err.Error())
 		} else {
-			sendMessage(bot, chatID, fmt.Sprintf("✅ Pesan terkirim ke %d", tid))
+			sendMessage(bot, chatID, tr(chatID, "✅ Pesan terkirim ke %d", tid))
+		}
+		states.ClearData(userID)
+		resetState(userID)
+
+	case "owner_staff_input":
+		if !isOwner(config, userID) {
+			resetState(userID)
+			return
+		}
+		parts := strings.Fields(text)
+		if len(parts) < 2 {
+			sendMessage(bot, chatID, tr(chatID, "❌ Format salah. Contoh: 7251232303 support Budi"))
+			return
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id == config.AdminID {
+			sendMessage(bot, chatID, tr(chatID, "❌ Telegram ID tidak valid. Coba lagi:"))
+			return
+		}
+		if _, ok := rolePermissions(config, parts[1]); !ok {
+			sendMessage(bot, chatID, tr(chatID, "❌ Role tidak dikenal. Pilih: ")+strings.Join(roleNames(config), ", "))
+			return
+		}
+		resetState(userID)
+		if err := setStaff(config, StaffMember{ID: id, Role: parts[1], Name: strings.Join(parts[2:], " ")}); err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal menyimpan config: ")+err.Error())
+			return
+		}
+		go setCommands(bot, tgbotapi.NewBotCommandScopeChat(id), "", commandsFor(config, id, userLanguage(id)))
+		showStaff(bot, chatID, config)
+
+	case "admin_obfs_input":
+		if !can(config, userID, PermConfigEdit) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		updateObfs(bot, chatID, text)
+
+	case "admin_backup_passphrase":
+		if !can(config, userID, PermBackup) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+			resetState(userID)
+			return
+		}
+		resetState(userID)
+		bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+		setBackupPassphrase(bot, chatID, text)
+
+	case "waiting_restore_secret":
+		if !can(config, userID, PermRestore) {
+			resetState(userID)
+			return
+		}
+		bot.Request(tgbotapi.NewDeleteMessage(chatID, msg.MessageID))
+		previewRestore(bot, chatID, userID, text)
+
+	case "admin_backup_schedule":
+		if !can(config, userID, PermBackup) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+			resetState(userID)
+			return
 		}
-		delete(tempUserData, userID)
 		resetState(userID)
+		if strings.EqualFold(text, "off") {
+			text = ""
+		}
+		updateBackupSettings(bot, chatID, map[string]interface{}{"schedule": text})
+
+	case "admin_backup_keep":
+		if !can(config, userID, PermBackup) {
+			replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+			resetState(userID)
+			return
+		}
+		parts := strings.Fields(text)
+		if len(parts) != 2 {
+			sendMessage(bot, chatID, tr(chatID, "❌ Format salah. Contoh: 7 4"))
+			return
+		}
+		daily, err1 := strconv.Atoi(parts[0])
+		weekly, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			sendMessage(bot, chatID, tr(chatID, "❌ Jumlah harus angka. Contoh: 7 4"))
+			return
+		}
+		resetState(userID)
+		updateBackupSettings(bot, chatID, map[string]interface{}{"keep_daily": daily, "keep_weekly": weekly})
 
 	case "topup_amount":
 		// parse amount
 		amt, err := strconv.Atoi(text)
 		if err != nil || amt < 5000 {
-			sendMessage(bot, chatID, "❌ Jumlah tidak valid. Minimal topup adalah Rp 5000. Silakan coba lagi:")
+			sendMessage(bot, chatID, tr(chatID, "❌ Jumlah tidak valid. Minimal topup adalah Rp 5000. Silakan coba lagi:"))
 			return
 		}
 		// create pakasir order
 		orderID := fmt.Sprintf("ZIVPN-TOPUP-%d-%d", userID, time.Now().Unix())
 		payment, err := createPakasirTransaction(config, orderID, amt)
 		if err != nil {
-			replyError(bot, chatID, "Gagal membuat transaksi topup: "+err.Error())
+			replyError(bot, chatID, tr(chatID, "Gagal membuat transaksi topup: ")+err.Error())
 			resetState(userID)
 			return
 		}
 		// store order for checking
-		mutex.Lock()
-		tempUserData[userID]["order_id"] = orderID
-		tempUserData[userID]["price"] = strconv.Itoa(amt)
-		tempUserData[userID]["action"] = "topup"
-		mutex.Unlock()
+		states.SetPayment(userID, PendingPayment{
+			OrderID: orderID,
+			Price:   amt,
+			Action:  "topup",
+			ChatID:  chatID,
+		})
 
 		qrUrl := fmt.Sprintf("https://api.qrserver.com/v1/create-qr-code/?size=300x300&data=%s", payment.PaymentNumber)
-		msgText := fmt.Sprintf("💳 **Topup Saldo**\nJumlah: Rp %d\nSilakan scan QRIS di bawah untuk melakukan pembayaran.\nExpired: %s", amt, payment.ExpiredAt)
+		msgText := tr(chatID, "💳 **Topup Saldo**\nJumlah: Rp %d\nSilakan scan QRIS di bawah untuk melakukan pembayaran.\nExpired: %s", amt, payment.ExpiredAt)
 		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(qrUrl))
 		photo.Caption = msgText
 		photo.ParseMode = "Markdown"
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "cancel"),
+				tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
 			),
 		)
 		photo.ReplyMarkup = keyboard
 		deleteLastMessage(bot, chatID)
 		sentMsg, err := bot.Send(photo)
 		if err == nil {
-			lastMessageIDs[chatID] = sentMsg.MessageID
+			states.SetLastMessage(chatID, sentMsg.MessageID)
 		}
-		delete(userStates, userID)
+		states.ClearState(userID)
 	}
 }
 
 // ==========================================
-// Feature Implementation
+// Conversation State
 // ==========================================
 
-func startCreateUser(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "create_password"
-	mutex.Lock()
-	tempUserData[userID] = make(map[string]string)
-	tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-	mutex.Unlock()
-	sendMessage(bot, chatID, "👤 Masukkan Password Baru:")
+// StatePrompts is repeated when a user resumes an unfinished flow.
+var StatePrompts = map[string]string{
+	"create_password":            "👤 Masukkan Password Baru:",
+	"create_days":                "⏳ Masukkan Durasi (hari):",
+	"trial_password":             "🆓 Trial Akun\nSilakan masukkan password yang diinginkan (3-20 karakter):",
+	"renew_password":             "🔁 Perpanjang Akun\nSilakan masukkan password akun yang ingin diperpanjang:",
+	"renew_days":                 "⏳ Masukkan Durasi Perpanjangan (hari):",
+	"admin_create_password":      "➕ Buat Akun Gratis\nMasukkan password untuk akun baru (3-20 karakter):",
+	"admin_create_days":          "⏳ Masukkan Durasi (hari) untuk akun gratis:",
+	"topup_amount":               "💳 Topup Saldo\nMasukkan jumlah topup minimal Rp 5000 (contoh: 5000):",
+	"admin_add_balance_input":    "🟢 Masukkan TelegramID dan jumlah untuk ditambahkan (contoh: 7251232303 50000):",
+	"admin_remove_balance_input": "🔴 Masukkan TelegramID dan jumlah untuk dikurangkan (contoh: 7251232303 50000):",
+	"admin_ban_input":            "⛔ Masukkan TelegramID untuk diban (contoh: 7251232303):",
+	"admin_unban_input":          "✅ Masukkan TelegramID untuk di-unban (contoh: 7251232303):",
+	"admin_forward_mode":         "📨 Silakan forward pesan dari pengguna ke chat ini.",
+	"admin_forward_compose":      "Ketik pesan yang ingin Anda kirim ke pengguna:",
+	"admin_obfs_input":           "🔐 Masukkan obfs baru (1-64 karakter, tanpa spasi):",
+	"admin_backup_passphrase":    "🔑 Masukkan passphrase backup baru (minimal 8 karakter):",
+	"admin_backup_schedule":      "🕒 Masukkan jadwal cron, contoh \"0 3 * * *\", atau \"off\":",
+	"admin_backup_keep":          "🗂️ Masukkan jumlah backup harian dan mingguan (contoh: 7 4):",
+	"waiting_restore_file":       "⬆️ Silakan kirim file backup (.zip atau .zip.enc).",
+	"waiting_restore_secret":     "🔒 Kirim passphrase atau secret key (ZIVPNSEC1...):",
+	"owner_staff_input":          "👑 Masukkan: <TelegramID> <role> [nama]",
+	"admin_broadcast_days":       "⏳ Kirim ke pemilik akun yang expired dalam berapa hari? (1-365)",
+	"admin_broadcast_content":    "📣 Kirim pesan yang akan disiarkan: teks, foto atau dokumen.",
+	"admin_botuser_search":       "🔍 Masukkan ID, username atau nama pengguna:",
 }
 
-func processPayment(bot *tgbotapi.BotAPI, chatID int64, userID int64, days int, config *BotConfig) {
-	// Now handled by wallet topup model. This function is deprecated.
-	sendMessage(bot, chatID, "Fitur pembayaran langsung sudah digantikan oleh sistem Topup. Silakan gunakan menu Topup Saldo terlebih dahulu jika saldo tidak mencukupi.")
+// DefaultStateTTL is how long an unfinished flow is kept before it expires.
+const DefaultStateTTL = 30 * time.Minute
+
+// StateTTLs overrides DefaultStateTTL for individual states.
+var StateTTLs = map[string]time.Duration{
+	"waiting_restore_file":   10 * time.Minute,
+	"waiting_restore_secret": 10 * time.Minute,
 }
 
-func startPaymentChecker(bot *tgbotapi.BotAPI, config *BotConfig) {
-	ticker := time.NewTicker(1 * time.Minute)
-	for range ticker.C {
-		mutex.Lock()
-		for userID, data := range tempUserData {
-			if orderID, ok := data["order_id"]; ok {
-				price := data["price"]
-				chatID, _ := strconv.ParseInt(data["chat_id"], 10, 64)
-				// Determine action (topup or account_purchase)
-				action := data["action"]
-				status, err := checkPakasirStatus(config, orderID, price)
-				if err == nil && (status == "completed" || status == "success") {
-					if action == "topup" {
-						// Add balance to user's wallet
-						amt, _ := strconv.Atoi(price)
-						addBalance(userID, amt)
-						current := getBalance(userID)
-						sendMessage(bot, chatID, fmt.Sprintf("✅ Topup berhasil: Rp %d. Saldo Anda saat ini: Rp %d", amt, current))
-						// If there is a pending purchase, try to complete it
-						wallets, _ := loadWallets()
-						idx := getWalletIndex(wallets, userID)
-						if idx != -1 && wallets[idx].PendingPassword != "" && wallets[idx].PendingDays > 0 {
-							required := wallets[idx].PendingDays * config.DailyPrice
-							if current >= required {
-								// deduct and create account
-								deductBalance(userID, required)
-								pw := wallets[idx].PendingPassword
-								doDays := wallets[idx].PendingDays
-								clearPendingPurchase(userID)
-								createUser(bot, chatID, userID, pw, doDays, config)
-								sendMessage(bot, chatID, fmt.Sprintf("✅ Pembelian otomatis selesai. Akun dibuat. Saldo tersisa: Rp %d", getBalance(userID)))
-							}
-						}
-					} else if action == "buy_account" {
-						password := data["password"]
-						days, _ := strconv.Atoi(data["days"])
-						// Deduct balance and create account
-						required := days * config.DailyPrice
-						if getBalance(userID) >= required {
-							deductBalance(userID, required)
-							// tempUserData stores strings, so password is already a string
-							createUser(bot, chatID, userID, password, days, config)
-						} else {
-							sendMessage(bot, chatID, "Pembayaran berhasil, tetapi saldo tidak mencukupi untuk pemotongan. Silakan hubungi admin.")
-						}
-					}
-					delete(tempUserData, userID)
-					delete(userStates, userID)
-				} else if err != nil {
-					log.Printf("Error checking payment for %d: %v", userID, err)
-				}
-			}
-		}
-		mutex.Unlock()
-	}
+// PaymentTTL is how long a Pakasir order is polled before it is dropped.
+const PaymentTTL = 24 * time.Hour
+
+// PendingPayment is a Pakasir order waiting to be paid. It is kept apart
+// from the flow data so starting another flow does not lose it.
+type PendingPayment struct {
+	OrderID   string    `json:"order_id"`
+	Price     int       `json:"price"`
+	Action    string    `json:"action"` // "topup" or "buy_account"
+	ChatID    int64     `json:"chat_id"`
+	Password  string    `json:"password,omitempty"`
+	Days      int       `json:"days,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
-func createUser(bot *tgbotapi.BotAPI, chatID int64, ownerID int64, password string, days int, config *BotConfig) {
-	res, err := apiCall("POST", "/user/create", map[string]interface{}{
-		"password": password,
+// UserState is a user's position in a multi-step flow and the values
+// collected so far.
+type UserState struct {
+	State     string            `json:"state"`
+	Data      map[string]string `json:"data"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// StateStore holds all conversation state behind one lock. With a path it
+// is mirrored to disk after every change, so flows survive a restart.
+// Last-message IDs change on every reply, so they only mark the store dirty
+// and are written by the janitor.
+type StateStore struct {
+	mu       sync.Mutex
+	path     string
+	Users    map[int64]*UserState `json:"users"`
+	Messages map[int64]int        `json:"messages"` // last tracked bot message per chat
+	secrets  map[int64]string     // never written to disk
+	Payments map[int64]*PendingPayment `json:"payments"`
+	dirty    bool
+}
+
+var states = newStateStore("")
+
+func newStateStore(path string) *StateStore {
+	return &StateStore{
+		path:     path,
+		Users:    make(map[int64]*UserState),
+		Messages: make(map[int64]int),
+		secrets:  make(map[int64]string),
+		Payments: make(map[int64]*PendingPayment),
+	}
+}
+
+// loadStateStore restores the store from path, starting empty when the file
+// is missing or unreadable.
+func loadStateStore(path string) *StateStore {
+	s := newStateStore(path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		log.Printf("State tersimpan tidak valid, mulai kosong: %v", err)
+		return newStateStore(path)
+	}
+	if s.Users == nil {
+		s.Users = make(map[int64]*UserState)
+	}
+	if s.Messages == nil {
+		s.Messages = make(map[int64]int)
+	}
+	if s.Payments == nil {
+		s.Payments = make(map[int64]*PendingPayment)
+	}
+	s.Expire()
+	return s
+}
+
+// State returns the user's current state, or "" when there is none.
+func (s *StateStore) State(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok && !u.expired(time.Now()) {
+		return u.State
+	}
+	return ""
+}
+
+func (s *StateStore) SetState(userID int64, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(userID).State = state
+	s.save()
+}
+
+// ClearState ends the flow but keeps the collected data.
+func (s *StateStore) ClearState(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok {
+		u.State = ""
+		u.UpdatedAt = time.Now()
+		s.prune(userID)
+		s.save()
+	}
+}
+
+func (s *StateStore) Get(userID int64, key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok && !u.expired(time.Now()) {
+		return u.Data[key]
+	}
+	return ""
+}
+
+func (s *StateStore) Set(userID int64, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(userID).Data[key] = value
+	s.save()
+}
+
+// Reset replaces the user's data, keeping the current state.
+func (s *StateStore) Reset(userID int64, data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(userID)
+	u.Data = make(map[string]string)
+	for k, v := range data {
+		u.Data[k] = v
+	}
+	s.save()
+}
+
+func (s *StateStore) ClearData(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[userID]; ok {
+		u.Data = make(map[string]string)
+		s.prune(userID)
+		s.save()
+	}
+}
+
+// Clear drops both the state and the data of a user.
+func (s *StateStore) Clear(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, userID)
+	if _, ok := s.Users[userID]; ok {
+		delete(s.Users, userID)
+		s.save()
+	}
+}
+
+// SetSecret keeps a value such as a backup passphrase in memory only.
+func (s *StateStore) SetSecret(userID int64, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[userID] = secret
+}
+
+// TakeSecret returns and forgets the user's secret.
+func (s *StateStore) TakeSecret(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secret := s.secrets[userID]
+	delete(s.secrets, userID)
+	return secret
+}
+
+func (s *StateStore) SetLastMessage(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages[chatID] = messageID
+	s.dirty = true
+}
+
+// TakeLastMessage returns and forgets the last tracked message of a chat.
+func (s *StateStore) TakeLastMessage(chatID int64) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.Messages[chatID]
+	if ok {
+		delete(s.Messages, chatID)
+		s.dirty = true
+	}
+	return id, ok
+}
+
+func (s *StateStore) SetPayment(userID int64, payment PendingPayment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment.CreatedAt = time.Now()
+	s.Payments[userID] = &payment
+	s.save()
+}
+
+func (s *StateStore) ClearPayment(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Payments[userID]; ok {
+		delete(s.Payments, userID)
+		s.save()
+	}
+}
+
+// PendingPayments returns a copy of all pending payments, safe to range over while
+// handlers keep changing the store.
+func (s *StateStore) PendingPayments() map[int64]PendingPayment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payments := make(map[int64]PendingPayment, len(s.Payments))
+	for userID, p := range s.Payments {
+		payments[userID] = *p
+	}
+	return payments
+}
+
+// Expire drops flows idle longer than their TTL and payments older than
+// PaymentTTL, and flushes pending last-message changes.
+func (s *StateStore) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	changed := false
+	for userID, u := range s.Users {
+		if u.expired(now) {
+			delete(s.Users, userID)
+			changed = true
+		}
+	}
+	for userID, p := range s.Payments {
+		if now.Sub(p.CreatedAt) > PaymentTTL {
+			delete(s.Payments, userID)
+			changed = true
+		}
+	}
+	if changed || s.dirty {
+		s.save()
+	}
+}
+
+// user returns the entry for userID, creating it, and marks it as active.
+// Callers hold s.mu.
+func (s *StateStore) user(userID int64) *UserState {
+	u, ok := s.Users[userID]
+	if !ok || u.expired(time.Now()) {
+		u = &UserState{Data: make(map[string]string)}
+		s.Users[userID] = u
+	}
+	u.UpdatedAt = time.Now()
+	return u
+}
+
+func (s *StateStore) prune(userID int64) {
+	if u := s.Users[userID]; u.State == "" && len(u.Data) == 0 {
+		delete(s.Users, userID)
+	}
+}
+
+// save writes the store to disk atomically. Callers hold s.mu.
+func (s *StateStore) save() {
+	s.dirty = false
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Printf("Gagal menyimpan state: %v", err)
+	}
+}
+
+func (u *UserState) expired(now time.Time) bool {
+	ttl, ok := StateTTLs[u.State]
+	if !ok {
+		ttl = DefaultStateTTL
+	}
+	return now.Sub(u.UpdatedAt) > ttl
+}
+
+// runStateJanitor expires abandoned flows and flushes the store once a
+// minute.
+func runStateJanitor() {
+	for range time.Tick(time.Minute) {
+		states.Expire()
+	}
+}
+
+// offerResume is shown on /start when the user left a flow unfinished.
+func offerResume(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "⏸️ Anda memiliki proses yang belum selesai. Lanjutkan?"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Lanjutkan"), "resume_flow"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+// resumeFlow repeats the prompt of the user's current state.
+func resumeFlow(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	prompt, ok := StatePrompts[states.State(userID)]
+	if !ok {
+		prompt = "Silakan kirim input berikutnya."
+	}
+	sendMessage(bot, chatID, "▶️ "+prompt)
+}
+
+// ==========================================
+// Staff & Permissions
+// ==========================================
+
+// Permissions a staff role can grant. The owner (admin_id) has all of them.
+const (
+	PermBackup       = "backup"
+	PermRestore      = "restore"
+	PermUsersManage  = "users.manage"
+	PermWalletAdjust = "wallet.adjust"
+	PermBroadcast    = "broadcast"
+	PermConfigEdit   = "config.edit"
+)
+
+var AllPermissions = []string{PermBackup, PermRestore, PermUsersManage, PermWalletAdjust, PermBroadcast, PermConfigEdit}
+
+// DefaultRoles can be overridden or extended with "roles" in bot-config.json.
+var DefaultRoles = map[string][]string{
+	"admin":   AllPermissions,
+	"finance": {PermWalletAdjust, PermBackup},
+	"support": {PermUsersManage, PermBroadcast},
+}
+
+// StaffMember is a Telegram user with a role.
+type StaffMember struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+	Name string `json:"name,omitempty"`
+}
+
+// configMu guards the shared BotConfig, which admins edit while workers
+// read it. saveConfig must be called with it held.
+var configMu sync.RWMutex
+
+func rolePermissions(config *BotConfig, role string) ([]string, bool) {
+	if perms, ok := config.Roles[role]; ok {
+		return perms, true
+	}
+	perms, ok := DefaultRoles[role]
+	return perms, ok
+}
+
+func roleNames(config *BotConfig) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, roles := range []map[string][]string{DefaultRoles, config.Roles} {
+		for name := range roles {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func staffRole(config *BotConfig, userID int64) (string, bool) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	for _, m := range config.Staff {
+		if m.ID == userID {
+			return m.Role, true
+		}
+	}
+	return "", false
+}
+
+func isOwner(config *BotConfig, userID int64) bool {
+	return userID == config.AdminID
+}
+
+func isStaff(config *BotConfig, userID int64) bool {
+	if isOwner(config, userID) {
+		return true
+	}
+	_, ok := staffRole(config, userID)
+	return ok
+}
+
+// can reports whether the user holds perm. An empty perm only requires staff.
+func can(config *BotConfig, userID int64, perm string) bool {
+	if isOwner(config, userID) {
+		return true
+	}
+	role, ok := staffRole(config, userID)
+	if !ok {
+		return false
+	}
+	if perm == "" {
+		return true
+	}
+	perms, _ := rolePermissions(config, role)
+	for _, p := range perms {
+		if p == perm || p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// requirePerm is can that tells staff members which permission they lack.
+// Non-staff are ignored silently, like the old admin-only checks.
+func requirePerm(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, userID int64, perm string) bool {
+	if can(config, userID, perm) {
+		return true
+	}
+	if isStaff(config, userID) {
+		replyError(bot, chatID, tr(chatID, "Role Anda tidak memiliki izin %s.", perm))
+	}
+	return false
+}
+
+func showStaff(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	var b strings.Builder
+	b.WriteString(tr(chatID, "👑 Kelola Staff\n\n"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	configMu.RLock()
+	if len(config.Staff) == 0 {
+		b.WriteString(tr(chatID, "Belum ada staff.\n"))
+	}
+	for _, m := range config.Staff {
+		label := strconv.FormatInt(m.ID, 10)
+		if m.Name != "" {
+			label += " (" + m.Name + ")"
+		}
+		b.WriteString(fmt.Sprintf("• %s — %s\n", label, m.Role))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗑️ Hapus ")+label, fmt.Sprintf("staff_remove:%d", m.ID)),
+		))
+	}
+	configMu.RUnlock()
+
+	b.WriteString(tr(chatID, "\nRole:\n"))
+	for _, name := range roleNames(config) {
+		perms, _ := rolePermissions(config, name)
+		b.WriteString(fmt.Sprintf("• %s: %s\n", name, strings.Join(perms, ", ")))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "➕ Tambah / Ubah Staff"), "staff_add")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")),
+	)
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// setStaff adds the member or changes their role and name.
+func setStaff(config *BotConfig, member StaffMember) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	for i, m := range config.Staff {
+		if m.ID == member.ID {
+			config.Staff[i] = member
+			return saveConfig(config)
+		}
+	}
+	config.Staff = append(config.Staff, member)
+	return saveConfig(config)
+}
+
+func removeStaff(config *BotConfig, userID int64) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	kept := config.Staff[:0:0]
+	for _, m := range config.Staff {
+		if m.ID != userID {
+			kept = append(kept, m)
+		}
+	}
+	config.Staff = kept
+	return saveConfig(config)
+}
+
+// ==========================================
+// Slash Commands
+// ==========================================
+
+type commandSpec struct {
+	Command     string
+	Args        string // optional arguments are written as [arg]
+	Description string
+	Perm        string // required permission when not Public
+	Public      bool
+}
+
+var botCommands = []commandSpec{
+	{Command: "start", Description: "Menu utama", Public: true},
+	{Command: "info", Args: "<pass>", Description: "Detail akun", Public: true},
+	{Command: "balance", Args: "[tgid]", Description: "Cek saldo", Public: true},
+	{Command: "create", Args: "<pass> <hari>", Description: "Buat akun gratis", Perm: PermUsersManage},
+	{Command: "renew", Args: "<pass> <hari>", Description: "Perpanjang akun tanpa saldo", Perm: PermUsersManage},
+	{Command: "delete", Args: "<pass>", Description: "Hapus akun", Perm: PermUsersManage},
+	{Command: "lock", Args: "<pass>", Description: "Kunci akun", Perm: PermUsersManage},
+	{Command: "addbalance", Args: "<tgid> <jumlah>", Description: "Tambah saldo user", Perm: PermWalletAdjust},
+}
+
+func findCommand(name string) (commandSpec, bool) {
+	for _, c := range botCommands {
+		if c.Command == name {
+			return c, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+func (c commandSpec) allowed(config *BotConfig, userID int64) bool {
+	return c.Public || can(config, userID, c.Perm)
+}
+
+func (c commandSpec) usage(lang string) string {
+	return strings.TrimSpace("/" + c.Command + " " + translate(lang, c.Args))
+}
+
+// arity returns how many arguments the command takes, at least and at most.
+func (c commandSpec) arity() (int, int) {
+	fields := strings.Fields(c.Args)
+	required := 0
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "[") {
+			required++
+		}
+	}
+	return required, len(fields)
+}
+
+// commandsFor lists what the user may run, as shown in Telegram's menu.
+func commandsFor(config *BotConfig, userID int64, lang string) []tgbotapi.BotCommand {
+	var cmds []tgbotapi.BotCommand
+	for _, c := range botCommands {
+		if !c.allowed(config, userID) {
+			continue
+		}
+		desc := translate(lang, c.Description)
+		if c.Args != "" {
+			desc = translate(lang, c.Args) + " — " + desc
+		}
+		cmds = append(cmds, tgbotapi.BotCommand{Command: c.Command, Description: desc})
+	}
+	return cmds
+}
+
+// registerCommands publishes the user command list for all private chats,
+// once per language, and a per-chat list for the owner and every staff
+// member in their own language.
+func registerCommands(bot *tgbotapi.BotAPI, config *BotConfig) {
+	setCommands(bot, tgbotapi.NewBotCommandScopeAllPrivateChats(), "", commandsFor(config, 0, DefaultLanguage))
+	for _, lang := range languageCodes() {
+		// Telegram only accepts two-letter ISO 639-1 codes here
+		if lang != DefaultLanguage && len(lang) == 2 {
+			setCommands(bot, tgbotapi.NewBotCommandScopeAllPrivateChats(), lang, commandsFor(config, 0, lang))
+		}
+	}
+
+	ids := []int64{config.AdminID}
+	configMu.RLock()
+	for _, m := range config.Staff {
+		ids = append(ids, m.ID)
+	}
+	configMu.RUnlock()
+	for _, id := range ids {
+		setCommands(bot, tgbotapi.NewBotCommandScopeChat(id), "", commandsFor(config, id, userLanguage(id)))
+	}
+}
+
+// setCommands sets the list for scope, for users of lang or, with "",
+// everyone the scope covers.
+func setCommands(bot *tgbotapi.BotAPI, scope tgbotapi.BotCommandScope, lang string, cmds []tgbotapi.BotCommand) {
+	if _, err := bot.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope, lang, cmds...)); err != nil {
+		log.Printf("Gagal mendaftarkan perintah (%s %d): %v", scope.Type, scope.ChatID, err)
+	}
+}
+
+// dropCommands removes a chat's own list so it falls back to the user list.
+func dropCommands(bot *tgbotapi.BotAPI, chatID int64) {
+	if _, err := bot.Request(tgbotapi.NewDeleteMyCommandsWithScope(tgbotapi.NewBotCommandScopeChat(chatID))); err != nil {
+		log.Printf("Gagal menghapus perintah chat %d: %v", chatID, err)
+	}
+}
+
+func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
+	userID := msg.From.ID
+	chatID := msg.Chat.ID
+
+	spec, ok := findCommand(msg.Command())
+	if !ok {
+		replyError(bot, chatID, tr(chatID, "Perintah tidak dikenal."))
+		return
+	}
+	if !spec.allowed(config, userID) {
+		replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+		return
+	}
+	args := strings.Fields(msg.CommandArguments())
+	if min, max := spec.arity(); len(args) < min || len(args) > max {
+		replyError(bot, chatID, tr(chatID, "Format: ")+spec.usage(userLanguage(userID)))
+		return
+	}
+
+	switch spec.Command {
+	case "start":
+		showMainMenu(bot, chatID, config, userID)
+
+	case "info":
+		showAccountCard(bot, chatID, userID, args[0], config)
+
+	case "balance":
+		target := userID
+		if len(args) == 1 {
+			tid, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				replyError(bot, chatID, tr(chatID, "Telegram ID tidak valid."))
+				return
+			}
+			if tid != userID && !can(config, userID, PermWalletAdjust) {
+				replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
+				return
+			}
+			target = tid
+		}
+		sendMessage(bot, chatID, tr(chatID, "💰 Saldo %d: Rp %d", target, getBalance(target)))
+
+	case "create":
+		if !validatePassword(bot, chatID, args[0]) {
+			return
+		}
+		days, ok := validateNumber(bot, chatID, args[1], 1, 3650, "Durasi")
+		if !ok {
+			return
+		}
+		createFreeAccount(bot, chatID, userID, args[0], days, config)
+
+	case "renew":
+		days, ok := validateNumber(bot, chatID, args[1], 1, 3650, "Durasi")
+		if !ok {
+			return
+		}
+		res, err := apiCall("POST", "/user/renew", map[string]interface{}{
+			"password": args[0],
+			"days":     days,
+		})
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+			return
+		}
+		if res["success"] != true {
+			replyError(bot, chatID, tr(chatID, "Gagal memperpanjang: %s", res["message"]))
+			return
+		}
+		data := res["data"].(map[string]interface{})
+		sendMessage(bot, chatID, tr(chatID, "✅ User %s berhasil diperpanjang. Expired: %s", data["password"], data["expired"]))
+
+	case "delete":
+		res, err := apiCall("POST", "/user/delete", map[string]interface{}{
+			"password": args[0],
+		})
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+			return
+		}
+		if res["success"] != true {
+			replyError(bot, chatID, tr(chatID, "Gagal menghapus: %s", res["message"]))
+			return
+		}
+		sendMessage(bot, chatID, tr(chatID, "✅ Akun %s berhasil dihapus.", args[0]))
+
+	case "lock":
+		res, err := apiCall("POST", "/user/lock", map[string]interface{}{
+			"password": args[0],
+		})
+		if err != nil {
+			replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+			return
+		}
+		if res["success"] != true {
+			replyError(bot, chatID, tr(chatID, "Gagal mengunci: %s", res["message"]))
+			return
+		}
+		sendMessage(bot, chatID, tr(chatID, "🔒 Akun %s dikunci. Perpanjang untuk membukanya kembali.", args[0]))
+
+	case "addbalance":
+		tid, err1 := strconv.ParseInt(args[0], 10, 64)
+		amt, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || amt <= 0 {
+			replyError(bot, chatID, tr(chatID, "ID atau jumlah tidak valid."))
+			return
+		}
+		if err := addBalance(tid, amt); err != nil {
+			replyError(bot, chatID, tr(chatID, "Gagal menambah saldo: ")+err.Error())
+			return
+		}
+		sendMessage(bot, chatID, tr(chatID, "✅ Berhasil menambah Rp %d ke user %d", amt, tid))
+	}
+}
+
+// ==========================================
+// Account Detail
+// ==========================================
+
+// Clients may connect on any port in this range; install.sh DNATs it to
+// the core's listen port.
+const (
+	PortRangeStart = 6000
+	PortRangeEnd   = 19999
+)
+
+// Hysteria v1 clients refuse a profile without bandwidth, and the server
+// does not limit it, so the QR carries a generous default.
+const (
+	ClientUpMbps   = 100
+	ClientDownMbps = 100
+)
+
+// ConnInfo is the server half of a client's connection settings.
+type ConnInfo struct {
+	Domain string
+	Obfs   string
+}
+
+func connectionInfo(config *BotConfig) ConnInfo {
+	// The server IP is never shown to customers, so no fallback for Domain
+	conn := ConnInfo{Domain: config.Domain, }
+	if res, err := apiCall("GET", "/config", nil); err == nil && res["success"] == true {
+		if data, ok := res["data"].(map[string]interface{}); ok {
+			conn.Obfs, _ = data["obfs"].(string)
+		}
+	}
+	return conn
+}
+
+// clientURI is the hysteria:// link encoded in the account QR code, in the
+// Hysteria v1 URI scheme that client apps import.
+func clientURI(password string, conn ConnInfo) string {
+	q := url.Values{}
+	q.Set("protocol", "udp")
+	q.Set("auth", password)
+	q.Set("peer", conn.Domain)
+	q.Set("insecure", "1")
+	q.Set("upmbps", strconv.Itoa(ClientUpMbps))
+	q.Set("downmbps", strconv.Itoa(ClientDownMbps))
+	q.Set("obfs", "xplus")
+	q.Set("obfsParam", conn.Obfs)
+	q.Set("mport", fmt.Sprintf("%d-%d", PortRangeStart, PortRangeEnd))
+	return fmt.Sprintf("hysteria://%s:%d?%s#%s", conn.Domain, PortRangeStart, q.Encode(), url.PathEscape(password))
+}
+
+// daysRemaining counts whole days left, 0 once the account has expired.
+func daysRemaining(expired string) int {
+	exp, err := time.Parse("2006-01-02", expired)
+	if err != nil {
+		return 0
+	}
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	if days := int(exp.Sub(today).Hours() / 24); days > 0 {
+		return days
+	}
+	return 0
+}
+
+func accountCardText(userID int64, u UserData, conn ConnInfo, showOwner bool) string {
+	domain := conn.Domain
+	if domain == "" {
+		domain = tr(userID, "(Belum Diatur)")
+	}
+	obfs := conn.Obfs
+	if obfs == "" {
+		obfs = "-"
+	}
+	text := tr(userID, "📇 *Detail Akun*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Aktif s/d: %s\n⏳ Sisa     : %d hari\n📌 Status   : %s %s",
+		u.Password, domain, PortRangeStart, PortRangeEnd, obfs, u.Expired, daysRemaining(u.Expired), statusIcon(u.Status), statusLabel(userID, u.Status))
+	if showOwner {
+		owner := "admin"
+		if u.Owner != 0 {
+			owner = strconv.FormatInt(u.Owner, 10)
+		}
+		text += tr(userID, "\n👤 Pemilik  : ") + owner
+	}
+	return text
+}
+
+// sendAccountCard sends the card as a photo of the connection QR code, or as
+// plain text if the QR cannot be generated. It is not tracked, so the card
+// stays in the chat after the user moves on.
+func sendAccountCard(bot *tgbotapi.BotAPI, chatID int64, u UserData, showOwner bool, markup tgbotapi.InlineKeyboardMarkup, config *BotConfig) {
+	conn := connectionInfo(config)
+	text := accountCardText(chatID, u, conn, showOwner)
+	deleteLastMessage(bot, chatID)
+
+	// Without the domain or obfs the QR would import a profile that cannot connect
+	var png []byte
+	if conn.Domain != "" && conn.Obfs != "" {
+		var err error
+		if png, err = qrcode.Encode(clientURI(u.Password, conn), qrcode.Medium, 512); err != nil {
+			log.Printf("Gagal membuat QR untuk %s: %v", u.Password, err)
+		}
+	}
+	if png == nil {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = markup
+		bot.Send(msg)
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "zivpn-" + u.Password + ".png", Bytes: png})
+	photo.Caption = text + tr(chatID, "\n\n📷 Scan QR untuk mengimpor akun ke aplikasi.")
+	photo.ParseMode = "Markdown"
+	photo.ReplyMarkup = markup
+	if _, err := bot.Send(photo); err != nil {
+		log.Printf("Gagal mengirim kartu akun %s: %v", u.Password, err)
+	}
+}
+
+func statusIcon(status string) string {
+	switch status {
+	case "Expired":
+		return "🔴"
+	case "Locked":
+		return "🔒"
+	}
+	return "🟢"
+}
+
+func statusLabel(userID int64, status string) string {
+	switch status {
+	case "Active":
+		return tr(userID, "Aktif")
+	case "Locked":
+		return tr(userID, "Terkunci")
+	case "Expired":
+		return tr(userID, "Kedaluwarsa")
+	}
+	return status
+}
+
+// findAccount returns the account if it belongs to the user, or to anyone
+// for staff with users.manage.
+func findAccount(userID int64, password string, config *BotConfig) (UserData, bool) {
+	users, err := getUsers()
+	if err != nil {
+		return UserData{}, false
+	}
+	manage := can(config, userID, PermUsersManage)
+	for _, u := range users {
+		if u.Password == password && (manage || u.Owner == userID) {
+			return u, true
+		}
+	}
+	return UserData{}, false
+}
+
+func showAccountCard(bot *tgbotapi.BotAPI, chatID int64, userID int64, password string, config *BotConfig) {
+	u, ok := findAccount(userID, password, config)
+	if !ok {
+		replyError(bot, chatID, tr(chatID, "Akun tidak ditemukan atau bukan milik Anda."))
+		return
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔁 Perpanjang"), "acct_renew:"+u.Password)),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🏠 Menu Utama"), "cancel")),
+	)
+	sendAccountCard(bot, chatID, u, can(config, userID, PermUsersManage), markup, config)
+}
+
+// showMyAccounts lists the accounts the user bought or received as a trial.
+func showMyAccounts(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	users, err := getUsers()
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data user."))
+		return
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, u := range users {
+		if u.Owner != userID {
+			continue
+		}
+		label := fmt.Sprintf("%s %s (%s)", statusIcon(u.Status), u.Password, u.Expired)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, "acct:"+u.Password)))
+	}
+	if len(rows) == 0 {
+		sendMessage(bot, chatID, tr(chatID, "📂 Anda belum memiliki akun."))
+		return
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")))
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "📇 Akun Saya — pilih akun untuk melihat detail dan QR:"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// ==========================================
+// Broadcast
+// ==========================================
+
+// BroadcastInterval paces deliveries at 20 per second, under Telegram's
+// limit of about 30, leaving room for normal replies.
+const BroadcastInterval = 50 * time.Millisecond
+
+// BroadcastRetries is how often a delivery is retried after a 429.
+const BroadcastRetries = 3
+
+var broadcastSegments = []struct{ Key, Label string }{
+	{"all", "👥 Semua User"},
+	{"active", "🟢 Pemilik Akun Aktif"},
+	{"balance", "💰 Saldo > 0"},
+	{"expiring", "⏳ Akan Expired"},
+	{"never", "🆕 Belum Pernah Beli"},
+}
+
+func segmentLabel(userID int64, segment string, days int) string {
+	for _, seg := range broadcastSegments {
+		if seg.Key == segment {
+			if segment == "expiring" {
+				return tr(userID, "%s (≤ %d hari)", tr(userID, seg.Label), days)
+			}
+			return tr(userID, seg.Label)
+		}
+	}
+	return segment
+}
+
+// Broadcast is a delivery in progress. Only one runs at a time.
+type Broadcast struct {
+	Label                 string
+	Total                 int
+	Sent, Blocked, Failed int
+	stop                  chan struct{}
+	stopOnce              sync.Once
+}
+
+func (b *Broadcast) Stop() {
+	b.stopOnce.Do(func() { close(b.stop) })
+}
+
+var (
+	broadcastMu sync.Mutex
+	broadcast   *Broadcast
+)
+
+// broadcastAudience returns the Telegram IDs in a segment. Known users are
+// everyone in the user registry or with a wallet or an account; banned users
+// and users who blocked the bot are skipped.
+func broadcastAudience(segment string, days int) ([]int64, error) {
+	wallets, err := loadWallets()
+	if err != nil {
+		return nil, err
+	}
+	users, err := getUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int64]bool)
+	banned := make(map[int64]bool)
+	owners := make(map[int64]bool)
+	for _, w := range wallets {
+		if w.Banned {
+			banned[w.TelegramID] = true
+			continue
+		}
+		switch segment {
+		case "all":
+			ids[w.TelegramID] = true
+		case "balance":
+			if w.Balance > 0 {
+				ids[w.TelegramID] = true
+			}
+		case "never":
+			if w.CreatedCount == 0 {
+				ids[w.TelegramID] = true
+			}
+		}
+	}
+	for _, u := range users {
+		if u.Owner == 0 {
+			continue
+		}
+		owners[u.Owner] = true
+		switch segment {
+		case "all":
+			ids[u.Owner] = true
+		case "active":
+			if u.Status == "Active" {
+				ids[u.Owner] = true
+			}
+		case "expiring":
+			if u.Status == "Active" && daysRemaining(u.Expired) <= days {
+				ids[u.Owner] = true
+			}
+		}
+	}
+
+	buyers := make(map[int64]bool)
+	for _, w := range wallets {
+		if w.CreatedCount > 0 {
+			buyers[w.TelegramID] = true
+		}
+	}
+	blocked := make(map[int64]bool)
+	for _, u := range botUsers.Search("") {
+		if u.Blocked {
+			blocked[u.ID] = true
+			continue
+		}
+		if segment == "all" || (segment == "never" && !buyers[u.ID]) {
+			ids[u.ID] = true
+		}
+	}
+
+	var audience []int64
+	for id := range ids {
+		if banned[id] || blocked[id] || (segment == "never" && owners[id]) {
+			continue
+		}
+		audience = append(audience, id)
+	}
+	sort.Slice(audience, func(i, j int) bool { return audience[i] < audience[j] })
+	return audience, nil
+}
+
+func showBroadcastMenu(bot *tgbotapi.BotAPI, chatID int64) {
+	text := tr(chatID, "📣 *Broadcast*\nPilih target penerima:")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, seg := range broadcastSegments {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, seg.Label), "bc_seg:"+seg.Key)))
+	}
+	broadcastMu.Lock()
+	if b := broadcast; b != nil {
+		text = tr(chatID, "📣 *Broadcast*\nSedang berjalan: %s (%d/%d)", b.Label, b.Sent+b.Blocked+b.Failed, b.Total)
+		rows = [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⏹️ Hentikan Broadcast"), "bc_cancel"))}
+	}
+	broadcastMu.Unlock()
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+func startBroadcastCompose(bot *tgbotapi.BotAPI, chatID int64, userID int64, segment string, days int) {
+	audience, err := broadcastAudience(segment, days)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data user: ")+err.Error())
+		return
+	}
+	if len(audience) == 0 {
+		sendMessage(bot, chatID, tr(chatID, "📭 Tidak ada user di segmen ini."))
+		return
+	}
+	states.Reset(userID, map[string]string{
+		"segment": segment,
+		"days":    strconv.Itoa(days),
+	})
+	states.SetState(userID, "admin_broadcast_content")
+	sendMessage(bot, chatID, tr(chatID, "📣 Target: %s (%d user)\nKirim pesan yang akan disiarkan: teks, foto atau dokumen (caption ikut terkirim).", segmentLabel(chatID, segment, days), len(audience)))
+}
+
+// confirmBroadcast remembers the admin's message and asks before sending it.
+func confirmBroadcast(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
+	chatID := msg.Chat.ID
+	if msg.Text == "" && len(msg.Photo) == 0 && msg.Document == nil {
+		sendMessage(bot, chatID, tr(chatID, "❌ Hanya teks, foto atau dokumen yang didukung. Coba lagi:"))
+		return
+	}
+	states.Set(userID, "src_chat", strconv.FormatInt(chatID, 10))
+	states.Set(userID, "src_msg", strconv.Itoa(msg.MessageID))
+	resetState(userID)
+
+	days, _ := strconv.Atoi(states.Get(userID, "days"))
+	reply := tgbotapi.NewMessage(chatID, tr(chatID, "📣 Kirim pesan di atas ke %s?", segmentLabel(chatID, states.Get(userID, "segment"), days)))
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Kirim"), "bc_send"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel"),
+	))
+	sendAndTrack(bot, reply)
+}
+
+func startBroadcast(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	segment := states.Get(userID, "segment")
+	days, _ := strconv.Atoi(states.Get(userID, "days"))
+	srcChat, err1 := strconv.ParseInt(states.Get(userID, "src_chat"), 10, 64)
+	srcMsg, err2 := strconv.Atoi(states.Get(userID, "src_msg"))
+	if segment == "" || err1 != nil || err2 != nil {
+		replyError(bot, chatID, tr(chatID, "Pesan broadcast tidak ditemukan. Silakan mulai ulang."))
+		return
+	}
+	audience, err := broadcastAudience(segment, days)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil data user: ")+err.Error())
+		return
+	}
+
+	b := &Broadcast{Label: segmentLabel(chatID, segment, days), Total: len(audience), stop: make(chan struct{})}
+	broadcastMu.Lock()
+	if broadcast != nil {
+		broadcastMu.Unlock()
+		replyError(bot, chatID, tr(chatID, "Broadcast lain sedang berjalan. Hentikan dulu atau tunggu hingga selesai."))
+		return
+	}
+	broadcast = b
+	broadcastMu.Unlock()
+	states.ClearData(userID)
+	deleteLastMessage(bot, chatID)
+
+	log.Printf("Broadcast oleh %d ke %s: %d user", userID, b.Label, b.Total)
+	go runBroadcast(bot, chatID, b, audience, srcChat, srcMsg)
+}
+
+func stopBroadcast(bot *tgbotapi.BotAPI, chatID int64) {
+	broadcastMu.Lock()
+	b := broadcast
+	broadcastMu.Unlock()
+	if b == nil {
+		sendMessage(bot, chatID, tr(chatID, "Tidak ada broadcast yang sedang berjalan."))
+		return
+	}
+	b.Stop()
+}
+
+func runBroadcast(bot *tgbotapi.BotAPI, chatID int64, b *Broadcast, audience []int64, srcChat int64, srcMsg int) {
+	stopMarkup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⏹️ Hentikan"), "bc_cancel"),
+	))
+	status := tgbotapi.NewMessage(chatID, broadcastReport(chatID, b, "⏳ Broadcast berjalan"))
+	status.ReplyMarkup = stopMarkup
+	statusMsg, _ := bot.Send(status)
+
+	ticker := time.NewTicker(BroadcastInterval)
+	defer ticker.Stop()
+	stopped := false
+	for i, id := range audience {
+		select {
+		case <-b.stop:
+			stopped = true
+		case <-ticker.C:
+		}
+		if stopped {
+			break
+		}
+
+		err := deliverBroadcast(bot, id, srcChat, srcMsg)
+		broadcastMu.Lock()
+		switch {
+		case err == nil:
+			b.Sent++
+		case isBlockedError(err):
+			b.Blocked++
+			botUsers.MarkBlocked(id)
+		default:
+			b.Failed++
+			log.Printf("Broadcast ke %d gagal: %v", id, err)
+		}
+		broadcastMu.Unlock()
+
+		if statusMsg.MessageID != 0 && (i+1)%25 == 0 {
+			edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, statusMsg.MessageID, broadcastReport(chatID, b, "⏳ Broadcast berjalan"), stopMarkup)
+			bot.Request(edit)
+		}
+	}
+
+	broadcastMu.Lock()
+	broadcast = nil
+	broadcastMu.Unlock()
+
+	title := "✅ Broadcast selesai"
+	if stopped {
+		title = "⏹️ Broadcast dihentikan"
+	}
+	report := broadcastReport(chatID, b, title)
+	log.Printf("%s: terkirim %d, diblokir %d, gagal %d dari %d", title, b.Sent, b.Blocked, b.Failed, b.Total)
+	if statusMsg.MessageID != 0 {
+		bot.Request(tgbotapi.NewEditMessageText(chatID, statusMsg.MessageID, report))
+	} else {
+		bot.Send(tgbotapi.NewMessage(chatID, report))
+	}
+}
+
+// broadcastReport formats the counters; callers must not race the runner,
+// so the counters are read under broadcastMu.
+func broadcastReport(chatID int64, b *Broadcast, title string) string {
+	broadcastMu.Lock()
+	defer broadcastMu.Unlock()
+	return tr(chatID, "%s\nTarget: %s\nProgres: %d/%d\n✅ Terkirim: %d\n🚫 Memblokir bot: %d\n⚠️ Gagal: %d",
+		tr(chatID, title), b.Label, b.Sent+b.Blocked+b.Failed, b.Total, b.Sent, b.Blocked, b.Failed)
+}
+
+// deliverBroadcast copies the admin's message, waiting out flood limits.
+func deliverBroadcast(bot *tgbotapi.BotAPI, to int64, srcChat int64, srcMsg int) error {
+	var err error
+	for attempt := 0; attempt < BroadcastRetries; attempt++ {
+		_, err = bot.Request(tgbotapi.NewCopyMessage(to, srcChat, srcMsg))
+		tgErr, ok := err.(*tgbotapi.Error)
+		if !ok || tgErr.RetryAfter == 0 {
+			return err
+		}
+		time.Sleep(time.Duration(tgErr.RetryAfter) * time.Second)
+	}
+	return err
+}
+
+// isBlockedError reports a 403: the user blocked the bot or deleted the account.
+func isBlockedError(err error) bool {
+	tgErr, ok := err.(*tgbotapi.Error)
+	return ok && tgErr.Code == 403
+}
+
+// ==========================================
+// User Registry
+// ==========================================
+
+// RegistryFlushInterval batches last-seen updates; new users and block
+// changes are written immediately.
+const RegistryFlushInterval = time.Minute
+
+// BotUser is everyone who has sent the bot an update.
+type BotUser struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username,omitempty"`
+	FirstName    string    `json:"first_name,omitempty"`
+	LanguageCode string    `json:"language_code,omitempty"`
+	Language     string    `json:"language,omitempty"` // picked with the language button
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Blocked      bool      `json:"blocked,omitempty"` // a send failed with 403
+}
+
+// Label is the user's @username, else first name, else Telegram ID.
+func (u BotUser) Label() string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
+
+type UserRegistry struct {
+	mu    sync.Mutex
+	path  string
+	dirty bool
+	Users map[int64]*BotUser `json:"users"`
+}
+
+var botUsers = &UserRegistry{Users: make(map[int64]*BotUser)}
+
+func loadUserRegistry(path string) *UserRegistry {
+	r := &UserRegistry{path: path, Users: make(map[int64]*BotUser)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r
+	}
+	if err := json.Unmarshal(data, r); err != nil || r.Users == nil {
+		log.Printf("Data pengguna bot tidak valid, mulai kosong: %v", err)
+		return &UserRegistry{path: path, Users: make(map[int64]*BotUser)}
+	}
+	return r
+}
+
+// Touch records an update from the user. Hearing from a user also means
+// they no longer block the bot.
+func (r *UserRegistry) Touch(from *tgbotapi.User) {
+	if from == nil || from.IsBot {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	u, ok := r.Users[from.ID]
+	if !ok {
+		u = &BotUser{ID: from.ID, FirstSeen: now}
+		r.Users[from.ID] = u
+	}
+	unblocked := u.Blocked
+	u.Username = from.UserName
+	u.FirstName = from.FirstName
+	u.LanguageCode = from.LanguageCode
+	u.LastSeen = now
+	u.Blocked = false
+	r.dirty = true
+	if !ok || unblocked {
+		r.save()
+	}
+}
+
+func (r *UserRegistry) MarkBlocked(userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok && !u.Blocked {
+		u.Blocked = true
+		r.save()
+	}
+}
+
+// SetLanguage records the user's choice, which wins over language_code.
+func (r *UserRegistry) SetLanguage(userID int64, lang string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok {
+		u.Language = lang
+		r.save()
+	}
+}
+
+func (r *UserRegistry) Get(userID int64) (BotUser, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.Users[userID]; ok {
+		return *u, true
+	}
+	return BotUser{}, false
+}
+
+// Search matches the ID, username or first name, most recently seen first.
+// An empty query returns everyone.
+func (r *UserRegistry) Search(query string) []BotUser {
+	query = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	r.mu.Lock()
+	var found []BotUser
+	for _, u := range r.Users {
+		if query == "" || strings.Contains(strconv.FormatInt(u.ID, 10), query) ||
+			strings.Contains(strings.ToLower(u.Username), query) ||
+			strings.Contains(strings.ToLower(u.FirstName), query) {
+			found = append(found, *u)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(found, func(i, j int) bool { return found[i].LastSeen.After(found[j].LastSeen) })
+	return found
+}
+
+func (r *UserRegistry) flushLoop() {
+	for range time.Tick(RegistryFlushInterval) {
+		r.mu.Lock()
+		if r.dirty {
+			r.save()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Callers hold r.mu.
+func (r *UserRegistry) save() {
+	r.dirty = false
+	if r.path == "" {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Gagal menyimpan data pengguna bot: %v", err)
+		return
+	}
+	os.Rename(tmp, r.path)
+}
+
+func updateSender(update tgbotapi.Update) *tgbotapi.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From
+	case update.InlineQuery != nil:
+		return update.InlineQuery.From
+	}
+	return nil
+}
+
+// BotUsersPageSize is how many users one page of the admin view lists.
+const BotUsersPageSize = 10
+
+// showBotUsers pages through the registry, optionally filtered by query.
+func showBotUsers(bot *tgbotapi.BotAPI, chatID int64, page int, query string) {
+	all := botUsers.Search("")
+	found := all
+	if query != "" {
+		found = botUsers.Search(query)
+	}
+	active, blocked := 0, 0
+	for _, u := range all {
+		if time.Since(u.LastSeen) < 7*24*time.Hour {
+			active++
+		}
+		if u.Blocked {
+			blocked++
+		}
+	}
+
+	totalPages := (len(found) + BotUsersPageSize - 1) / BotUsersPageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+	start := (page - 1) * BotUsersPageSize
+	end := start + BotUsersPageSize
+	if end > len(found) {
+		end = len(found)
+	}
+
+	var b strings.Builder
+	b.WriteString(tr(chatID, "👥 Pengguna Bot\nTotal %d • aktif 7 hari %d • memblokir bot %d\n", len(all), active, blocked))
+	if query != "" {
+		b.WriteString(tr(chatID, "🔍 \"%s\": %d hasil\n", query, len(found)))
+	}
+	b.WriteString(tr(chatID, "Halaman %d/%d\n", page, totalPages))
+	if len(found) == 0 {
+		b.WriteString(tr(chatID, "\nTidak ada pengguna."))
+	}
+	for _, u := range found[start:end] {
+		line := fmt.Sprintf("\n• %d %s", u.ID, u.Label())
+		if u.Username != "" && u.FirstName != "" {
+			line += " (" + u.FirstName + ")"
+		}
+		if u.LanguageCode != "" {
+			line += " 🌐" + u.LanguageCode
+		}
+		if u.Blocked {
+			line += " 🚫"
+		}
+		line += tr(chatID, "\n  pertama %s • terakhir %s", u.FirstSeen.Format("2006-01-02"), u.LastSeen.Format("2006-01-02 15:04"))
+		b.WriteString(line)
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬅️ Sebelumnya"), fmt.Sprintf("botusers:%d:%s", page-1, query)))
+	}
+	if page < totalPages {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "Berikutnya ➡️"), fmt.Sprintf("botusers:%d:%s", page+1, query)))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔍 Cari Pengguna"), "botusers_search")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel")),
+	)
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// handleBotUsersPage parses "botusers:<page>:<query>".
+func handleBotUsersPage(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	parts := strings.SplitN(strings.TrimPrefix(data, "botusers:"), ":", 2)
+	page, _ := strconv.Atoi(parts[0])
+	query := ""
+	if len(parts) == 2 {
+		query = parts[1]
+	}
+	showBotUsers(bot, chatID, page, query)
+}
+
+// ==========================================
+// Localization
+// ==========================================
+
+// Messages are written in Indonesian and the Indonesian text is the key,
+// gettext style: a catalog maps it to a translation, and anything missing
+// falls back to Indonesian. More languages are <code>.json files in LangDir,
+// shaped like the template the bot writes there on start:
+// {"name": "🇲🇾 Bahasa Melayu", "messages": {"<indonesian>": "<translation>"}}
+const DefaultLanguage = "id"
+
+// LanguageButton is the same in every language so anyone can find it.
+const LanguageButton = "🌐 Bahasa / Language"
+
+type Catalog struct {
+	Name     string            `json:"name"`
+	Messages map[string]string `json:"messages"`
+}
+
+// catalogs is filled before updates are served and only read afterwards.
+var catalogs = map[string]*Catalog{
+	"id": {Name: "🇮🇩 Bahasa Indonesia", Messages: map[string]string{}},
+	"en": {Name: "🇬🇧 English", Messages: catalogEN},
+}
+
+var langCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]+)?$`)
+
+// loadCatalogs adds the language files in dir. A file for a built-in
+// language overrides individual messages.
+func loadCatalogs(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		code := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !langCodePattern.MatchString(code) {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Printf("Gagal membaca bahasa %s: %v", file, err)
+			continue
+		}
+		var c Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			log.Printf("File bahasa %s tidak valid: %v", file, err)
+			continue
+		}
+		if existing, ok := catalogs[code]; ok {
+			if c.Name != "" {
+				existing.Name = c.Name
+			}
+			for id, text := range c.Messages {
+				existing.Messages[id] = text
+			}
+			continue
+		}
+		if c.Name == "" {
+			c.Name = code
+		}
+		if c.Messages == nil {
+			c.Messages = map[string]string{}
+		}
+		catalogs[code] = &c
+	}
+}
+
+// writeCatalogTemplate lists every message with its English translation as
+// a starting point for a new language file.
+func writeCatalogTemplate(path string) {
+	data, err := json.MarshalIndent(Catalog{Name: "", Messages: catalogEN}, "", "  ")
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Printf("Gagal menulis template bahasa: %v", err)
+	}
+}
+
+// languageCodes lists the available languages, built-in ones first.
+func languageCodes() []string {
+	codes := []string{"id", "en"}
+	var extra []string
+	for code := range catalogs {
+		if code != "id" && code != "en" {
+			extra = append(extra, code)
+		}
+	}
+	sort.Strings(extra)
+	return append(codes, extra...)
+}
+
+// userLanguage is the language the user picked, else the one Telegram
+// reports for them. Malay speakers read Indonesian more easily than
+// English, and other unknown languages get English.
+func userLanguage(userID int64) string {
+	u, _ := botUsers.Get(userID)
+	if _, ok := catalogs[u.Language]; ok {
+		return u.Language
+	}
+	code := strings.ToLower(u.LanguageCode)
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	base := strings.SplitN(code, "-", 2)[0]
+	if _, ok := catalogs[base]; ok {
+		return base
+	}
+	if base == "" || base == "ms" {
+		return DefaultLanguage
+	}
+	return "en"
+}
+
+// translate looks msg up in lang's catalog and formats it when args are given.
+func translate(lang, msg string, args ...interface{}) string {
+	if c, ok := catalogs[lang]; ok {
+		if text := c.Messages[msg]; text != "" {
+			msg = text
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// tr translates msg into the user's language.
+func tr(userID int64, msg string, args ...interface{}) string {
+	return translate(userLanguage(userID), msg, args...)
+}
+
+func showLanguageMenu(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	current := userLanguage(userID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, code := range languageCodes() {
+		label := catalogs[code].Name
+		if code == current {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "lang_set:"+code),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+	))
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "🌐 Pilih bahasa:"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAndTrack(bot, msg)
+}
+
+// setLanguage saves the user's choice and refreshes their command list,
+// which staff get per chat.
+func setLanguage(bot *tgbotapi.BotAPI, chatID int64, userID int64, lang string, config *BotConfig) {
+	if _, ok := catalogs[lang]; !ok {
+		return
+	}
+	botUsers.SetLanguage(userID, lang)
+	if isStaff(config, userID) {
+		go setCommands(bot, tgbotapi.NewBotCommandScopeChat(userID), "", commandsFor(config, userID, lang))
+	}
+	showMainMenu(bot, chatID, config, userID)
+}
+
+// ==========================================
+// Feature Implementation
+// ==========================================
+
+func startCreateUser(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	states.SetState(userID, "create_password")
+	states.Reset(userID, map[string]string{"chat_id": strconv.FormatInt(chatID, 10)})
+	sendMessage(bot, chatID, tr(chatID, "👤 Masukkan Password Baru:"))
+}
+
+func processPayment(bot *tgbotapi.BotAPI, chatID int64, userID int64, days int, config *BotConfig) {
+	// Now handled by wallet topup model. This function is deprecated.
+	sendMessage(bot, chatID, tr(chatID, "Fitur pembayaran langsung sudah digantikan oleh sistem Topup. Silakan gunakan menu Topup Saldo terlebih dahulu jika saldo tidak mencukupi."))
+}
+
+// startPaymentChecker polls Pakasir for every pending payment. Payments live
+// in the state store, so orders placed before a restart are still completed.
+func startPaymentChecker(bot *tgbotapi.BotAPI, config *BotConfig) {
+	ticker := time.NewTicker(1 * time.Minute)
+	for range ticker.C {
+		for userID, payment := range states.PendingPayments() {
+			chatID := payment.ChatID
+			price := strconv.Itoa(payment.Price)
+			status, err := checkPakasirStatus(config, payment.OrderID, price)
+			if err != nil {
+				log.Printf("Error checking payment for %d: %v", userID, err)
+				continue
+			}
+			if status != "completed" && status != "success" {
+				continue
+			}
+			// Clear first so a slow completion is never paid out twice
+			states.ClearPayment(userID)
+
+			if payment.Action == "topup" {
+				// Add balance to user's wallet
+				amt := payment.Price
+				addBalance(userID, amt)
+				recordTopup(amt)
+				current := getBalance(userID)
+				sendMessage(bot, chatID, tr(chatID, "✅ Topup berhasil: Rp %d. Saldo Anda saat ini: Rp %d", amt, current))
+				// If there is a pending purchase, try to complete it
+				wallets, _ := loadWallets()
+				idx := getWalletIndex(wallets, userID)
+				if idx != -1 && wallets[idx].PendingPassword != "" && wallets[idx].PendingDays > 0 {
+					required := wallets[idx].PendingDays * config.DailyPrice
+					if paid, _ := deductIfSufficient(userID, required); paid {
+						pw := wallets[idx].PendingPassword
+						doDays := wallets[idx].PendingDays
+						clearPendingPurchase(userID)
+						if createUser(bot, chatID, userID, pw, doDays, config) {
+							recordPurchase("create", required)
+							sendMessage(bot, chatID, tr(chatID, "✅ Pembelian otomatis selesai. Akun dibuat. Saldo tersisa: Rp %d", getBalance(userID)))
+						} else {
+							refundPurchase(bot, chatID, userID, required)
+						}
+					}
+				}
+			} else if payment.Action == "buy_account" {
+				// Deduct balance and create account
+				required := payment.Days * config.DailyPrice
+				if paid, _ := deductIfSufficient(userID, required); paid {
+					if createUser(bot, chatID, userID, payment.Password, payment.Days, config) {
+						recordPurchase("create", required)
+					} else {
+						refundPurchase(bot, chatID, userID, required)
+					}
+				} else {
+					sendMessage(bot, chatID, tr(chatID, "Pembayaran berhasil, tetapi saldo tidak mencukupi untuk pemotongan. Silakan hubungi admin."))
+				}
+			}
+		}
+	}
+}
+
+// createUser reports whether the API created the account, so callers only
+// count a sale that went through.
+func createUser(bot *tgbotapi.BotAPI, chatID int64, ownerID int64, password string, days int, config *BotConfig) bool {
+	res, err := apiCall("POST", "/user/create", map[string]interface{}{
+		"password": password,
 		"days":     days,
+		"owner":    ownerID,
 	})
 
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
-		return
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return false
 	}
 
 	if res["success"] == true {
@@ -686,9 +2939,10 @@ func createUser(bot *tgbotapi.BotAPI, chatID int64, ownerID int64, password stri
 		incrementCreatedCount(ownerID)
 		appendMetric(ownerID)
 		sendAccountInfo(bot, chatID, data, config)
-	} else {
-		replyError(bot, chatID, fmt.Sprintf("Gagal membuat akun: %s", res["message"]))
+		return true
 	}
+	replyError(bot, chatID, tr(chatID, "Gagal membuat akun: %s", res["message"]))
+	return false
 }
 
 // ==========================================
@@ -753,7 +3007,12 @@ func saveWallets(wallets []WalletEntry) error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(WalletFile, data, 0644)
+	// Write atomically so concurrent readers never see a partial file
+	tmp := WalletFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, WalletFile)
 }
 
 func getWalletIndex(wallets []WalletEntry, telegramID int64) int {
@@ -778,6 +3037,8 @@ func getBalance(telegramID int64) int {
 }
 
 func addBalance(telegramID int64, amount int) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -792,6 +3053,8 @@ func addBalance(telegramID int64, amount int) error {
 }
 
 func deductBalance(telegramID int64, amount int) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -807,6 +3070,33 @@ func deductBalance(telegramID int64, amount int) error {
 	return saveWallets(wallets)
 }
 
+// deductIfSufficient takes amount from the wallet only if it covers it. The
+// check and the deduction share walletMu, so a purchase from the chat and
+// one from the payment checker cannot both spend the same balance.
+func deductIfSufficient(telegramID int64, amount int) (bool, error) {
+	walletMu.Lock()
+	defer walletMu.Unlock()
+	wallets, err := loadWallets()
+	if err != nil {
+		return false, err
+	}
+	idx := getWalletIndex(wallets, telegramID)
+	if idx == -1 || wallets[idx].Balance < amount {
+		return false, nil
+	}
+	wallets[idx].Balance -= amount
+	return true, saveWallets(wallets)
+}
+
+// refundPurchase gives back a charge whose account was not created or renewed.
+func refundPurchase(bot *tgbotapi.BotAPI, chatID int64, userID int64, amount int) {
+	if err := addBalance(userID, amount); err != nil {
+		log.Printf("Gagal mengembalikan saldo %d ke %d: %v", amount, userID, err)
+		return
+	}
+	sendMessage(bot, chatID, tr(chatID, "💰 Saldo Rp %d telah dikembalikan.", amount))
+}
+
 func hasUsedTrial(telegramID int64) bool {
 	wallets, err := loadWallets()
 	if err != nil {
@@ -820,6 +3110,8 @@ func hasUsedTrial(telegramID int64) bool {
 }
 
 func markTrialUsed(telegramID int64) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -834,6 +3126,8 @@ func markTrialUsed(telegramID int64) error {
 }
 
 func setPendingPurchase(telegramID int64, password string, days int) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -849,6 +3143,8 @@ func setPendingPurchase(telegramID int64, password string, days int) error {
 }
 
 func clearPendingPurchase(telegramID int64) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -888,6 +3184,8 @@ type MetricsEntry struct {
 }
 
 func appendMetric(owner int64) error {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
 	var entries []MetricsEntry
 	data, err := ioutil.ReadFile(MetricsFile)
 	if err == nil {
@@ -938,6 +3236,8 @@ func computeMetrics() (today int, week int, month int, err error) {
 }
 
 func incrementCreatedCount(telegramID int64) error {
+	walletMu.Lock()
+	defer walletMu.Unlock()
 	wallets, err := loadWallets()
 	if err != nil {
 		return err
@@ -951,6 +3251,48 @@ func incrementCreatedCount(telegramID int64) error {
 	return saveWallets(wallets)
 }
 
+func recordTopup(amount int) {
+	sales.mu.Lock()
+	defer sales.mu.Unlock()
+	sales.topups++
+	sales.topupAmount += int64(amount)
+}
+
+func recordPurchase(kind string, amount int) {
+	sales.mu.Lock()
+	defer sales.mu.Unlock()
+	sales.purchases[kind]++
+	sales.purchaseAmounts[kind] += int64(amount)
+}
+
+// serveMetrics exposes wallet and sales counters in Prometheus text format.
+func serveMetrics(port int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		sales.mu.Lock()
+		defer sales.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, "# HELP zivpn_bot_topups_total Completed wallet top-ups.\n# TYPE zivpn_bot_topups_total counter\n")
+		fmt.Fprintf(w, "zivpn_bot_topups_total %d\n", sales.topups)
+		fmt.Fprint(w, "# HELP zivpn_bot_topup_amount_rupiah_total Rupiah credited by top-ups.\n# TYPE zivpn_bot_topup_amount_rupiah_total counter\n")
+		fmt.Fprintf(w, "zivpn_bot_topup_amount_rupiah_total %d\n", sales.topupAmount)
+		fmt.Fprint(w, "# HELP zivpn_bot_purchases_total Paid account purchases by kind.\n# TYPE zivpn_bot_purchases_total counter\n")
+		for _, kind := range []string{"create", "renew"} {
+			fmt.Fprintf(w, "zivpn_bot_purchases_total{kind=%q} %d\n", kind, sales.purchases[kind])
+		}
+		fmt.Fprint(w, "# HELP zivpn_bot_purchase_amount_rupiah_total Rupiah spent on purchases by kind.\n# TYPE zivpn_bot_purchase_amount_rupiah_total counter\n")
+		for _, kind := range []string{"create", "renew"} {
+			fmt.Fprintf(w, "zivpn_bot_purchase_amount_rupiah_total{kind=%q} %d\n", kind, sales.purchaseAmounts[kind])
+		}
+	})
+
+	log.Printf("Metrics listening on :%d", port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		log.Printf("Metrics server stopped: %v", err)
+	}
+}
+
 // ==========================================
 // UI & Helpers (Simplified for Paid Bot)
 // ==========================================
@@ -961,22 +3303,27 @@ func getDisplayName(bot *tgbotapi.BotAPI, userID int64) string {
 	}
 
 	// Avoid calling bot.GetChat to remain compatible with different
-	// versions of the telegram library on target systems. Return a
-	// simple fallback display name based on the Telegram ID.
-	return fmt.Sprintf("Pengguna %d", userID)
+	// versions of the telegram library on target systems; the registry
+	// already has the name from the user's last update.
+	if u, ok := botUsers.Get(userID); ok && u.FirstName != "" {
+		return u.FirstName
+	}
+	return tr(userID, "Pengguna %d", userID)
 }
 
 func showMainMenu(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, requesterID ...int64) {
 	ipInfo, _ := getIpInfo()
 	domain := config.Domain
 	if domain == "" {
-		domain = "(Not Configured)"
+		domain = tr(chatID, "(Belum Diatur)")
 	}
 
 	// Accept optional requesterID so admin buttons still appear even in group chats.
-	isAdmin := chatID == config.AdminID
-	if len(requesterID) > 0 && requesterID[0] == config.AdminID {
-		isAdmin = true
+	isAdmin := isStaff(config, chatID)
+	isOwnerChat := isOwner(config, chatID)
+	if len(requesterID) > 0 {
+		isAdmin = isAdmin || isStaff(config, requesterID[0])
+		isOwnerChat = isOwnerChat || isOwner(config, requesterID[0])
 	}
 
 	// Greeting and stats
@@ -987,7 +3334,7 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, request
 		userName = getDisplayName(bot, userID)
 	}
 	if userName == "" {
-		userName = "Pengguna"
+		userName = tr(chatID, "Pengguna")
 	}
 
 	balance := 0
@@ -1003,46 +3350,36 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, request
 
 	todayCount, weekCount, monthCount, _ := computeMetrics()
 
-			msgText := fmt.Sprintf(`━━━━━━━━━━━━━━━━━━━━━
-		RyyStore Zivpn UDP
-	━━━━━━━━━━━━━━━━━━━━━
-	Hai %s
-	Saldo Anda : Rp %d
-	Akun dibuat oleh Anda : %d
-	Statistik : Hari ini %d • Minggu ini %d • Bulan ini %d
-	━━━━━━━━━━━━━━━━━━━━━
-	 • Domain   : %s
-	 • City     : %s
-	 • ISP      : %s
-	 • Harga    : Rp %d / Hari
-	━━━━━━━━━━━━━━━━━━━━━
-
-	Credit: [RyyStorevp1](https://t.me/RyyStorevp1)
-	Bot: [%s](https://t.me/%s)`, userName, balance, created, todayCount, weekCount, monthCount, domain, ipInfo.City, ipInfo.Isp, config.DailyPrice, bot.Self.UserName, bot.Self.UserName)
+	msgText := tr(chatID, "━━━━━━━━━━━━━━━━━━━━━\nRyyStore Zivpn UDP\n━━━━━━━━━━━━━━━━━━━━━\nHai %s\nSaldo Anda : Rp %d\nAkun dibuat oleh Anda : %d\nStatistik : Hari ini %d • Minggu ini %d • Bulan ini %d\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • Kota     : %s\n • ISP      : %s\n • Harga    : Rp %d / Hari\n━━━━━━━━━━━━━━━━━━━━━\n\nCredit: [RyyStorevp1](https://t.me/RyyStorevp1)\nBot: [%s](https://t.me/%s)", userName, balance, created, todayCount, weekCount, monthCount, domain, ipInfo.City, ipInfo.Isp, config.DailyPrice, bot.Self.UserName, bot.Self.UserName)
 
 	msg := tgbotapi.NewMessage(chatID, msgText)
 	msg.ParseMode = "Markdown"
 
 	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🛒 Beli Akun Premium", "menu_create"),
-			tgbotapi.NewInlineKeyboardButtonData("💳 Topup Saldo", "menu_topup"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🛒 Beli Akun Premium"), "menu_create"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "💳 Topup Saldo"), "menu_topup"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🆓 Trial Akun", "menu_trial"),
-			tgbotapi.NewInlineKeyboardButtonData("🔁 Renew Akun", "menu_renew"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🆓 Trial Akun"), "menu_trial"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔁 Perpanjang Akun"), "menu_renew"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📋 List Akun", "menu_list"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📇 Akun Saya"), "menu_my_accounts"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📋 Daftar Akun"), "menu_list"),
 		),
 	}
 
 	if isAdmin {
 		rows = append(rows,
-			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("📊 System Info", "menu_info")),
-			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🛠️ Admin Panel", "menu_admin")),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📊 Info Sistem"), "menu_info")),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🛠️ Panel Admin"), "menu_admin")),
 		)
 	}
+	if isOwnerChat {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "👑 Kelola Staff"), "menu_staff")))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(LanguageButton, "menu_lang")))
 
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	sendAndTrack(bot, msg)
@@ -1051,19 +3388,22 @@ func showMainMenu(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig, request
 func sendAccountInfo(bot *tgbotapi.BotAPI, chatID int64, data map[string]interface{}, config *BotConfig) {
 	domain := config.Domain
 	if domain == "" {
-		domain = "(Not Configured)"
+		domain = tr(chatID, "(Belum Diatur)")
 	}
 
 	// Prefer API-provided fields if available; avoid showing server IP
 	pwd := data["password"]
 	exp := data["expired"]
 
-	msg := fmt.Sprintf("```\n━━━━━━━━━━━━━━━━━━━━━\n  PREMIUM ACCOUNT\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nDomain     : %s\nExpired On : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\nTerima kasih telah berlangganan!",
+	msg := tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n  AKUN PREMIUM\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nDomain     : %s\nMasa Aktif : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\nTerima kasih telah berlangganan!",
 		pwd, domain, exp,
 	)
 
 	reply := tgbotapi.NewMessage(chatID, msg)
 	reply.ParseMode = "Markdown"
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📇 Detail & QR"), fmt.Sprintf("acct:%v", pwd)),
+	))
 	deleteLastMessage(bot, chatID)
 	bot.Send(reply)
 	showMainMenu(bot, chatID, config, chatID)
@@ -1071,52 +3411,46 @@ func sendAccountInfo(bot *tgbotapi.BotAPI, chatID int64, data map[string]interfa
 
 // Start trial flow: ask for desired password then create a 1-day account
 func startTrial(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "trial_password"
-	mutex.Lock()
-	tempUserData[userID] = make(map[string]string)
-	tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-	mutex.Unlock()
-	sendMessage(bot, chatID, "🆓 Trial Akun\nSilakan masukkan password yang diinginkan (3-20 karakter):")
+	states.SetState(userID, "trial_password")
+	states.Reset(userID, map[string]string{"chat_id": strconv.FormatInt(chatID, 10)})
+	sendMessage(bot, chatID, tr(chatID, "🆓 Trial Akun\nSilakan masukkan password yang diinginkan (3-20 karakter):"))
 }
 
 // Start renew flow: ask for password then duration
 func startRenew(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "renew_password"
-	mutex.Lock()
-	tempUserData[userID] = make(map[string]string)
-	tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-	mutex.Unlock()
-	sendMessage(bot, chatID, "🔁 Renew Akun\nSilakan masukkan password akun yang ingin diperpanjang:")
+	states.SetState(userID, "renew_password")
+	states.Reset(userID, map[string]string{"chat_id": strconv.FormatInt(chatID, 10)})
+	sendMessage(bot, chatID, tr(chatID, "🔁 Perpanjang Akun\nSilakan masukkan password akun yang ingin diperpanjang:"))
 }
 
 // List accounts: call API and show a formatted list
 func listAccounts(bot *tgbotapi.BotAPI, chatID int64) {
 	res, err := apiCall("GET", "/users", nil)
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
 		return
 	}
 	if res["success"] != true {
-		replyError(bot, chatID, fmt.Sprintf("Gagal mengambil daftar: %v", res["message"]))
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil daftar: %v", res["message"]))
 		return
 	}
 	data := res["data"]
 	usersArr, ok := data.([]interface{})
 	if !ok {
-		replyError(bot, chatID, "Format data tidak sesuai dari API")
+		replyError(bot, chatID, tr(chatID, "Format data tidak sesuai dari API"))
 		return
 	}
 
 	if len(usersArr) == 0 {
-		sendMessage(bot, chatID, "📋 Daftar akun kosong.")
+		sendMessage(bot, chatID, tr(chatID, "📋 Daftar akun kosong."))
 		return
 	}
 
 	var b strings.Builder
-	b.WriteString("📋 Daftar Akun:\n")
+	b.WriteString(tr(chatID, "📋 Daftar Akun:\n"))
 	for i, u := range usersArr {
 		if i >= 200 { // safety cap
-			b.WriteString("\n...dan masih banyak lagi...\n")
+			b.WriteString(tr(chatID, "\n...dan masih banyak lagi...\n"))
 			break
 		}
 		m, ok := u.(map[string]interface{})
@@ -1125,44 +3459,57 @@ func listAccounts(bot *tgbotapi.BotAPI, chatID int64) {
 		}
 		pwd := m["password"]
 		exp := m["expired"]
-		status := m["status"]
-		b.WriteString(fmt.Sprintf("%d. %s — Exp: %s — %s\n", i+1, pwd, exp, status))
+		status := fmt.Sprintf("%v", m["status"])
+		b.WriteString(tr(chatID, "%d. %s — Aktif s/d: %s — %s\n", i+1, pwd, exp, statusLabel(chatID, status)))
 	}
 
 	sendMessage(bot, chatID, b.String())
 }
 
+// createFreeAccount creates an admin-owned account without touching any wallet.
+func createFreeAccount(bot *tgbotapi.BotAPI, chatID int64, userID int64, password string, days int, config *BotConfig) bool {
+	res, err := apiCall("POST", "/user/create", map[string]interface{}{
+		"password": password,
+		"days":     days,
+	})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return false
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal membuat akun: %s", res["message"]))
+		return false
+	}
+	data := res["data"].(map[string]interface{})
+	sendMessage(bot, chatID, tr(chatID, "✅ Akun gratis dibuat: %s\nExpired: %s", data["password"], data["expired"]))
+	return true
+}
+
 // Admin: start free-account creation flow
 func startAdminCreateFree(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
-	if userID != config.AdminID {
-		replyError(bot, chatID, "Hanya admin yang dapat melakukan ini.")
+	if !can(config, userID, PermUsersManage) {
+		replyError(bot, chatID, tr(chatID, "Anda tidak memiliki izin untuk ini."))
 		return
 	}
-	userStates[userID] = "admin_create_password"
-	mutex.Lock()
-	tempUserData[userID] = make(map[string]string)
-	tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-	mutex.Unlock()
-	sendMessage(bot, chatID, "➕ Buat Akun Gratis\nMasukkan password untuk akun baru (3-20 karakter):")
+	states.SetState(userID, "admin_create_password")
+	states.Reset(userID, map[string]string{"chat_id": strconv.FormatInt(chatID, 10)})
+	sendMessage(bot, chatID, tr(chatID, "➕ Buat Akun Gratis\nMasukkan password untuk akun baru (3-20 karakter):"))
 }
 
 // ---------------- Topup flow ----------------
 func startTopup(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "topup_amount"
-	mutex.Lock()
-	tempUserData[userID] = make(map[string]string)
-	tempUserData[userID]["chat_id"] = strconv.FormatInt(chatID, 10)
-	mutex.Unlock()
-	sendMessage(bot, chatID, "💳 Topup Saldo\nMasukkan jumlah topup minimal Rp 5000 (contoh: 5000):")
+	states.SetState(userID, "topup_amount")
+	states.Reset(userID, map[string]string{"chat_id": strconv.FormatInt(chatID, 10)})
+	sendMessage(bot, chatID, tr(chatID, "💳 Topup Saldo\nMasukkan jumlah topup minimal Rp 5000 (contoh: 5000):"))
 }
 
 
 
 func sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	if _, inState := userStates[chatID]; inState {
+	if states.State(chatID) != "" {
 		cancelKb := tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "cancel")),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "cancel")),
 		)
 		msg.ReplyMarkup = cancelKb
 	}
@@ -1182,250 +3529,668 @@ func sendAndTrack(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig) {
 	deleteLastMessage(bot, msg.ChatID)
 	sentMsg, err := bot.Send(msg)
 	if err == nil {
-		lastMessageIDs[msg.ChatID] = sentMsg.MessageID
+		states.SetLastMessage(msg.ChatID, sentMsg.MessageID)
+	} else if isBlockedError(err) {
+		botUsers.MarkBlocked(msg.ChatID)
 	}
 }
 
 func deleteLastMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	if msgID, ok := lastMessageIDs[chatID]; ok {
+	if msgID, ok := states.TakeLastMessage(chatID); ok {
 		deleteMsg := tgbotapi.NewDeleteMessage(chatID, msgID)
 		bot.Request(deleteMsg)
-		delete(lastMessageIDs, chatID)
 	}
 }
 
 func resetState(userID int64) {
-	delete(userStates, userID)
-	// Don't delete tempUserData immediately if pending payment, but here we do for cancel
+	// Collected data stays until the next flow resets it; pending payments
+	// are kept separately and are not affected
+	states.ClearState(userID)
 }
 
 func validatePassword(bot *tgbotapi.BotAPI, chatID int64, text string) bool {
 	if len(text) < 3 || len(text) > 20 {
-		sendMessage(bot, chatID, "❌ Password harus 3-20 karakter. Coba lagi:")
+		sendMessage(bot, chatID, tr(chatID, "❌ Password harus 3-20 karakter. Coba lagi:"))
 		return false
 	}
 	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(text) {
-		sendMessage(bot, chatID, "❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:")
+		sendMessage(bot, chatID, tr(chatID, "❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:"))
 		return false
 	}
 	return true
 }
 
-func validateNumber(bot *tgbotapi.BotAPI, chatID int64, text string, min, max int, fieldName string) (int, bool) {
-	val, err := strconv.Atoi(text)
-	if err != nil || val < min || val > max {
-		sendMessage(bot, chatID, fmt.Sprintf("❌ %s harus angka positif (%d-%d). Coba lagi:", fieldName, min, max))
-		return 0, false
+func validateNumber(bot *tgbotapi.BotAPI, chatID int64, text string, min, max int, fieldName string) (int, bool) {
+	val, err := strconv.Atoi(text)
+	if err != nil || val < min || val > max {
+		sendMessage(bot, chatID, tr(chatID, "❌ %s harus angka positif (%d-%d). Coba lagi:", tr(chatID, fieldName), min, max))
+		return 0, false
+	}
+	return val, true
+}
+
+func systemInfo(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
+	res, err := apiCall("GET", "/info", nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+
+	if res["success"] == true {
+		data := res["data"].(map[string]interface{})
+		ipInfo, _ := getIpInfo()
+
+		var info struct {
+			Uptime int64      `json:"uptime_seconds"`
+			Load   [3]float64 `json:"load"`
+			CPU    struct {
+				Cores        int     `json:"cores"`
+				UsagePercent float64 `json:"usage_percent"`
+			} `json:"cpu"`
+			Memory struct {
+				Total uint64 `json:"total"`
+				Used  uint64 `json:"used"`
+			} `json:"memory"`
+		}
+		dataBytes, _ := json.Marshal(data)
+		json.Unmarshal(dataBytes, &info)
+		uptime := time.Duration(info.Uptime) * time.Second
+
+		msg := tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Publik      : %s\nPort           : %s\nLayanan        : %s\nKota           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```",
+			config.Domain, data["public_ip"], data["port"], data["service"], ipInfo.City, ipInfo.Isp,
+			int(uptime.Hours())/24, int(uptime.Hours())%24,
+			info.CPU.Cores, info.CPU.UsagePercent,
+			info.Load[0], info.Load[1], info.Load[2],
+			info.Memory.Used/1024/1024, info.Memory.Total/1024/1024)
+
+		reply := tgbotapi.NewMessage(chatID, msg)
+		reply.ParseMode = "Markdown"
+		deleteLastMessage(bot, chatID)
+		bot.Send(reply)
+		showMainMenu(bot, chatID, config, chatID)
+	} else {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil info."))
+	}
+}
+
+func showBackupRestoreMenu(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "🛠️ *Panel Admin*\nSilakan pilih menu:"))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬇️ Backup Data"), "menu_backup_action"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⬆️ Restore Data"), "menu_restore_action"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "➕ Buat Akun Gratis"), "menu_admin_create_free"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔒 Enkripsi Backup"), "menu_backup_encryption"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🧾 Kelola User"), "menu_admin_manage"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⚙️ Service Core"), "menu_service"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔐 Ubah Obfs"), "menu_obfs"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗓️ Backup Otomatis"), "menu_backup_schedule"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func showBackupEncryption(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/settings", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil pengaturan backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+
+	passphrase := tr(chatID, "❌ Tidak aktif")
+	if data["passphrase_set"] == true {
+		passphrase = tr(chatID, "✅ Aktif")
+	}
+	recipients, _ := data["recipients"].([]interface{})
+	identity := tr(chatID, "Tidak ada")
+	if data["server_identity"] == true {
+		identity = tr(chatID, "Tersimpan di server")
+	}
+
+	text := tr(chatID, "🔒 Enkripsi Backup\n\nPassphrase: %s\nRecipient key: %d\nSecret key: %s\n\nRecipient key diatur lewat API /api/backup/settings.", passphrase, len(recipients), identity)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔑 Atur Passphrase"), "backup_pass_set"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🚫 Hapus Passphrase"), "backup_pass_clear"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func setBackupPassphrase(bot *tgbotapi.BotAPI, chatID int64, passphrase string) {
+	res, err := apiCall("PUT", "/backup/settings", map[string]string{"passphrase": passphrase})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan passphrase: %s", res["message"]))
+		return
+	}
+	if passphrase == "" {
+		sendMessage(bot, chatID, tr(chatID, "✅ Passphrase backup dihapus."))
+	} else {
+		sendMessage(bot, chatID, tr(chatID, "✅ Passphrase backup disimpan. Backup berikutnya akan terenkripsi."))
+	}
+	showBackupEncryption(bot, chatID)
+}
+
+// showBackupSchedule shows the scheduled backup status from /backup/status.
+func showBackupSchedule(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/status", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil status backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+
+	schedule := tr(chatID, "❌ Tidak aktif")
+	if s, _ := data["schedule"].(string); s != "" {
+		schedule = s
+	}
+	nextRun := "-"
+	if s, ok := data["next_run"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			nextRun = t.Local().Format("02 Jan 2006 15:04")
+		}
+	} else if s, _ := data["schedule"].(string); s != "" {
+		nextRun = tr(chatID, "Tidak ada jadwal berikutnya")
+	}
+	lastRun := tr(chatID, "Belum pernah")
+	if run, ok := data["last_run"].(map[string]interface{}); ok {
+		when := fmt.Sprintf("%v", run["time"])
+		if t, err := time.Parse(time.RFC3339, when); err == nil {
+			when = t.Local().Format("02 Jan 2006 15:04")
+		}
+		if run["success"] == true {
+			lastRun = fmt.Sprintf("✅ %s (%s)", when, formatBytes(run["size"]))
+		} else {
+			lastRun = fmt.Sprintf("❌ %s: %v", when, run["error"])
+		}
+	}
+	daily, _ := data["daily"].([]interface{})
+	weekly, _ := data["weekly"].([]interface{})
+	telegram := "OFF"
+	if data["telegram"] == true {
+		telegram = "ON"
+	}
+	encrypted := tr(chatID, "Tidak")
+	if data["encrypted"] == true {
+		encrypted = tr(chatID, "Ya")
+	}
+
+	text := tr(chatID, "🗓️ Backup Otomatis\n\nJadwal: %s\nBerikutnya: %s\nTerakhir: %s\nHarian: %d/%v\nMingguan: %d/%v\nTerenkripsi: %s\nKirim ke Telegram: %s\n\nFile disimpan di /var/backups/zivpn",
+		schedule, nextRun, lastRun, len(daily), data["keep_daily"], len(weekly), data["keep_weekly"], encrypted, telegram)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Backup Sekarang"), "backup_run_now"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📨 Telegram: ")+telegram, "backup_tg_toggle"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🕒 Ubah Jadwal"), "backup_schedule_set"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🗂️ Retensi"), "backup_keep_set"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func runBackupNow(bot *tgbotapi.BotAPI, chatID int64) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang membuat backup..."))
+	res, err := apiCall("POST", "/backup/run", nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, fmt.Sprintf("%v", res["message"]))
+		return
+	}
+	showBackupSchedule(bot, chatID)
+}
+
+func toggleBackupTelegram(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/backup/settings", nil)
+	if err != nil || res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal mengambil pengaturan backup."))
+		return
+	}
+	data, _ := res["data"].(map[string]interface{})
+	updateBackupSettings(bot, chatID, map[string]interface{}{"telegram": data["telegram"] != true})
+}
+
+func updateBackupSettings(bot *tgbotapi.BotAPI, chatID int64, settings map[string]interface{}) {
+	res, err := apiCall("PUT", "/backup/settings", settings)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan: %s", res["message"]))
+		return
+	}
+	showBackupSchedule(bot, chatID)
+}
+
+func formatBytes(v interface{}) string {
+	size, _ := v.(float64)
+	if size >= 1024*1024 {
+		return fmt.Sprintf("%.1f MB", size/1024/1024)
+	}
+	return fmt.Sprintf("%.1f KB", size/1024)
+}
+
+func showServiceMenu(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "⚙️ *Service Core*\nSilakan pilih aksi:"))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📟 Status"), "svc_status"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📜 Log"), "svc_logs"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "🔄 Restart"), "svc_restart"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "▶️ Start"), "svc_start"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⏹️ Stop"), "svc_stop"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func confirmServiceStop(bot *tgbotapi.BotAPI, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "❓ Yakin ingin menghentikan zivpn.service? Semua user akan terputus."))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Ya, Stop"), "svc_stop_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "menu_service"),
+		),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func serviceStatus(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/service/status", nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+
+	data, _ := res["data"].(map[string]interface{})
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "```\n━━━━━━━━━━━━━━━━━━━━━\n    STATUS ZIVPN\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSejak    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```",
+		data["ActiveState"], data["SubState"], data["MainPID"], data["ActiveEnterTimestamp"], data["NRestarts"]))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "menu_service")),
+	)
+	sendAndTrack(bot, msg)
+}
+
+func serviceAction(bot *tgbotapi.BotAPI, chatID int64, action string) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Menjalankan %s...", action))
+	res, err := apiCall("POST", "/service/"+action, nil)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
 	}
-	return val, true
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+	serviceStatus(bot, chatID)
 }
 
-func systemInfo(bot *tgbotapi.BotAPI, chatID int64, config *BotConfig) {
-	res, err := apiCall("GET", "/info", nil)
+func serviceLogs(bot *tgbotapi.BotAPI, chatID int64) {
+	res, err := apiCall("GET", "/logs?unit=zivpn&lines=30", nil)
 	if err != nil {
-		replyError(bot, chatID, "Error API: "+err.Error())
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
 		return
 	}
 
-	if res["success"] == true {
-		data := res["data"].(map[string]interface{})
-		ipInfo, _ := getIpInfo()
-
-		msg := fmt.Sprintf("```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Public      : %s\nPort           : %s\nService        : %s\nCITY           : %s\nISP            : %s\n━━━━━━━━━━━━━━━━━━━━━\n```",
-			config.Domain, data["public_ip"], data["port"], data["service"], ipInfo.City, ipInfo.Isp)
-
-		reply := tgbotapi.NewMessage(chatID, msg)
-		reply.ParseMode = "Markdown"
-		deleteLastMessage(bot, chatID)
-		bot.Send(reply)
-		showMainMenu(bot, chatID, config, config.AdminID)
-	} else {
-		replyError(bot, chatID, "Gagal mengambil info.")
+	lines, _ := res["data"].([]interface{})
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(fmt.Sprintf("%v\n", l))
+	}
+	text := strings.ReplaceAll(b.String(), "`", "'")
+	if text == "" {
+		text = tr(chatID, "(log kosong)\n")
+	}
+	// Telegram messages are capped at 4096 characters
+	if len(text) > 3800 {
+		cut := len(text) - 3800
+		for cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut++
+		}
+		text = "...\n" + text[cut:]
 	}
-}
 
-func showBackupRestoreMenu(bot *tgbotapi.BotAPI, chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "🛠️ *Admin Panel*\nSilakan pilih menu:")
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "📜 Log zivpn.service\n```\n")+text+"```")
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⬇️ Backup Data", "menu_backup_action"),
-			tgbotapi.NewInlineKeyboardButtonData("⬆️ Restore Data", "menu_restore_action"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("➕ Buat Akun Gratis", "menu_admin_create_free"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🧾 Manage Users", "menu_admin_manage"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Kembali", "cancel"),
-		),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "menu_service")),
 	)
 	sendAndTrack(bot, msg)
 }
 
+func startEditObfs(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	current := "-"
+	if res, err := apiCall("GET", "/config", nil); err == nil && res["success"] == true {
+		if data, ok := res["data"].(map[string]interface{}); ok {
+			current = fmt.Sprintf("%v", data["obfs"])
+		}
+	}
+	states.SetState(userID, "admin_obfs_input")
+	sendMessage(bot, chatID, tr(chatID, "🔐 Obfs saat ini: %s\nMasukkan obfs baru (1-64 karakter, tanpa spasi):", current))
+}
+
+func updateObfs(bot *tgbotapi.BotAPI, chatID int64, obfs string) {
+	sendMessage(bot, chatID, tr(chatID, "⏳ Menerapkan config dan merestart service..."))
+	res, err := apiCall("PUT", "/config", map[string]interface{}{"obfs": obfs})
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Gagal: %s", res["message"]))
+		return
+	}
+	sendMessage(bot, chatID, tr(chatID, "✅ Obfs diubah menjadi `%s`. Client harus memakai obfs baru.", obfs))
+}
+
 func showAdminManageMenu(bot *tgbotapi.BotAPI, chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "⚙️ *Admin - Manage Users*\nPilih aksi:")
+	msg := tgbotapi.NewMessage(chatID, tr(chatID, "⚙️ *Admin - Kelola User*\nPilih aksi:"))
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("➕ Tambah Saldo", "admin_add_balance"),
-			tgbotapi.NewInlineKeyboardButtonData("➖ Kurangi Saldo", "admin_remove_balance"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "➕ Tambah Saldo"), "admin_add_balance"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "➖ Kurangi Saldo"), "admin_remove_balance"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "⛔ Ban User"), "admin_ban"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Unban User"), "admin_unban"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⛔ Ban User", "admin_ban"),
-			tgbotapi.NewInlineKeyboardButtonData("✅ Unban User", "admin_unban"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📈 Lihat Aktivitas"), "admin_view_activity"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📨 Mode Forward"), "admin_forward_mode"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📈 Lihat Aktivitas", "admin_view_activity"),
-			tgbotapi.NewInlineKeyboardButtonData("📨 Mode Forward", "admin_forward_mode"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "📣 Broadcast"), "admin_broadcast"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "👥 Pengguna Bot"), "menu_bot_users"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Kembali", "cancel"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Kembali"), "cancel"),
 		),
 	)
 	sendAndTrack(bot, msg)
 }
 
 func performBackup(bot *tgbotapi.BotAPI, chatID int64) {
-	sendMessage(bot, chatID, "⏳ Sedang membuat backup...")
-
-	// Files to backup
-	files := []string{
-		"/etc/zivpn/config.json",
-		"/etc/zivpn/users.json",
-		"/etc/zivpn/domain",
-	}
-
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
-
-	for _, file := range files {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			continue
-		}
-
-		f, err := os.Open(file)
-		if err != nil {
-			continue
-		}
-		defer f.Close()
-
-		w, err := zipWriter.Create(filepath.Base(file))
-		if err != nil {
-			continue
-		}
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang membuat backup..."))
 
-		if _, err := io.Copy(w, f); err != nil {
-			continue
-		}
+	data, err := apiDownload("/backup")
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal membuat backup: ")+err.Error())
+		return
 	}
 
-	zipWriter.Close()
-
 	fileName := fmt.Sprintf("zivpn-backup-%s.zip", time.Now().Format("20060102-150405"))
+	caption := tr(chatID, "✅ Backup Data ZiVPN")
+	if bytes.HasPrefix(data, []byte(EncryptedMagic)) {
+		fileName += ".enc"
+		caption += tr(chatID, " (🔒 terenkripsi)")
+	}
 	
 	// Create a temporary file for the upload
 	tmpFile := "/tmp/" + fileName
-	if err := ioutil.WriteFile(tmpFile, buf.Bytes(), 0644); err != nil {
-		replyError(bot, chatID, "Gagal membuat file backup.")
+	if err := ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal membuat file backup."))
 		return
 	}
 	defer os.Remove(tmpFile)
 
 	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(tmpFile))
-	doc.Caption = "✅ Backup Data ZiVPN"
+	doc.Caption = caption
 	
 	deleteLastMessage(bot, chatID)
 	bot.Send(doc)
 }
 
 func startRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
-	userStates[userID] = "waiting_restore_file"
-	sendMessage(bot, chatID, "⬆️ *Restore Data*\n\nSilakan kirim file ZIP backup Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!")
+	states.SetState(userID, "waiting_restore_file")
+	sendMessage(bot, chatID, tr(chatID, "⬆️ *Restore Data*\n\nSilakan kirim file backup (.zip atau .zip.enc) Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!"))
+}
+
+func restoreTempFile(userID int64) string {
+	return fmt.Sprintf("/tmp/zivpn-restore-%d.zip", userID)
 }
 
+// processRestoreFile downloads the uploaded archive and asks the API for a
+// dry run, so the admin sees what will change before confirming.
 func processRestoreFile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, config *BotConfig) {
 	chatID := msg.Chat.ID
 	userID := msg.From.ID
 	
 	resetState(userID)
-	sendMessage(bot, chatID, "⏳ Sedang memproses file...")
+	if msg.Document.FileSize > MaxRestoreSize {
+		replyError(bot, chatID, tr(chatID, "File terlalu besar (maksimal %d MB).", MaxRestoreSize>>20))
+		return
+	}
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang memproses file..."))
 
 	// Download file
 	fileID := msg.Document.FileID
 	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
 	if err != nil {
-		replyError(bot, chatID, "Gagal mengunduh file.")
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh file."))
 		return
 	}
 
 	fileUrl := file.Link(config.BotToken)
 	resp, err := http.Get(fileUrl)
 	if err != nil {
-		replyError(bot, chatID, "Gagal mengunduh file content.")
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh isi file."))
 		return
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		replyError(bot, chatID, tr(chatID, "Gagal mengunduh isi file."))
+		return
+	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxRestoreSize+1))
 	if err != nil {
-		replyError(bot, chatID, "Gagal membaca file.")
+		replyError(bot, chatID, tr(chatID, "Gagal membaca file."))
+		return
+	}
+	if len(body) > MaxRestoreSize {
+		replyError(bot, chatID, tr(chatID, "File terlalu besar (maksimal %d MB).", MaxRestoreSize>>20))
+		return
+	}
+
+	if err := ioutil.WriteFile(restoreTempFile(userID), body, 0600); err != nil {
+		replyError(bot, chatID, tr(chatID, "Gagal menyimpan file sementara."))
 		return
 	}
 
-	// Unzip
-	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	previewRestore(bot, chatID, userID, "")
+}
+
+// previewRestore runs a dry run of the saved upload. Encrypted backups ask
+// for a passphrase or secret key first; the secret is kept until the admin
+// confirms or cancels.
+func previewRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, secret string) {
+	body, err := ioutil.ReadFile(restoreTempFile(userID))
 	if err != nil {
-		replyError(bot, chatID, "File bukan format ZIP yang valid.")
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "File restore tidak ditemukan, silakan kirim ulang."))
 		return
 	}
 
-	for _, f := range zipReader.File {
-		// Security check: only allow specific files
-		validFiles := map[string]bool{
-			"config.json": true,
-			"users.json": true,
-			"bot-config.json": true,
-			"domain": true,
-			"apikey": true,
-		}
-		
-		if !validFiles[f.Name] {
-			continue
+	res, err := apiUpload("/restore?dry_run=1", body, secret)
+	if err != nil {
+		resetState(userID)
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		if data, ok := res["data"].(map[string]interface{}); ok && data["encrypted"] == true {
+			states.SetState(userID, "waiting_restore_secret")
+			text := tr(chatID, "🔒 Backup terenkripsi.\nKirim passphrase atau secret key (ZIVPNSEC1...):")
+			if secret != "" {
+				text = fmt.Sprintf("❌ %s\n\n%s", res["message"], text)
+			}
+			reply := tgbotapi.NewMessage(chatID, text)
+			reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "restore_cancel"),
+				),
+			)
+			sendAndTrack(bot, reply)
+			return
 		}
+		resetState(userID)
+		os.Remove(restoreTempFile(userID))
+		replyError(bot, chatID, tr(chatID, "Backup ditolak: %s", res["message"]))
+		return
+	}
 
-		rc, err := f.Open()
-		if err != nil {
-			continue
-		}
-		defer rc.Close()
+	states.ClearState(userID)
+	states.SetSecret(userID, secret)
 
-		dstPath := filepath.Join("/etc/zivpn", f.Name)
-		dst, err := os.Create(dstPath)
-		if err != nil {
-			continue
-		}
-		defer dst.Close()
+	report, _ := res["data"].(map[string]interface{})
+	text := tr(chatID, "🔍 Pratinjau Restore\n\n") + formatRestoreReport(chatID, report)
+	if report["encrypted"] == true {
+		text += tr(chatID, "\n🔒 Backup terenkripsi berhasil dibuka.")
+	}
+	if report["legacy"] == true {
+		text += tr(chatID, "\n⚠️ Backup lama tanpa manifest, checksum tidak diverifikasi.")
+	}
+	text += tr(chatID, "\n\nLanjutkan restore?")
 
-		io.Copy(dst, rc)
+	reply := tgbotapi.NewMessage(chatID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "✅ Ya, Restore"), "restore_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(chatID, "❌ Batal"), "restore_cancel"),
+		),
+	)
+	sendAndTrack(bot, reply)
+}
+
+func confirmRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	path := restoreTempFile(userID)
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "File restore tidak ditemukan, silakan kirim ulang."))
+		return
 	}
+	os.Remove(path)
+	secret := states.TakeSecret(userID)
 
-	// Restart Services
-	exec.Command("systemctl", "restart", "zivpn").Run()
-	exec.Command("systemctl", "restart", "zivpn-api").Run()
-	
-	msgSuccess := tgbotapi.NewMessage(chatID, "✅ Restore Berhasil!\nService ZiVPN, API, dan Bot telah direstart.")
+	sendMessage(bot, chatID, tr(chatID, "⏳ Sedang merestore data..."))
+	res, err := apiUpload("/restore", body, secret)
+	if err != nil {
+		replyError(bot, chatID, tr(chatID, "Error API: ")+err.Error())
+		return
+	}
+	if res["success"] != true {
+		replyError(bot, chatID, tr(chatID, "Restore gagal: %s", res["message"]))
+		return
+	}
+
+	report, _ := res["data"].(map[string]interface{})
+	msgSuccess := tgbotapi.NewMessage(chatID, tr(chatID, "✅ Restore Berhasil!\n\n")+formatRestoreReport(chatID, report))
+	deleteLastMessage(bot, chatID)
 	bot.Send(msgSuccess)
 
-	// Restart Bot with delay to allow message sending
-	go func() {
-		time.Sleep(2 * time.Second)
-		exec.Command("systemctl", "restart", "zivpn-bot").Run()
-	}()
+	restartBot := false
+	if services, ok := report["restart_required"].([]interface{}); ok {
+		for _, svc := range services {
+			if svc == "zivpn-bot" {
+				restartBot = true
+			}
+		}
+	}
+	if restartBot {
+		go func() {
+			time.Sleep(2 * time.Second)
+			exec.Command("systemctl", "restart", "zivpn-bot").Run()
+		}()
+	}
+
+	showMainMenu(bot, chatID, config, chatID)
+}
+
+func cancelRestore(bot *tgbotapi.BotAPI, chatID int64, userID int64, config *BotConfig) {
+	os.Remove(restoreTempFile(userID))
+	states.TakeSecret(userID)
+	cancelOperation(bot, chatID, userID, config)
+}
+
+func formatRestoreReport(chatID int64, report map[string]interface{}) string {
+	join := func(key string) string {
+		items := []string{}
+		if list, ok := report[key].([]interface{}); ok {
+			for _, v := range list {
+				items = append(items, fmt.Sprintf("%v", v))
+			}
+		}
+		if len(items) == 0 {
+			return "-"
+		}
+		return strings.Join(items, ", ")
+	}
+	text := tr(chatID, "File: %s\nUser ditambah: %s\nUser dihapus: %s", join("files"), join("users_added"), join("users_removed"))
+	if skipped := join("skipped"); skipped != "-" {
+		text += tr(chatID, "\nDilewati: ") + skipped
+	}
+	if safety, ok := report["safety_backup"].(string); ok && safety != "" {
+		text += tr(chatID, "\nBackup pengaman: ") + safety
+	}
+	return text
+}
 
-	showMainMenu(bot, chatID, config, config.AdminID)
+// saveConfig keeps the file private since it holds the Pakasir API key.
+// saveConfig writes the config atomically. Callers hold configMu.
+func saveConfig(config *BotConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := BotConfigFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, BotConfigFile)
 }
 
 func loadConfig() (BotConfig, error) {
@@ -1445,6 +4210,22 @@ func loadConfig() (BotConfig, error) {
 	return config, err
 }
 
+func getUsers() ([]UserData, error) {
+	res, err := apiCall("GET", "/users", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res["success"] != true {
+		return nil, fmt.Errorf("failed to get users")
+	}
+
+	var users []UserData
+	dataBytes, _ := json.Marshal(res["data"])
+	json.Unmarshal(dataBytes, &users)
+	return users, nil
+}
+
 func apiCall(method, endpoint string, payload interface{}) (map[string]interface{}, error) {
 	var reqBody []byte
 	var err error
@@ -1478,16 +4259,478 @@ func apiCall(method, endpoint string, payload interface{}) (map[string]interface
 	return result, nil
 }
 
+// watchApiEvents follows the API's /api/events stream and tells the admin
+// about events that need attention, reconnecting with Last-Event-ID so no
+// event is missed across API restarts.
+func watchApiEvents(bot *tgbotapi.BotAPI, config *BotConfig) {
+	lastID := ""
+	for {
+		err := followApiEvents(&lastID, func(eventType string, data map[string]interface{}) {
+			notifyAdminEvent(bot, config, eventType, data)
+		})
+		if err != nil {
+			log.Printf("Event stream terputus: %v", err)
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func followApiEvents(lastID *string, handle func(string, map[string]interface{})) error {
+	req, err := http.NewRequest("GET", ApiUrl+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", ApiKey)
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var id, eventType, payload string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if payload != "" {
+				var ev struct {
+					Data map[string]interface{} `json:"data"`
+				}
+				if json.Unmarshal([]byte(payload), &ev) == nil {
+					handle(eventType, ev.Data)
+				}
+			}
+			if id != "" {
+				*lastID = id
+			}
+			id, eventType, payload = "", "", ""
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			payload += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func notifyAdminEvent(bot *tgbotapi.BotAPI, config *BotConfig, eventType string, data map[string]interface{}) {
+	admin := config.AdminID
+	var text string
+	switch eventType {
+	case "expire.run":
+		count, _ := data["revoked_count"].(float64)
+		if count == 0 && data["success"] != false {
+			return
+		}
+		names := []string{}
+		if list, ok := data["revoked"].([]interface{}); ok {
+			for _, p := range list {
+				names = append(names, fmt.Sprintf("%v", p))
+			}
+		}
+		text = tr(admin, "⏰ Pemeriksaan expired: %d akun dicabut.\n%s", int(count), strings.Join(names, "\n"))
+		if data["success"] == false {
+			text += tr(admin, "\n⚠️ Sebagian akun gagal dicabut, cek log zivpn-api.")
+		}
+	case "service.restarted":
+		if data["success"] == true {
+			return
+		}
+		text = tr(admin, "⚠️ Restart zivpn.service gagal: %v", data["error"])
+	case "backup.created":
+		if data["success"] != true {
+			text = tr(admin, "⚠️ Backup otomatis gagal: %v", data["error"])
+			break
+		}
+		if data["telegram"] != true {
+			return
+		}
+		// The stream replays buffered events on reconnect; only forward fresh ones
+		if t, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", data["time"])); err != nil || time.Since(t) > 10*time.Minute {
+			return
+		}
+		doc := tgbotapi.NewDocument(admin, tgbotapi.FilePath(fmt.Sprintf("%v", data["file"])))
+		doc.Caption = tr(admin, "🗓️ Backup otomatis ZiVPN")
+		if data["encrypted"] == true {
+			doc.Caption += tr(admin, " (🔒 terenkripsi)")
+		}
+		if _, err := bot.Send(doc); err != nil {
+			log.Printf("Gagal mengirim backup ke admin: %v", err)
+		}
+		return
+	default:
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(admin, text))
+}
+
+// apiDownload fetches a binary API response such as /backup.
+func apiDownload(endpoint string) ([]byte, error) {
+	req, err := http.NewRequest("GET", ApiUrl+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", ApiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var result map[string]interface{}
+		json.Unmarshal(body, &result)
+		return nil, fmt.Errorf("%v", result["message"])
+	}
+	return body, nil
+}
+
+// apiUpload posts a backup archive to the API and decodes the JSON response.
+// secret, when set, is the passphrase or secret key of an encrypted backup.
+func apiUpload(endpoint string, data []byte, secret string) (map[string]interface{}, error) {
+	req, err := http.NewRequest("POST", ApiUrl+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("X-API-Key", ApiKey)
+	if secret != "" {
+		req.Header.Set("X-Backup-Secret", secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Server location rarely changes; cache it so menus render without waiting on ip-api.com
+var ipInfoCache struct {
+	sync.Mutex
+	info    IpInfo
+	fetched time.Time
+}
+
+const IpInfoTTL = 6 * time.Hour
+
 func getIpInfo() (IpInfo, error) {
-	resp, err := http.Get("http://ip-api.com/json/")
+	ipInfoCache.Lock()
+	defer ipInfoCache.Unlock()
+	if !ipInfoCache.fetched.IsZero() && time.Since(ipInfoCache.fetched) < IpInfoTTL {
+		return ipInfoCache.info, nil
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://ip-api.com/json/")
 	if err != nil {
-		return IpInfo{}, err
+		return ipInfoCache.info, err
 	}
 	defer resp.Body.Close()
 
 	var info IpInfo
 	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return IpInfo{}, err
+		return ipInfoCache.info, err
 	}
+	ipInfoCache.info = info
+	ipInfoCache.fetched = time.Now()
 	return info, nil
 }
+
+// ==========================================
+// Message Catalog (English)
+// ==========================================
+
+// catalogEN translates every message into English. Its keys double as the
+// list of messages in the template written to LangDir.
+var catalogEN = map[string]string{
+	"❌ Terjadi kesalahan internal. Silakan coba lagi.":                                                                                         "❌ Internal error. Please try again.",
+	"👑 Masukkan: <TelegramID> <role> [nama]\nContoh: 7251232303 support Budi\nRole: ":                                                          "👑 Enter: <TelegramID> <role> [name]\nExample: 7251232303 support Budi\nRoles: ",
+	"Gagal menyimpan config: ":                                                                                                                 "Failed to save config: ",
+	"🔍 Masukkan ID, username atau nama pengguna:":                                                                                              "🔍 Enter an ID, username or name:",
+	"Akun tidak ditemukan atau bukan milik Anda.":                                                                                              "Account not found or not yours.",
+	"🔁 Perpanjang %s\n⏳ Masukkan Durasi Perpanjangan (hari):":                                                                                  "🔁 Renew %s\n⏳ Enter the renewal duration (days):",
+	"🟢 Masukkan TelegramID dan jumlah untuk ditambahkan (contoh: 7251232303 50000):":                                                           "🟢 Enter the TelegramID and the amount to add (example: 7251232303 50000):",
+	"🔴 Masukkan TelegramID dan jumlah untuk dikurangkan (contoh: 7251232303 50000):":                                                           "🔴 Enter the TelegramID and the amount to deduct (example: 7251232303 50000):",
+	"⛔ Masukkan TelegramID untuk diban (contoh: 7251232303):":                                                                                  "⛔ Enter the TelegramID to ban (example: 7251232303):",
+	"✅ Masukkan TelegramID untuk di-unban (contoh: 7251232303):":                                                                               "✅ Enter the TelegramID to unban (example: 7251232303):",
+	"📈 Aktivitas: Hari ini %d • Minggu ini %d • Bulan ini %d":                                                                                  "📈 Activity: Today %d • This week %d • This month %d",
+	"⏳ Kirim ke pemilik akun yang expired dalam berapa hari? (1-365)":                                                                          "⏳ Send to owners of accounts expiring within how many days? (1-365)",
+	"📨 Silakan forward pesan dari pengguna ke chat ini. Setelah diterima, Anda akan diberi opsi tindakan.":                                     "📨 Please forward a message from the user to this chat. Once it arrives, you will be offered actions.",
+	"🔑 Masukkan passphrase backup baru (minimal 8 karakter):":                                                                                  "🔑 Enter the new backup passphrase (at least 8 characters):",
+	"🕒 Masukkan jadwal cron (menit jam tanggal bulan hari), contoh \"0 3 * * *\" untuk setiap hari jam 03:00.\nKetik \"off\" untuk mematikan.": "🕒 Enter a cron schedule (minute hour day month weekday), e.g. \"0 3 * * *\" for every day at 03:00.\nType \"off\" to turn it off.",
+	"🗂️ Masukkan jumlah backup harian dan mingguan yang disimpan (contoh: 7 4):":                                                               "🗂️ Enter how many daily and weekly backups to keep (e.g. 7 4):",
+	"⏳ Masukkan Durasi (hari)\nHarga: Rp %d / hari:":                                                                                           "⏳ Enter the duration (days)\nPrice: Rp %d / day:",
+	"⚠️ Saldo Anda: Rp %d. Diperlukan Rp %d untuk membuat akun %d hari. Silakan Topup minimal Rp 5000.":                                        "⚠️ Your balance: Rp %d. Rp %d is needed to create a %d-day account. Please top up at least Rp 5000.",
+	"Gagal memproses saldo: ": "Failed to process the balance: ",
+	"⚠️ Anda sudah menggunakan trial. Untuk mendapatkan trial lagi, silakan Topup minimal Rp 5000.": "⚠️ You have already used your trial. To get another trial, please top up at least Rp 5000.",
+	"Error API: ":                            "API error: ",
+	"Gagal membuat trial: %s":                "Failed to create the trial: %s",
+	"⏳ Masukkan Durasi Perpanjangan (hari):": "⏳ Enter the renewal duration (days):",
+	"⚠️ Saldo tidak mencukupi. Diperlukan Rp %d. Silakan Topup minimal Rp 5000.": "⚠️ Insufficient balance. Rp %d is needed. Please top up at least Rp 5000.",
+	"✅ User %s berhasil diperpanjang. Expired: %s\nSaldo tersisa: Rp %d":         "✅ User %s renewed. Expires: %s\nRemaining balance: Rp %d",
+	"Gagal memperpanjang: %s":                                                      "Failed to renew: %s",
+	"Anda tidak memiliki izin untuk ini.":                                          "You do not have permission for this.",
+	"⏳ Masukkan Durasi (hari) untuk akun gratis:":                                  "⏳ Enter the duration (days) for the free account:",
+	"Format salah. Contoh: 7251232303 50000":                                       "Wrong format. Example: 7251232303 50000",
+	"ID atau jumlah tidak valid.":                                                  "Invalid ID or amount.",
+	"Gagal menambah saldo: ":                                                       "Failed to add balance: ",
+	"✅ Berhasil menambah Rp %d ke user %d":                                         "✅ Added Rp %d to user %d",
+	"Gagal mengurangi saldo: ":                                                     "Failed to deduct balance: ",
+	"✅ Berhasil mengurangi Rp %d dari user %d":                                     "✅ Deducted Rp %d from user %d",
+	"TelegramID tidak valid.":                                                      "Invalid TelegramID.",
+	"⛔ User %d telah diban.":                                                       "⛔ User %d has been banned.",
+	"✅ User %d telah di-unban.":                                                    "✅ User %d has been unbanned.",
+	"User tidak ditemukan di wallet.":                                              "User not found in the wallets.",
+	"Silakan forward pesan dari pengguna yang ingin Anda tindak lanjuti.":          "Please forward a message from the user you want to follow up with.",
+	"Forward diterima dari %d. Ketik pesan yang ingin Anda kirim ke pengguna ini:": "Forward received from %d. Type the message you want to send to this user:",
+	"Target tidak ditemukan. Mulai ulang mode forward.":                            "Target not found. Restart forward mode.",
+	"Gagal mengirim pesan ke user: ":                                               "Failed to send the message to the user: ",
+	"✅ Pesan terkirim ke %d":                                                       "✅ Message sent to %d",
+	"❌ Format salah. Contoh: 7251232303 support Budi":                              "❌ Wrong format. Example: 7251232303 support Budi",
+	"❌ Telegram ID tidak valid. Coba lagi:":                                        "❌ Invalid Telegram ID. Try again:",
+	"❌ Role tidak dikenal. Pilih: ":                                                "❌ Unknown role. Choose: ",
+	"❌ Format salah. Contoh: 7 4":                                                  "❌ Wrong format. Example: 7 4",
+	"❌ Jumlah harus angka. Contoh: 7 4":                                            "❌ Both values must be numbers. Example: 7 4",
+	"❌ Jumlah tidak valid. Minimal topup adalah Rp 5000. Silakan coba lagi:":       "❌ Invalid amount. The minimum top-up is Rp 5000. Please try again:",
+	"Gagal membuat transaksi topup: ":                                              "Failed to create the top-up transaction: ",
+	"💳 **Topup Saldo**\nJumlah: Rp %d\nSilakan scan QRIS di bawah untuk melakukan pembayaran.\nExpired: %s": "💳 **Top Up Balance**\nAmount: Rp %d\nPlease scan the QRIS below to pay.\nExpires: %s",
+	"❌ Batal": "❌ Cancel",
+	"⏸️ Anda memiliki proses yang belum selesai. Lanjutkan?": "⏸️ You have an unfinished step. Continue?",
+	"▶️ Lanjutkan":                      "▶️ Continue",
+	"Role Anda tidak memiliki izin %s.": "Your role lacks the %s permission.",
+	"👑 Kelola Staff\n\n":                "👑 Manage Staff\n\n",
+	"Belum ada staff.\n":                "No staff yet.\n",
+	"🗑️ Hapus ":                         "🗑️ Remove ",
+	"\nRole:\n":                         "\nRoles:\n",
+	"➕ Tambah / Ubah Staff":             "➕ Add / Change Staff",
+	"❌ Kembali":                         "❌ Back",
+	"Perintah tidak dikenal.":           "Unknown command.",
+	"Format: ":                          "Usage: ",
+	"Telegram ID tidak valid.":          "Invalid Telegram ID.",
+	"💰 Saldo %d: Rp %d":                 "💰 Balance of %d: Rp %d",
+	"✅ User %s berhasil diperpanjang. Expired: %s":            "✅ User %s renewed. Expires: %s",
+	"Gagal menghapus: %s":                                     "Failed to delete: %s",
+	"✅ Akun %s berhasil dihapus.":                             "✅ Account %s deleted.",
+	"Gagal mengunci: %s":                                      "Failed to lock: %s",
+	"🔒 Akun %s dikunci. Perpanjang untuk membukanya kembali.": "🔒 Account %s locked. Renew it to unlock it.",
+	"(Belum Diatur)":                                          "(Not Configured)",
+	"📇 *Detail Akun*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Aktif s/d: %s\n⏳ Sisa     : %d hari\n📌 Status   : %s %s": "📇 *Account Details*\n━━━━━━━━━━━━━━━━━━━━━\n🔑 Password : `%s`\n🌐 Domain   : `%s`\n🔌 Port     : %d-%d\n🔐 Obfs     : `%s`\n🗓️ Expires  : %s\n⏳ Left     : %d days\n📌 Status   : %s %s",
+	"\n👤 Pemilik  : ": "\n👤 Owner    : ",
+	"\n\n📷 Scan QR untuk mengimpor akun ke aplikasi.": "\n\n📷 Scan the QR code to import the account into the app.",
+	"Aktif":                       "Active",
+	"Terkunci":                    "Locked",
+	"Kedaluwarsa":                 "Expired",
+	"🔁 Perpanjang":                "🔁 Renew",
+	"🏠 Menu Utama":                "🏠 Main Menu",
+	"Gagal mengambil data user.":  "Failed to fetch user data.",
+	"📂 Anda belum memiliki akun.": "📂 You don't have any accounts yet.",
+	"📇 Akun Saya — pilih akun untuk melihat detail dan QR:": "📇 My Accounts — pick an account to see its details and QR:",
+	"%s (≤ %d hari)":                             "%s (≤ %d days)",
+	"📣 *Broadcast*\nPilih target penerima:":      "📣 *Broadcast*\nChoose the recipients:",
+	"📣 *Broadcast*\nSedang berjalan: %s (%d/%d)": "📣 *Broadcast*\nIn progress: %s (%d/%d)",
+	"⏹️ Hentikan Broadcast":                      "⏹️ Stop Broadcast",
+	"Gagal mengambil data user: ":                "Failed to fetch user data: ",
+	"📭 Tidak ada user di segmen ini.":            "📭 There are no users in this segment.",
+	"📣 Target: %s (%d user)\nKirim pesan yang akan disiarkan: teks, foto atau dokumen (caption ikut terkirim).": "📣 Target: %s (%d users)\nSend the message to broadcast: text, a photo or a document (the caption is included).",
+	"❌ Hanya teks, foto atau dokumen yang didukung. Coba lagi:":                                                 "❌ Only text, photos or documents are supported. Try again:",
+	"📣 Kirim pesan di atas ke %s?": "📣 Send the message above to %s?",
+	"✅ Kirim":                      "✅ Send",
+	"Pesan broadcast tidak ditemukan. Silakan mulai ulang.":                     "Broadcast message not found. Please start over.",
+	"Broadcast lain sedang berjalan. Hentikan dulu atau tunggu hingga selesai.": "Another broadcast is running. Stop it first or wait until it finishes.",
+	"Tidak ada broadcast yang sedang berjalan.":                                 "No broadcast is running.",
+	"⏹️ Hentikan": "⏹️ Stop",
+	"%s\nTarget: %s\nProgres: %d/%d\n✅ Terkirim: %d\n🚫 Memblokir bot: %d\n⚠️ Gagal: %d": "%s\nTarget: %s\nProgress: %d/%d\n✅ Sent: %d\n🚫 Blocked the bot: %d\n⚠️ Failed: %d",
+	"👥 Pengguna Bot\nTotal %d • aktif 7 hari %d • memblokir bot %d\n":                   "👥 Bot Users\nTotal %d • active in 7 days %d • blocked the bot %d\n",
+	"🔍 \"%s\": %d hasil\n":         "🔍 \"%s\": %d results\n",
+	"Halaman %d/%d\n":              "Page %d/%d\n",
+	"\nTidak ada pengguna.":        "\nNo users.",
+	"\n  pertama %s • terakhir %s": "\n  first %s • last %s",
+	"⬅️ Sebelumnya":                "⬅️ Prev",
+	"Berikutnya ➡️":                "Next ➡️",
+	"🔍 Cari Pengguna":              "🔍 Search Users",
+	"🌐 Pilih bahasa:":              "🌐 Choose a language:",
+	"👤 Masukkan Password Baru:":    "👤 Enter the new password:",
+	"Fitur pembayaran langsung sudah digantikan oleh sistem Topup. Silakan gunakan menu Topup Saldo terlebih dahulu jika saldo tidak mencukupi.": "Direct payment has been replaced by the top-up system. Please use the Top Up Balance menu first if your balance is not enough.",
+	"✅ Topup berhasil: Rp %d. Saldo Anda saat ini: Rp %d":                                        "✅ Top-up successful: Rp %d. Your current balance: Rp %d",
+	"✅ Pembelian otomatis selesai. Akun dibuat. Saldo tersisa: Rp %d":                            "✅ Automatic purchase complete. Account created. Remaining balance: Rp %d",
+	"Pembayaran berhasil, tetapi saldo tidak mencukupi untuk pemotongan. Silakan hubungi admin.": "Payment succeeded, but the balance is not enough for the deduction. Please contact the admin.",
+	"Gagal membuat akun: %s":            "Failed to create the account: %s",
+	"💰 Saldo Rp %d telah dikembalikan.": "💰 Rp %d has been refunded to your balance.",
+	"Pengguna %d":                       "User %d",
+	"Pengguna":                          "User",
+	"━━━━━━━━━━━━━━━━━━━━━\nRyyStore Zivpn UDP\n━━━━━━━━━━━━━━━━━━━━━\nHai %s\nSaldo Anda : Rp %d\nAkun dibuat oleh Anda : %d\nStatistik : Hari ini %d • Minggu ini %d • Bulan ini %d\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • Kota     : %s\n • ISP      : %s\n • Harga    : Rp %d / Hari\n━━━━━━━━━━━━━━━━━━━━━\n\nCredit: [RyyStorevp1](https://t.me/RyyStorevp1)\nBot: [%s](https://t.me/%s)": "━━━━━━━━━━━━━━━━━━━━━\nRyyStore Zivpn UDP\n━━━━━━━━━━━━━━━━━━━━━\nHi %s\nYour balance : Rp %d\nAccounts you created : %d\nStats : Today %d • This week %d • This month %d\n━━━━━━━━━━━━━━━━━━━━━\n • Domain   : %s\n • City     : %s\n • ISP      : %s\n • Price    : Rp %d / Day\n━━━━━━━━━━━━━━━━━━━━━\n\nCredit: [RyyStorevp1](https://t.me/RyyStorevp1)\nBot: [%s](https://t.me/%s)",
+	"🛒 Beli Akun Premium": "🛒 Buy Premium Account",
+	"💳 Topup Saldo":       "💳 Top Up Balance",
+	"🆓 Trial Akun":        "🆓 Trial Account",
+	"🔁 Perpanjang Akun":   "🔁 Renew Account",
+	"📇 Akun Saya":         "📇 My Accounts",
+	"📋 Daftar Akun":       "📋 Account List",
+	"📊 Info Sistem":       "📊 System Info",
+	"🛠️ Panel Admin":      "🛠️ Admin Panel",
+	"👑 Kelola Staff":      "👑 Manage Staff",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n  AKUN PREMIUM\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nDomain     : %s\nMasa Aktif : %s\n━━━━━━━━━━━━━━━━━━━━━\n```\nTerima kasih telah berlangganan!": "```\n━━━━━━━━━━━━━━━━━━━━━\n  PREMIUM ACCOUNT\n━━━━━━━━━━━━━━━━━━━━━\nPassword   : %s\nDomain     : %s\nValid until: %s\n━━━━━━━━━━━━━━━━━━━━━\n```\nThank you for subscribing!",
+	"📇 Detail & QR": "📇 Details & QR",
+	"🆓 Trial Akun\nSilakan masukkan password yang diinginkan (3-20 karakter):":   "🆓 Trial Account\nPlease enter the password you want (3-20 characters):",
+	"🔁 Perpanjang Akun\nSilakan masukkan password akun yang ingin diperpanjang:": "🔁 Renew Account\nPlease enter the password of the account to renew:",
+	"Gagal mengambil daftar: %v":                                             "Failed to fetch the list: %v",
+	"Format data tidak sesuai dari API":                                      "Unexpected data format from the API",
+	"📋 Daftar akun kosong.":                                                  "📋 The account list is empty.",
+	"📋 Daftar Akun:\n":                                                       "📋 Account List:\n",
+	"\n...dan masih banyak lagi...\n":                                        "\n...and many more...\n",
+	"%d. %s — Aktif s/d: %s — %s\n":                                          "%d. %s — Valid until: %s — %s\n",
+	"✅ Akun gratis dibuat: %s\nExpired: %s":                                  "✅ Free account created: %s\nExpires: %s",
+	"➕ Buat Akun Gratis\nMasukkan password untuk akun baru (3-20 karakter):": "➕ Create Free Account\nEnter the password for the new account (3-20 characters):",
+	"💳 Topup Saldo\nMasukkan jumlah topup minimal Rp 5000 (contoh: 5000):":   "💳 Top Up Balance\nEnter a top-up amount of at least Rp 5000 (example: 5000):",
+	"❌ Password harus 3-20 karakter. Coba lagi:":                             "❌ The password must be 3-20 characters. Try again:",
+	"❌ Password hanya boleh huruf, angka, - dan _. Coba lagi:":               "❌ The password may only contain letters, numbers, - and _. Try again:",
+	"❌ %s harus angka positif (%d-%d). Coba lagi:":                           "❌ %s must be a positive number (%d-%d). Try again:",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    INFO ZIVPN UDP\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nIP Publik      : %s\nPort           : %s\nLayanan        : %s\nKota           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```": "```\n━━━━━━━━━━━━━━━━━━━━━\n    ZIVPN UDP INFO\n━━━━━━━━━━━━━━━━━━━━━\nDomain         : %s\nPublic IP      : %s\nPort           : %s\nService        : %s\nCity           : %s\nISP            : %s\nUptime         : %dd %dh\nCPU            : %d core (%.0f%%)\nLoad           : %.2f %.2f %.2f\nRAM            : %d / %d MB\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	"Gagal mengambil info.":                 "Failed to fetch info.",
+	"🛠️ *Panel Admin*\nSilakan pilih menu:": "🛠️ *Admin Panel*\nPlease choose a menu:",
+	"⬇️ Backup Data":                        "⬇️ Backup Data",
+	"⬆️ Restore Data":                       "⬆️ Restore Data",
+	"➕ Buat Akun Gratis":                    "➕ Create Free Account",
+	"🔒 Enkripsi Backup":                     "🔒 Backup Encryption",
+	"🧾 Kelola User":                         "🧾 Manage Users",
+	"⚙️ Service Core":                       "⚙️ Service Core",
+	"🔐 Ubah Obfs":                           "🔐 Change Obfs",
+	"🗓️ Backup Otomatis":                    "🗓️ Automatic Backup",
+	"Gagal mengambil pengaturan backup.":    "Failed to fetch backup settings.",
+	"❌ Tidak aktif":                         "❌ Disabled",
+	"✅ Aktif":                               "✅ Enabled",
+	"Tidak ada":                             "None",
+	"Tersimpan di server":                   "Stored on the server",
+	"🔒 Enkripsi Backup\n\nPassphrase: %s\nRecipient key: %d\nSecret key: %s\n\nRecipient key diatur lewat API /api/backup/settings.": "🔒 Backup Encryption\n\nPassphrase: %s\nRecipient keys: %d\nSecret key: %s\n\nRecipient keys are managed through the API at /api/backup/settings.",
+	"🔑 Atur Passphrase":              "🔑 Set Passphrase",
+	"🚫 Hapus Passphrase":             "🚫 Remove Passphrase",
+	"Gagal menyimpan passphrase: %s": "Failed to save passphrase: %s",
+	"✅ Passphrase backup dihapus.":   "✅ Backup passphrase removed.",
+	"✅ Passphrase backup disimpan. Backup berikutnya akan terenkripsi.": "✅ Backup passphrase saved. The next backups will be encrypted.",
+	"Tidak ada jadwal berikutnya":                                       "No upcoming run",
+	"Gagal mengambil status backup.":                                    "Failed to fetch backup status.",
+	"Belum pernah":                                                      "Never",
+	"Tidak":                                                             "No",
+	"Ya":                                                                "Yes",
+	"🗓️ Backup Otomatis\n\nJadwal: %s\nBerikutnya: %s\nTerakhir: %s\nHarian: %d/%v\nMingguan: %d/%v\nTerenkripsi: %s\nKirim ke Telegram: %s\n\nFile disimpan di /var/backups/zivpn": "🗓️ Automatic Backup\n\nSchedule: %s\nNext: %s\nLast: %s\nDaily: %d/%v\nWeekly: %d/%v\nEncrypted: %s\nSend to Telegram: %s\n\nFiles are stored in /var/backups/zivpn",
+	"▶️ Backup Sekarang":                     "▶️ Back Up Now",
+	"📨 Telegram: ":                           "📨 Telegram: ",
+	"🕒 Ubah Jadwal":                          "🕒 Change Schedule",
+	"🗂️ Retensi":                             "🗂️ Retention",
+	"⏳ Sedang membuat backup...":             "⏳ Creating backup...",
+	"Gagal menyimpan: %s":                    "Failed to save: %s",
+	"⚙️ *Service Core*\nSilakan pilih aksi:": "⚙️ *Service Core*\nPlease choose an action:",
+	"📟 Status":                               "📟 Status",
+	"📜 Log":                                  "📜 Logs",
+	"🔄 Restart":                              "🔄 Restart",
+	"▶️ Start":                               "▶️ Start",
+	"⏹️ Stop":                                "⏹️ Stop",
+	"❓ Yakin ingin menghentikan zivpn.service? Semua user akan terputus.": "❓ Are you sure you want to stop zivpn.service? All users will be disconnected.",
+	"✅ Ya, Stop": "✅ Yes, Stop",
+	"Gagal: %s":  "Failed: %s",
+	"```\n━━━━━━━━━━━━━━━━━━━━━\n    STATUS ZIVPN\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSejak    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```": "```\n━━━━━━━━━━━━━━━━━━━━━\n    ZIVPN STATUS\n━━━━━━━━━━━━━━━━━━━━━\nState    : %v (%v)\nPID      : %v\nSince    : %v\nRestarts : %v\n━━━━━━━━━━━━━━━━━━━━━\n```",
+	"⏳ Menjalankan %s...":        "⏳ Running %s...",
+	"(log kosong)\n":             "(log is empty)\n",
+	"📜 Log zivpn.service\n```\n": "📜 zivpn.service logs\n```\n",
+	"🔐 Obfs saat ini: %s\nMasukkan obfs baru (1-64 karakter, tanpa spasi):": "🔐 Current obfs: %s\nEnter the new obfs (1-64 characters, no spaces):",
+	"⏳ Menerapkan config dan merestart service...":                          "⏳ Applying config and restarting the service...",
+	"✅ Obfs diubah menjadi `%s`. Client harus memakai obfs baru.":           "✅ Obfs changed to `%s`. Clients must use the new obfs.",
+	"⚙️ *Admin - Kelola User*\nPilih aksi:":                                 "⚙️ *Admin - Manage Users*\nChoose an action:",
+	"➕ Tambah Saldo":             "➕ Add Balance",
+	"➖ Kurangi Saldo":            "➖ Deduct Balance",
+	"⛔ Ban User":                 "⛔ Ban User",
+	"✅ Unban User":               "✅ Unban User",
+	"📈 Lihat Aktivitas":          "📈 View Activity",
+	"📨 Mode Forward":             "📨 Forward Mode",
+	"📣 Broadcast":                "📣 Broadcast",
+	"👥 Pengguna Bot":             "👥 Bot Users",
+	"Gagal membuat backup: ":     "Failed to create backup: ",
+	"✅ Backup Data ZiVPN":        "✅ ZiVPN Data Backup",
+	" (🔒 terenkripsi)":           " (🔒 encrypted)",
+	"Gagal membuat file backup.": "Failed to create the backup file.",
+	"⬆️ *Restore Data*\n\nSilakan kirim file backup (.zip atau .zip.enc) Anda sekarang.\n\n⚠️ PERINGATAN: Data saat ini akan ditimpa!": "⬆️ *Restore Data*\n\nPlease send your backup file (.zip or .zip.enc) now.\n\n⚠️ WARNING: Current data will be overwritten!",
+	"File terlalu besar (maksimal %d MB).":               "File too large (max %d MB).",
+	"⏳ Sedang memproses file...":                         "⏳ Processing file...",
+	"Gagal mengunduh file.":                              "Failed to download the file.",
+	"Gagal mengunduh isi file.":                          "Failed to download the file content.",
+	"Gagal membaca file.":                                "Failed to read the file.",
+	"Gagal menyimpan file sementara.":                    "Failed to save the temporary file.",
+	"File restore tidak ditemukan, silakan kirim ulang.": "Restore file not found, please send it again.",
+	"🔒 Backup terenkripsi.\nKirim passphrase atau secret key (ZIVPNSEC1...):": "🔒 Encrypted backup.\nSend the passphrase or secret key (ZIVPNSEC1...):",
+	"Backup ditolak: %s":                                            "Backup rejected: %s",
+	"🔍 Pratinjau Restore\n\n":                                       "🔍 Restore Preview\n\n",
+	"\n🔒 Backup terenkripsi berhasil dibuka.":                       "\n🔒 Encrypted backup opened successfully.",
+	"\n⚠️ Backup lama tanpa manifest, checksum tidak diverifikasi.": "\n⚠️ Old backup without a manifest, checksums not verified.",
+	"\n\nLanjutkan restore?":                                        "\n\nContinue the restore?",
+	"✅ Ya, Restore":                                                 "✅ Yes, Restore",
+	"⏳ Sedang merestore data...":                                    "⏳ Restoring data...",
+	"Restore gagal: %s":                                             "Restore failed: %s",
+	"✅ Restore Berhasil!\n\n":                                       "✅ Restore Successful!\n\n",
+	"File: %s\nUser ditambah: %s\nUser dihapus: %s":                 "Files: %s\nUsers added: %s\nUsers removed: %s",
+	"\nDilewati: ":                                                  "\nSkipped: ",
+	"\nBackup pengaman: ":                                           "\nSafety backup: ",
+	"⏰ Pemeriksaan expired: %d akun dicabut.\n%s":                   "⏰ Expiry check: %d accounts revoked.\n%s",
+	"\n⚠️ Sebagian akun gagal dicabut, cek log zivpn-api.":          "\n⚠️ Some accounts could not be revoked, check the zivpn-api log.",
+	"⚠️ Restart zivpn.service gagal: %v":                            "⚠️ Restarting zivpn.service failed: %v",
+	"⚠️ Backup otomatis gagal: %v":                                  "⚠️ Automatic backup failed: %v",
+	"🗓️ Backup otomatis ZiVPN":                                      "🗓️ ZiVPN automatic backup",
+	"⏳ Masukkan Durasi (hari):":                                     "⏳ Enter the duration (days):",
+	"📨 Silakan forward pesan dari pengguna ke chat ini.":            "📨 Please forward a message from the user to this chat.",
+	"Ketik pesan yang ingin Anda kirim ke pengguna:":                "Type the message you want to send to the user:",
+	"🔐 Masukkan obfs baru (1-64 karakter, tanpa spasi):":            "🔐 Enter the new obfs (1-64 characters, no spaces):",
+	"🕒 Masukkan jadwal cron, contoh \"0 3 * * *\", atau \"off\":":   "🕒 Enter a cron schedule, e.g. \"0 3 * * *\", or \"off\":",
+	"🗂️ Masukkan jumlah backup harian dan mingguan (contoh: 7 4):":  "🗂️ Enter how many daily and weekly backups to keep (e.g. 7 4):",
+	"⬆️ Silakan kirim file backup (.zip atau .zip.enc).":            "⬆️ Please send the backup file (.zip or .zip.enc).",
+	"🔒 Kirim passphrase atau secret key (ZIVPNSEC1...):":            "🔒 Send the passphrase or secret key (ZIVPNSEC1...):",
+	"👑 Masukkan: <TelegramID> <role> [nama]":                        "👑 Enter: <TelegramID> <role> [name]",
+	"📣 Kirim pesan yang akan disiarkan: teks, foto atau dokumen.":   "📣 Send the message to broadcast: text, a photo or a document.",
+	"Menu utama":                      "Main menu",
+	"<pass>":                          "<pass>",
+	"Detail akun":                     "Account details",
+	"[tgid]":                          "[tgid]",
+	"Cek saldo":                       "Check balance",
+	"<pass> <hari>":                   "<pass> <days>",
+	"Buat akun gratis":                "Create a free account",
+	"Perpanjang akun tanpa saldo":     "Renew an account without balance",
+	"Hapus akun":                      "Delete an account",
+	"Kunci akun":                      "Lock an account",
+	"<tgid> <jumlah>":                 "<tgid> <amount>",
+	"Tambah saldo user":               "Add balance to a user",
+	"Durasi":                          "Duration",
+	"Jumlah hari":                     "Number of days",
+	"👥 Semua User":                    "👥 All Users",
+	"🟢 Pemilik Akun Aktif":            "🟢 Active Account Owners",
+	"💰 Saldo > 0":                     "💰 Balance > 0",
+	"⏳ Akan Expired":                  "⏳ Expiring Soon",
+	"🆕 Belum Pernah Beli":             "🆕 Never Bought",
+	"Silakan kirim input berikutnya.": "Please send the next input.",
+	"⏳ Broadcast berjalan":            "⏳ Broadcast running",
+	"✅ Broadcast selesai":             "✅ Broadcast finished",
+	"⏹️ Broadcast dihentikan":         "⏹️ Broadcast stopped",
+}
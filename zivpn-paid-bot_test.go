@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMain(m *testing.M) {
+	// Recovered panics log a stack trace; keep test output readable
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func messageUpdate(id int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+// waitTimeout fails the test when wg is not done within a few seconds.
+func waitTimeout(t *testing.T, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the dispatcher")
+	}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	const perChat = 50
+	chats := []int64{1, 2, 3}
+
+	var mu sync.Mutex
+	seen := make(map[int64][]int)
+	var wg sync.WaitGroup
+	wg.Add(perChat * len(chats))
+
+	d := newDispatcher(4, func(update tgbotapi.Update) {
+		defer wg.Done()
+		time.Sleep(time.Duration(update.UpdateID%3) * time.Millisecond)
+		chatID := updateChatID(update)
+		mu.Lock()
+		seen[chatID] = append(seen[chatID], update.UpdateID)
+		mu.Unlock()
+	})
+	for i := 0; i < perChat; i++ {
+		for _, chatID := range chats {
+			d.Dispatch(messageUpdate(i, chatID))
+		}
+	}
+	waitTimeout(t, &wg)
+
+	for _, chatID := range chats {
+		ids := seen[chatID]
+		if len(ids) != perChat {
+			t.Fatalf("chat %d handled %d updates, want %d", chatID, len(ids), perChat)
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("chat %d handled %v, want arrival order", chatID, ids)
+			}
+		}
+	}
+	// drain forgets a chat right after its last handler returns
+	deadline := time.Now().Add(time.Second)
+	for {
+		d.mu.Lock()
+		left := len(d.pending)
+		d.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d chats still queued after their updates ran", left)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherBoundsWorkers(t *testing.T) {
+	const workers = 2
+	var mu sync.Mutex
+	running, peak := 0, 0
+	var wg sync.WaitGroup
+	wg.Add(6)
+
+	d := newDispatcher(workers, func(update tgbotapi.Update) {
+		defer wg.Done()
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	for chatID := int64(1); chatID <= 6; chatID++ {
+		d.Dispatch(messageUpdate(int(chatID), chatID))
+	}
+	waitTimeout(t, &wg)
+
+	if peak != workers {
+		t.Errorf("peak concurrency %d, want %d", peak, workers)
+	}
+}
+
+func TestDispatcherRecoversFromPanic(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	d := newDispatcher(1, func(update tgbotapi.Update) {
+		defer wg.Done()
+		if update.UpdateID == 1 {
+			panic("handler bug")
+		}
+		mu.Lock()
+		handled = append(handled, update.UpdateID)
+		mu.Unlock()
+	})
+	for i := 1; i <= 3; i++ {
+		d.Dispatch(messageUpdate(i, 42))
+	}
+	waitTimeout(t, &wg)
+
+	if len(handled) != 2 || handled[0] != 2 || handled[1] != 3 {
+		t.Errorf("handled %v after the panic, want [2 3]", handled)
+	}
+}
+
+func TestUpdateChatID(t *testing.T) {
+	user := &tgbotapi.User{ID: 7}
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		want   int64
+	}{
+		{"message", messageUpdate(1, 5), 5},
+		{"callback with message", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 9}}}}, 9},
+		{"inline callback", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user}}, 7},
+		{"other", tgbotapi.Update{}, 0},
+	}
+	for _, tt := range tests {
+		if got := updateChatID(tt.update); got != tt.want {
+			t.Errorf("%s: updateChatID = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}